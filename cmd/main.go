@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
+	"net/http"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	goredis "github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/routes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/bulkinvite"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/redis"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/migrations"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/oauth"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/outbox"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/purge"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/signing"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/sso"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/webhooks"
 )
 
 var (
@@ -23,6 +39,7 @@ var (
 	organizationsCollection *mongo.Collection
 	tokensCollection *mongo.Collection
 	invitationsCollection *mongo.Collection
+	redisClient *goredis.Client
 )
 
 func init() {
@@ -65,25 +82,152 @@ func init() {
 	organizationsCollection = database.Collection(organizationsCollectionName)
 	tokensCollection = database.Collection(tokensCollectionName)
 	invitationsCollection = database.Collection(invitationsCollectionName)
+
+	// Connect to Redis, falling back to in-process storage when it is unavailable
+	redisClient = redis.NewClient(context.Background())
 }
 
 func main() {
     // Initialize Gin router
 	router := gin.Default()
 
+	// Initialize the cache shared by repositories
+	entityCache := cache.New(redisClient)
+
 	// Initialize repositories
-    userRepository := repository.NewUserRepository(database)
-	organizationRepository := repository.NewOrganizationRepository(database)
+    userRepository := repository.NewUserRepository(database, entityCache)
+	organizationRepository := repository.NewOrganizationRepository(database, entityCache)
+	membershipRepository := repository.NewMembershipRepository(database, entityCache)
+	apiTokenRepository := repository.NewAPITokenRepository(database, entityCache)
+	auditEventRepository := repository.NewAuditEventRepository(database)
+	invitationRepository := repository.NewInvitationRepository(database)
+	invitationJobRepository := repository.NewInvitationJobRepository(database)
+	teamRepository := repository.NewTeamRepository(database, entityCache)
+	webhookRepository := repository.NewWebhookRepository(database)
+	webhookDeliveryRepository := repository.NewWebhookDeliveryRepository(database)
+
+	// Create the indexes of nested and owned organizations
+	if err := organizationRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating organization indexes: %v", err)
+	}
+
+	// Create the indexes of teams and their members
+	if err := teamRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating team indexes: %v", err)
+	}
+
+	// Move members embedded in organizations to their own collection
+	if err := membershipRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating membership indexes: %v", err)
+	}
+	if err := migrations.MoveEmbeddedMembers(context.Background(), organizationRepository, membershipRepository, userRepository); err != nil {
+		log.Fatalf("Error migrating organization members: %v", err)
+	}
+
+	outboxRepository := repository.NewOutboxRepository(database)
+	transactions := repository.NewTransactions(database)
+
+	// Create the index the relay finds unpublished events with
+	if err := outboxRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating outbox indexes: %v", err)
+	}
+
+	// Initialize webhook deliveries, attempted in the background
+	webhookDispatcher := webhooks.NewDispatcher(webhookRepository, webhookDeliveryRepository)
+	webhookDispatcher.Start(context.Background())
+
+	// Initialize the relay publishing the events of the outbox to its sinks
+	outboxSinks, err := outbox.LoadSinks(webhookDispatcher)
+	if err != nil {
+		log.Fatalf("Error loading outbox sinks: %v", err)
+	}
+	outbox.NewRelay(outboxRepository, outboxSinks).Start(context.Background())
+
+	// Initialize the purge of deleted organizations once their retention elapsed
+	organizationRetention, err := purge.LoadRetention()
+	if err != nil {
+		log.Fatalf("Error loading organization retention: %v", err)
+	}
+	oauthClientRepository := repository.NewOAuthClientRepository(database)
+	purge.NewPurger(organizationRepository, membershipRepository, teamRepository, invitationRepository, invitationJobRepository, webhookRepository, webhookDeliveryRepository, apiTokenRepository, oauthClientRepository, auditEventRepository, organizationRetention).Start(context.Background())
 
-	organizationHandler := handlers.NewOrganizationHandler(organizationRepository)
+	// Create the indexes of invitations, then process bulk invitations in the background
+	if err := invitationRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating invitation indexes: %v", err)
+	}
+	if err := invitationJobRepository.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Error creating invitation job indexes: %v", err)
+	}
+	bulkinvite.NewProcessor(invitationJobRepository, organizationRepository, membershipRepository, invitationRepository, outboxRepository, auditEventRepository, transactions).Start(context.Background())
+
+	organizationHandler := handlers.NewOrganizationHandler(organizationRepository, userRepository, membershipRepository, invitationRepository, invitationJobRepository, auditEventRepository, outboxRepository, transactions, dnsverify.DefaultResolver, organizationRetention)
+
+	// Initialize the keys access tokens are signed with
+	signingConfig, err := signing.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading token signing configuration: %v", err)
+	}
+	var keyRotator *signing.Rotator
+	if signingConfig.Enabled() {
+		keyRotator = signing.NewRotator(repository.NewSigningKeyRepository(database), signingConfig)
+		if err := keyRotator.Start(context.Background()); err != nil {
+			log.Fatalf("Error loading token signing keys: %v", err)
+		}
+		utils.UseSigningKeys(keyRotator)
+	}
+	jwksHandler := handlers.NewJWKSHandler(keyRotator)
+
+	// Initialize rate limit storage
+	rateLimitStore := ratelimit.NewStore(redisClient)
+
+	// Initialize external identity providers
+	oauthProviders := oauth.LoadProviders(context.Background())
+
+	// Initialize SAML service providers
+	serviceProviders, err := sso.NewServiceProviders()
+	if err != nil {
+		log.Fatalf("Error loading SAML service provider key pair: %v", err)
+	}
+	ssoHandler := handlers.NewSSOHandler(organizationRepository, membershipRepository, userRepository, auditEventRepository, outboxRepository, transactions, serviceProviders, entityCache)
+	scimHandler := handlers.NewSCIMHandler(organizationRepository, membershipRepository, userRepository, auditEventRepository, outboxRepository, transactions)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenRepository, auditEventRepository)
+	teamHandler := handlers.NewTeamHandler(teamRepository, membershipRepository, auditEventRepository, outboxRepository, transactions)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepository, webhookDeliveryRepository, auditEventRepository, webhookDispatcher)
+	authorizationServerHandler := handlers.NewAuthorizationServerHandler(oauthClientRepository, repository.NewAuthorizationCodeRepository(database), apiTokenRepository, organizationRepository, auditEventRepository)
 
 	// Setup middleware
-    router.Use(middleware.BearerTokenAuth(userRepository, organizationRepository))
+	router.Use(middleware.RequestID())
+    router.Use(middleware.BearerTokenAuth(userRepository, apiTokenRepository))
 
     // Setup routes
-    routes.SetupUserRoutes(router, userRepository)
-	routes.SetupOrganizationRoutes(router, organizationHandler)
+    routes.SetupUserRoutes(router, userRepository, organizationRepository, membershipRepository, auditEventRepository, outboxRepository, transactions, rateLimitStore, oauthProviders, entityCache)
+	routes.SetupOrganizationRoutes(router, organizationHandler, organizationRepository, membershipRepository, rateLimitStore)
+	routes.SetupSSORoutes(router, ssoHandler, organizationRepository, membershipRepository, rateLimitStore)
+	routes.SetupSCIMRoutes(router, scimHandler, organizationRepository, membershipRepository, rateLimitStore)
+	routes.SetupAPITokenRoutes(router, apiTokenHandler, organizationRepository, membershipRepository)
+	routes.SetupTeamRoutes(router, teamHandler, organizationRepository, membershipRepository)
+	routes.SetupWebhookRoutes(router, webhookHandler, organizationRepository, membershipRepository)
+	routes.SetupAuthorizationServerRoutes(router, authorizationServerHandler, organizationRepository, membershipRepository, rateLimitStore)
+	routes.SetupWellKnownRoutes(router, jwksHandler, authorizationServerHandler)
+
+	// Expose runtime metrics, including cache hits and misses, on an internal listener
+	go serveDebugVars()
 
 	// Start the Gin server
 	router.Run(":8080")
-}
\ No newline at end of file
+}
+
+// serveDebugVars serves runtime metrics at /debug/vars on DEBUG_ADDR, the loopback interface
+// by default, so they are never exposed on the public router.
+func serveDebugVars() {
+	addr := os.Getenv("DEBUG_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:6060"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Println("Error serving debug variables:", err)
+	}
+}
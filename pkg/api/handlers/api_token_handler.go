@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// displayedPrefixLength is the number of leading characters of a token kept to recognize it.
+const displayedPrefixLength = 12
+
+// APITokenHandler manages personal access tokens of users and API keys of organizations.
+type APITokenHandler struct {
+	apiTokenRepository   *repository.APITokenRepository
+	auditEventRepository *repository.AuditEventRepository
+}
+
+func NewAPITokenHandler(apiTokenRepository *repository.APITokenRepository, auditEventRepository *repository.AuditEventRepository) *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenRepository:   apiTokenRepository,
+		auditEventRepository: auditEventRepository,
+	}
+}
+
+type createAPITokenRequest struct {
+	Name        string    `json:"name"`
+	Scopes      []string  `json:"scopes"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	AccessLevel string    `json:"access_level"`
+}
+
+func (th *APITokenHandler) GetPersonalAccessTokens(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	tokens, err := th.apiTokenRepository.GetUserTokens(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreatePersonalAccessToken issues a token acting as the current user. The token is only
+// returned once.
+func (th *APITokenHandler) CreatePersonalAccessToken(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	th.issueToken(c, &req, &models.APIToken{
+		Type:   models.TokenTypePersonalAccessToken,
+		UserID: userID,
+	})
+}
+
+func (th *APITokenHandler) DeletePersonalAccessToken(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := th.apiTokenRepository.DeleteUserToken(context.Background(), tokenID, userID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
+
+func (th *APITokenHandler) GetAPIKeys(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	keys, err := th.apiTokenRepository.GetOrganizationTokens(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey issues a key acting on the organization with the requested access level. The
+// key is only returned once. Keys never get more access than the admin who created them still
+// has in the organization. By design they are exempt from the organization's two-factor
+// authentication requirement, as services using them have no second factor to present.
+func (th *APITokenHandler) CreateAPIKey(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Keys act as members unless an admin access level is requested
+	if req.AccessLevel == "" {
+		req.AccessLevel = models.AccessLevelMember
+	}
+	if req.AccessLevel != models.AccessLevelMember && req.AccessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+
+	th.issueToken(c, &req, &models.APIToken{
+		Type:           models.TokenTypeAPIKey,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		AccessLevel:    req.AccessLevel,
+	})
+}
+
+func (th *APITokenHandler) DeleteAPIKey(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	keyID, err := primitive.ObjectIDFromHex(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	if err := th.apiTokenRepository.DeleteOrganizationToken(context.Background(), keyID, organizationID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	recordAuditEvent(c, th.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionAPIKeyRevoked,
+		TargetType:     models.AuditTargetAPIKey,
+		TargetID:       keyID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// issueToken validates the request, generates the secret and stores its hash along with the
+// token's metadata.
+func (th *APITokenHandler) issueToken(c *gin.Context, req *createAPITokenRequest, apiToken *models.APIToken) {
+	apiToken.Name = strings.TrimSpace(req.Name)
+	if apiToken.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if !req.ExpiresAt.IsZero() && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+	apiToken.ExpiresAt = req.ExpiresAt
+
+	// Tokens only grant the scopes they were created with
+	tokenScopes, err := scopes.Normalize(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(tokenScopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	apiToken.Scopes = tokenScopes
+
+	prefix := utils.PersonalAccessTokenPrefix
+	if apiToken.Type == models.TokenTypeAPIKey {
+		prefix = utils.APIKeyPrefix
+	}
+	token, err := utils.GenerateOpaqueToken(prefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Only the hash is stored, with a prefix to help owners recognize the token
+	apiToken.ID = primitive.NewObjectID()
+	apiToken.Prefix = token[:displayedPrefixLength]
+	apiToken.TokenHash = utils.HashOpaqueToken(token)
+
+	if err := th.apiTokenRepository.CreateToken(context.Background(), apiToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save token"})
+		return
+	}
+
+	// API keys act on the organization, so their creation is recorded in its audit log. The
+	// key is recorded by its prefix, the secret and its hash are left out.
+	if apiToken.Type == models.TokenTypeAPIKey {
+		recordAuditEvent(c, th.auditEventRepository, &models.AuditEvent{
+			OrganizationID: apiToken.OrganizationID,
+			Action:         models.AuditActionAPIKeyCreated,
+			TargetType:     models.AuditTargetAPIKey,
+			TargetID:       apiToken.ID.Hex(),
+			Changes:        auditDiff(nil, apiToken),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Token created successfully",
+		"token":     token,
+		"api_token": apiToken,
+	})
+}
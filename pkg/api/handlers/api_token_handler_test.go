@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+)
+
+// insertedAuditEvent returns the audit event inserted in the mock deployment.
+func insertedAuditEvent(mt *mtest.T) bson.Raw {
+	for _, started := range mt.GetAllStartedEvents() {
+		if started.CommandName == "insert" && started.Command.Lookup("insert").StringValue() == "audit_events" {
+			return started.Command.Lookup("documents", "0").Document()
+		}
+	}
+	mt.Fatalf("commands %v, want an audit event", commandNames(mt))
+	return nil
+}
+
+func TestAPIKeyAudit(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organizationID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+
+	newRouter := func(mt *mtest.T) *gin.Engine {
+		th := NewAPITokenHandler(repository.NewAPITokenRepository(mt.DB, cache.NewLRU(16)), repository.NewAuditEventRepository(mt.DB))
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", userID.Hex()) })
+		router.POST("/organizations/:id/api-keys", th.CreateAPIKey)
+		router.DELETE("/organizations/:id/api-keys/:key_id", th.DeleteAPIKey)
+		return router
+	}
+
+	mt.Run("creation records the prefix, not the secret", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		recorder := serveJSON(newRouter(mt), http.MethodPost, "/organizations/"+organizationID.Hex()+"/api-keys", `{"name": "CI", "scopes": ["`+scopes.OrgsRead+`"]}`, nil)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("got %d %s", recorder.Code, recorder.Body)
+		}
+		var response struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+
+		event := insertedAuditEvent(mt)
+		if action := event.Lookup("action").StringValue(); action != models.AuditActionAPIKeyCreated {
+			mt.Errorf("action = %s, want %s", action, models.AuditActionAPIKeyCreated)
+		}
+		if prefix := event.Lookup("changes", "prefix", "after").StringValue(); prefix != response.Token[:displayedPrefixLength] {
+			mt.Errorf("prefix = %q, want the prefix of the key", prefix)
+		}
+		if strings.Contains(event.String(), response.Token) {
+			mt.Error("audit event contains the key")
+		}
+		if _, err := event.LookupErr("changes", "token_hash"); err == nil {
+			mt.Error("audit event contains the hash of the key")
+		}
+	})
+
+	mt.Run("revocation is recorded", func(mt *mtest.T) {
+		key := &models.APIToken{ID: primitive.NewObjectID(), Type: models.TokenTypeAPIKey, OrganizationID: organizationID, TokenHash: "hash"}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, key)}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		recorder := serve(newRouter(mt), http.MethodDelete, "/organizations/"+organizationID.Hex()+"/api-keys/"+key.ID.Hex())
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("got %d %s", recorder.Code, recorder.Body)
+		}
+		event := insertedAuditEvent(mt)
+		if event.Lookup("action").StringValue() != models.AuditActionAPIKeyRevoked || event.Lookup("target_id").StringValue() != key.ID.Hex() {
+			mt.Errorf("audit event %v, want the revocation of the key", event)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordAuditEvent completes the event with the actor and origin of the request and appends it
+// to the audit log. The actor is taken from the token unless already set, e.g. on signin.
+// Failures are logged, the change itself already happened.
+func recordAuditEvent(c *gin.Context, auditEventRepository *repository.AuditEventRepository, event *models.AuditEvent) {
+	if event.ActorID.IsZero() {
+		event.ActorID, _ = primitive.ObjectIDFromHex(c.GetString("user_id"))
+		event.ActorEmail = c.GetString("user_email")
+	}
+	event.ActorTokenType = c.GetString("token_type")
+	event.ActorTokenID, _ = primitive.ObjectIDFromHex(c.GetString("token_id"))
+	event.IP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	event.RequestID = c.GetString("request_id")
+	event.CreatedAt = time.Now()
+
+	if err := auditEventRepository.CreateEvent(context.Background(), event); err != nil {
+		log.Println("Error recording audit event", event.Action+":", err)
+	}
+}
+
+// auditDiff returns the attributes that differ between two values, compared through their
+// JSON representation so attributes hidden from responses are left out. Either value may be
+// nil, when something was created or deleted.
+func auditDiff(before, after interface{}) map[string]models.AuditChange {
+	beforeAttributes := auditAttributes(before)
+	afterAttributes := auditAttributes(after)
+
+	changes := map[string]models.AuditChange{}
+	for name, value := range beforeAttributes {
+		if !reflect.DeepEqual(value, afterAttributes[name]) {
+			changes[name] = models.AuditChange{Before: value, After: afterAttributes[name]}
+		}
+	}
+	for name, value := range afterAttributes {
+		if _, ok := beforeAttributes[name]; !ok {
+			changes[name] = models.AuditChange{After: value}
+		}
+	}
+
+	// The target is recorded separately and timestamps change with everything else
+	delete(changes, "_id")
+	delete(changes, "updated_at")
+
+	return changes
+}
+
+func auditAttributes(value interface{}) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return attributes
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Println("Error encoding audited value:", err)
+		return attributes
+	}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		log.Println("Error decoding audited value:", err)
+	}
+	return attributes
+}
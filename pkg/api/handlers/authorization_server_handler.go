@@ -0,0 +1,515 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Lifetimes of the codes and tokens issued to OAuth clients
+const (
+	authorizationCodeLifetime = 10 * time.Minute
+	oauthAccessTokenLifetime  = time.Hour
+	oauthRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// codeChallengeMethodS256 is the only PKCE method accepted, plain challenges are rejected.
+const codeChallengeMethodS256 = "S256"
+
+// AuthorizationServerHandler lets third-party applications registered by organizations obtain
+// tokens acting on behalf of users (authorization code grant with PKCE) or on the organization
+// itself (client credentials grant).
+type AuthorizationServerHandler struct {
+	clientRepository       *repository.OAuthClientRepository
+	codeRepository         *repository.AuthorizationCodeRepository
+	apiTokenRepository     *repository.APITokenRepository
+	organizationRepository *repository.OrganizationRepository
+	auditEventRepository   *repository.AuditEventRepository
+}
+
+func NewAuthorizationServerHandler(clientRepository *repository.OAuthClientRepository, codeRepository *repository.AuthorizationCodeRepository, apiTokenRepository *repository.APITokenRepository, organizationRepository *repository.OrganizationRepository, auditEventRepository *repository.AuditEventRepository) *AuthorizationServerHandler {
+	return &AuthorizationServerHandler{
+		clientRepository:       clientRepository,
+		codeRepository:         codeRepository,
+		apiTokenRepository:     apiTokenRepository,
+		organizationRepository: organizationRepository,
+		auditEventRepository:   auditEventRepository,
+	}
+}
+
+// oauthError is an error response defined by RFC 6749.
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func respondOAuthError(c *gin.Context, status int, e *oauthError) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": e.Code, "error_description": e.Description})
+}
+
+type authorizationRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Approve             bool   `form:"-" json:"approve"`
+}
+
+// GetAuthorization validates an authorization request and returns what the consent screen
+// shows the signed in user: the client, its organization and the requested scopes.
+func (ah *AuthorizationServerHandler) GetAuthorization(c *gin.Context) {
+	var req authorizationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_request", "Invalid authorization request"})
+		return
+	}
+
+	client, requestedScopes, oerr := ah.validateAuthorizationRequest(&req)
+	if oerr != nil {
+		respondOAuthError(c, http.StatusBadRequest, oerr)
+		return
+	}
+
+	organization, err := ah.organizationRepository.GetOrganizationByID(context.Background(), client.OrganizationID)
+	if err != nil {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_client", "Unknown client"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client": gin.H{
+			"client_id": client.ClientID,
+			"name":      client.Name,
+		},
+		"organization": gin.H{
+			"_id":  organization.ID,
+			"name": organization.Name,
+		},
+		"scopes":       requestedScopes,
+		"redirect_uri": req.RedirectURI,
+		"state":        req.State,
+	})
+}
+
+// Authorize records the user's decision on an authorization request. The response holds the
+// URI to redirect the user to, with either an authorization code or an access_denied error.
+func (ah *AuthorizationServerHandler) Authorize(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var req authorizationRequest
+	if err := c.BindJSON(&req); err != nil {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_request", "Invalid authorization request"})
+		return
+	}
+
+	// The code is only bound to a redirect URI the client gave, not to the default one
+	requestedRedirectURI := req.RedirectURI
+	client, requestedScopes, oerr := ah.validateAuthorizationRequest(&req)
+	if oerr != nil {
+		respondOAuthError(c, http.StatusBadRequest, oerr)
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", "access_denied")
+		c.JSON(http.StatusOK, gin.H{"redirect_uri": withQuery(req.RedirectURI, params)})
+		return
+	}
+
+	code, err := utils.GenerateOpaqueToken("")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate authorization code"})
+		return
+	}
+
+	// Only the hash of the code is stored, it is exchanged once for tokens
+	now := time.Now()
+	authorizationCode := &models.OAuthAuthorizationCode{
+		ID:            primitive.NewObjectID(),
+		CodeHash:      utils.HashOpaqueToken(code),
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   requestedRedirectURI,
+		Scopes:        requestedScopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(authorizationCodeLifetime),
+		CreatedAt:     now,
+	}
+	if err := ah.codeRepository.CreateCode(context.Background(), authorizationCode); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save authorization code"})
+		return
+	}
+
+	params.Set("code", code)
+	c.JSON(http.StatusOK, gin.H{"redirect_uri": withQuery(req.RedirectURI, params)})
+}
+
+// validateAuthorizationRequest checks the request against the client's registration. The
+// redirect URI defaults to the client's only one and the scopes to all the client's scopes.
+func (ah *AuthorizationServerHandler) validateAuthorizationRequest(req *authorizationRequest) (*models.OAuthClient, []string, *oauthError) {
+	if req.ResponseType != "code" {
+		return nil, nil, &oauthError{"unsupported_response_type", "Only the code response type is supported"}
+	}
+
+	client, err := ah.clientRepository.GetClientByClientID(context.Background(), req.ClientID)
+	if err != nil {
+		return nil, nil, &oauthError{"invalid_client", "Unknown client"}
+	}
+	if !client.AllowsGrant(models.GrantTypeAuthorizationCode) {
+		return nil, nil, &oauthError{"unauthorized_client", "The client is not allowed to use the authorization code grant"}
+	}
+
+	// Codes are only ever sent to a registered redirect URI
+	if req.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.RedirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, nil, &oauthError{"invalid_request", "Redirect URI is not registered for the client"}
+	}
+
+	// Public clients can't keep a secret, so they must prove they started the request
+	if req.CodeChallenge == "" {
+		if client.Public {
+			return nil, nil, &oauthError{"invalid_request", "Code challenge is required"}
+		}
+	} else if req.CodeChallengeMethod != codeChallengeMethodS256 {
+		return nil, nil, &oauthError{"invalid_request", "Code challenge method must be S256"}
+	}
+
+	requestedScopes, oerr := clientScopes(client.Scopes, req.Scope)
+	if oerr != nil {
+		return nil, nil, oerr
+	}
+
+	return client, requestedScopes, nil
+}
+
+// Token exchanges a grant for tokens (RFC 6749 section 3.2). Parameters are form-encoded and
+// clients authenticate with HTTP Basic or the client_id and client_secret parameters.
+func (ah *AuthorizationServerHandler) Token(c *gin.Context) {
+	client, ok := ah.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	if !client.AllowsGrant(grantType) {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"unauthorized_client", "The client is not allowed to use this grant type"})
+		return
+	}
+
+	switch grantType {
+	case models.GrantTypeAuthorizationCode:
+		ah.exchangeAuthorizationCode(c, client)
+	case models.GrantTypeClientCredentials:
+		ah.exchangeClientCredentials(c, client)
+	case models.GrantTypeRefreshToken:
+		ah.exchangeRefreshToken(c, client)
+	default:
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"unsupported_grant_type", "Unsupported grant type"})
+	}
+}
+
+func (ah *AuthorizationServerHandler) exchangeAuthorizationCode(c *gin.Context, client *models.OAuthClient) {
+	code := c.PostForm("code")
+	if code == "" {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_request", "Code is required"})
+		return
+	}
+
+	// Codes are deleted as they are read, so they can't be used twice. Only the client they were
+	// issued to can use them up.
+	authorizationCode, err := ah.codeRepository.TakeCode(context.Background(), utils.HashOpaqueToken(code), client.ClientID)
+	if err != nil || time.Now().After(authorizationCode.ExpiresAt) {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid or expired authorization code"})
+		return
+	}
+
+	// Codes bound to a redirect URI are only exchanged along with the same URI (RFC 6749
+	// section 4.1.3)
+	if authorizationCode.RedirectURI != "" && c.PostForm("redirect_uri") != authorizationCode.RedirectURI {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_grant", "Redirect URI does not match the authorization request"})
+		return
+	}
+
+	if authorizationCode.CodeChallenge != "" {
+		verifier := c.PostForm("code_verifier")
+		digest := sha256.Sum256([]byte(verifier))
+		challenge := base64.RawURLEncoding.EncodeToString(digest[:])
+		if verifier == "" || subtle.ConstantTimeCompare([]byte(challenge), []byte(authorizationCode.CodeChallenge)) != 1 {
+			respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid code verifier"})
+			return
+		}
+	}
+
+	ah.issueTokens(c, client, &models.APIToken{
+		UserID: authorizationCode.UserID,
+		Scopes: authorizationCode.Scopes,
+	}, client.AllowsGrant(models.GrantTypeRefreshToken))
+}
+
+// exchangeClientCredentials issues a token acting on the client's organization with the
+// client's access level, like an API key.
+func (ah *AuthorizationServerHandler) exchangeClientCredentials(c *gin.Context, client *models.OAuthClient) {
+	if client.Public {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"unauthorized_client", "Public clients can't use the client credentials grant"})
+		return
+	}
+
+	requestedScopes, oerr := clientScopes(client.Scopes, c.PostForm("scope"))
+	if oerr != nil {
+		respondOAuthError(c, http.StatusBadRequest, oerr)
+		return
+	}
+
+	ah.issueTokens(c, client, &models.APIToken{
+		OrganizationID: client.OrganizationID,
+		AccessLevel:    client.AccessLevel,
+		Scopes:         requestedScopes,
+	}, false)
+}
+
+// exchangeRefreshToken rotates a refresh token: the presented token is revoked and a new one is
+// issued along with the access token. The scopes can only be narrowed.
+func (ah *AuthorizationServerHandler) exchangeRefreshToken(c *gin.Context, client *models.OAuthClient) {
+	refreshToken := c.PostForm("refresh_token")
+	if refreshToken == "" {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_request", "Refresh token is required"})
+		return
+	}
+
+	previous, err := ah.apiTokenRepository.TakeClientToken(context.Background(), utils.HashOpaqueToken(refreshToken), client.ClientID, models.TokenTypeOAuthRefreshToken)
+	if err != nil || previous.Expired() {
+		respondOAuthError(c, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid or expired refresh token"})
+		return
+	}
+
+	requestedScopes, oerr := clientScopes(previous.Scopes, c.PostForm("scope"))
+	if oerr != nil {
+		respondOAuthError(c, http.StatusBadRequest, oerr)
+		return
+	}
+
+	ah.issueTokens(c, client, &models.APIToken{
+		UserID:         previous.UserID,
+		OrganizationID: previous.OrganizationID,
+		AccessLevel:    previous.AccessLevel,
+		Scopes:         requestedScopes,
+	}, true)
+}
+
+// issueTokens stores an access token, and a refresh token if requested, with the grant's
+// subject and scopes, and returns them to the client.
+func (ah *AuthorizationServerHandler) issueTokens(c *gin.Context, client *models.OAuthClient, grant *models.APIToken, withRefreshToken bool) {
+	accessToken, err := ah.storeToken(client, grant, models.TokenTypeOAuthAccessToken, utils.OAuthAccessTokenPrefix, oauthAccessTokenLifetime)
+	if err != nil {
+		log.Println("Error saving OAuth access token:", err)
+		respondOAuthError(c, http.StatusInternalServerError, &oauthError{"server_error", "Failed to issue token"})
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(oauthAccessTokenLifetime.Seconds()),
+		"scope":        strings.Join(grant.Scopes, " "),
+	}
+
+	if withRefreshToken {
+		refreshToken, err := ah.storeToken(client, grant, models.TokenTypeOAuthRefreshToken, utils.OAuthRefreshTokenPrefix, oauthRefreshTokenLifetime)
+		if err != nil {
+			log.Println("Error saving OAuth refresh token:", err)
+			respondOAuthError(c, http.StatusInternalServerError, &oauthError{"server_error", "Failed to issue token"})
+			return
+		}
+		response["refresh_token"] = refreshToken
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
+
+func (ah *AuthorizationServerHandler) storeToken(client *models.OAuthClient, grant *models.APIToken, tokenType, prefix string, lifetime time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	apiToken := &models.APIToken{
+		ID:             primitive.NewObjectID(),
+		Type:           tokenType,
+		Name:           client.Name,
+		Prefix:         token[:displayedPrefixLength],
+		TokenHash:      utils.HashOpaqueToken(token),
+		UserID:         grant.UserID,
+		OrganizationID: grant.OrganizationID,
+		AccessLevel:    grant.AccessLevel,
+		ClientID:       client.ClientID,
+		Scopes:         grant.Scopes,
+		ExpiresAt:      now.Add(lifetime),
+		CreatedAt:      now,
+	}
+	if err := ah.apiTokenRepository.CreateToken(context.Background(), apiToken); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Introspect describes a token issued to the calling client (RFC 7662). Tokens of other
+// clients, and unknown or expired tokens, are reported as inactive.
+func (ah *AuthorizationServerHandler) Introspect(c *gin.Context) {
+	client, ok := ah.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	apiToken, err := ah.apiTokenRepository.GetTokenByHash(context.Background(), utils.HashOpaqueToken(token))
+	if token == "" || err != nil || apiToken.ClientID != client.ClientID || apiToken.Expired() {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"scope":      strings.Join(apiToken.Scopes, " "),
+		"client_id":  apiToken.ClientID,
+		"token_type": "access_token",
+		"exp":        apiToken.ExpiresAt.Unix(),
+		"iat":        apiToken.CreatedAt.Unix(),
+	}
+	if apiToken.Type == models.TokenTypeOAuthRefreshToken {
+		response["token_type"] = "refresh_token"
+	}
+	if !apiToken.UserID.IsZero() {
+		response["sub"] = apiToken.UserID.Hex()
+	}
+	if !apiToken.OrganizationID.IsZero() {
+		response["organization_id"] = apiToken.OrganizationID.Hex()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke revokes a token issued to the calling client (RFC 7009). Unknown tokens are ignored,
+// so the response doesn't reveal whether the token existed.
+func (ah *AuthorizationServerHandler) Revoke(c *gin.Context) {
+	client, ok := ah.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	if token := c.PostForm("token"); token != "" {
+		if _, err := ah.apiTokenRepository.TakeClientToken(context.Background(), utils.HashOpaqueToken(token), client.ClientID, ""); err != nil && err != mongo.ErrNoDocuments {
+			log.Println("Error revoking OAuth token:", err)
+			respondOAuthError(c, http.StatusServiceUnavailable, &oauthError{"temporarily_unavailable", "Failed to revoke token"})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Metadata publishes the authorization server metadata (RFC 8414).
+func (ah *AuthorizationServerHandler) Metadata(c *gin.Context) {
+	baseURL := strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/")
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                baseURL,
+		"authorization_endpoint":                baseURL + "/oauth/authorize",
+		"token_endpoint":                        baseURL + "/oauth/token",
+		"introspection_endpoint":                baseURL + "/oauth/introspect",
+		"revocation_endpoint":                   baseURL + "/oauth/revoke",
+		"scopes_supported":                      scopes.All,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{models.GrantTypeAuthorizationCode, models.GrantTypeClientCredentials, models.GrantTypeRefreshToken},
+		"code_challenge_methods_supported":      []string{codeChallengeMethodS256},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+// authenticateClient identifies the client calling the token, introspection or revocation
+// endpoint. Confidential clients must present their secret, public clients only their ID.
+func (ah *AuthorizationServerHandler) authenticateClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// Credentials in the Authorization header are form-encoded (RFC 6749 section 2.3.1)
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	client, err := ah.clientRepository.GetClientByClientID(context.Background(), clientID)
+	authenticated := err == nil
+	if authenticated && !client.Public {
+		authenticated = secret != "" && subtle.ConstantTimeCompare([]byte(utils.HashOpaqueToken(secret)), []byte(client.SecretHash)) == 1
+	}
+
+	if !authenticated {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		respondOAuthError(c, http.StatusUnauthorized, &oauthError{"invalid_client", "Client authentication failed"})
+		return nil, false
+	}
+
+	return client, true
+}
+
+// clientScopes resolves the space-separated scopes requested from the granted ones, which are
+// all returned when none are requested.
+func clientScopes(granted []string, scope string) ([]string, *oauthError) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return granted, nil
+	}
+
+	requested, err := scopes.Normalize(requested)
+	if err != nil || len(scopes.Missing(granted, requested)) > 0 {
+		return nil, &oauthError{"invalid_scope", "Requested scopes exceed the scopes granted to the client"}
+	}
+
+	return requested, nil
+}
+
+// withQuery appends params to the query of uri.
+func withQuery(uri string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + params.Encode()
+}
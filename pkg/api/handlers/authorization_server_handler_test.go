@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+)
+
+// postForm serves a form-encoded POST request.
+func postForm(router *gin.Engine, target string, form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	client := &models.OAuthClient{
+		ID:           primitive.NewObjectID(),
+		ClientID:     "client-1",
+		Name:         "Example app",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		GrantTypes:   []string{models.GrantTypeAuthorizationCode},
+		Scopes:       []string{scopes.OrgsRead},
+		Public:       true,
+	}
+	verifier := "a verifier long enough to be hard to guess"
+	digest := sha256.Sum256([]byte(verifier))
+	code := &models.OAuthAuthorizationCode{
+		ID:            primitive.NewObjectID(),
+		ClientID:      client.ClientID,
+		UserID:        primitive.NewObjectID(),
+		RedirectURI:   "https://app.example.com/callback",
+		Scopes:        []string{scopes.OrgsRead},
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(digest[:]),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	exchange := func(mt *mtest.T, form url.Values) *httptest.ResponseRecorder {
+		ah := &AuthorizationServerHandler{
+			clientRepository:   repository.NewOAuthClientRepository(mt.DB),
+			codeRepository:     repository.NewAuthorizationCodeRepository(mt.DB),
+			apiTokenRepository: repository.NewAPITokenRepository(mt.DB, cache.NewLRU(16)),
+		}
+		router := gin.New()
+		router.POST("/oauth/token", ah.Token)
+
+		form.Set("grant_type", models.GrantTypeAuthorizationCode)
+		form.Set("client_id", client.ClientID)
+		form.Set("code", "the code")
+		return postForm(router, "/oauth/token", form)
+	}
+	codeFound := func(mt *mtest.T, code *models.OAuthAuthorizationCode) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.oauth_clients", mtest.FirstBatch, mockDocument(mt, client)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, code)}),
+		)
+	}
+
+	mt.Run("issues a token with the approved scopes", func(mt *mtest.T) {
+		codeFound(mt, code)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		recorder := exchange(mt, url.Values{"redirect_uri": {code.RedirectURI}, "code_verifier": {verifier}})
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("exchange returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		var response struct {
+			Scope string `json:"scope"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Scope != scopes.OrgsRead {
+			mt.Errorf("scope = %s, want %s", response.Scope, scopes.OrgsRead)
+		}
+	})
+
+	mt.Run("only takes codes issued to the client", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.oauth_clients", mtest.FirstBatch, mockDocument(mt, client)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+
+		recorder := exchange(mt, url.Values{"redirect_uri": {code.RedirectURI}, "code_verifier": {verifier}})
+		if recorder.Code != http.StatusBadRequest {
+			mt.Errorf("exchange returned %d, want %d", recorder.Code, http.StatusBadRequest)
+		}
+		take := mt.GetAllStartedEvents()[1]
+		if clientID, err := take.Command.LookupErr("query", "client_id"); err != nil || clientID.StringValue() != client.ClientID {
+			mt.Errorf("code taken with %v, want it filtered on the client", take.Command.Lookup("query"))
+		}
+	})
+
+	mt.Run("requires the redirect URI the code is bound to", func(mt *mtest.T) {
+		for _, redirectURI := range []string{"", "https://app.example.com/other"} {
+			codeFound(mt, code)
+
+			recorder := exchange(mt, url.Values{"redirect_uri": {redirectURI}, "code_verifier": {verifier}})
+			if recorder.Code != http.StatusBadRequest {
+				mt.Errorf("exchange with redirect URI %q returned %d, want %d", redirectURI, recorder.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	mt.Run("code not bound to a redirect URI", func(mt *mtest.T) {
+		unbound := *code
+		unbound.RedirectURI = ""
+		codeFound(mt, &unbound)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		recorder := exchange(mt, url.Values{"code_verifier": {verifier}})
+		if recorder.Code != http.StatusOK {
+			mt.Errorf("exchange returned %d %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	mt.Run("invalid code verifier", func(mt *mtest.T) {
+		codeFound(mt, code)
+
+		recorder := exchange(mt, url.Values{"redirect_uri": {code.RedirectURI}, "code_verifier": {"another verifier"}})
+		if recorder.Code != http.StatusBadRequest {
+			mt.Errorf("exchange returned %d, want %d", recorder.Code, http.StatusBadRequest)
+		}
+	})
+}
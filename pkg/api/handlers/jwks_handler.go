@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/signing"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys access tokens are signed with, so other services can
+// verify tokens without sharing a secret.
+type JWKSHandler struct {
+	keys *signing.Rotator
+}
+
+// NewJWKSHandler creates the handler. keys is nil while tokens are signed with a shared
+// secret, in which case the key set is empty.
+func NewJWKSHandler(keys *signing.Rotator) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+func (jh *JWKSHandler) GetJWKS(c *gin.Context) {
+	keySet := signing.JWKSet{Keys: []signing.JWK{}}
+	if jh.keys != nil {
+		keySet = jh.keys.JWKS()
+	}
+
+	// Verifiers may cache the key set, new keys are published well before being used
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keySet)
+}
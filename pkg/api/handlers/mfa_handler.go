@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaSigninRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// EnrollTOTP starts two-factor authentication enrollment by generating a new secret. The secret
+// only becomes active once confirmed with ConfirmTOTP.
+func (uh *UserHandler) EnrollTOTP(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.MFAEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	// Generate a new secret for the user's authenticator app
+	key, err := utils.GenerateTOTPKey(user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate TOTP secret"})
+		return
+	}
+
+	if err := uh.userRepository.SetPendingTOTPSecret(context.Background(), user.ID, key.Secret()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save TOTP secret"})
+		return
+	}
+	uh.recordMFAEvent(c, user, models.AuditActionUserMFAEnrollment, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Scan the QR code or enter the secret in your authenticator app, then confirm with a code",
+		"secret":      key.Secret(),
+		"otpauth_uri": key.URL(),
+	})
+}
+
+// GetTOTPQRCode renders the secret of a pending enrollment as a PNG QR code.
+func (uh *UserHandler) GetTOTPQRCode(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.PendingTOTPSecret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No two-factor authentication enrollment in progress"})
+		return
+	}
+
+	key, err := utils.TOTPKey(user.Email, user.PendingTOTPSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load TOTP secret"})
+		return
+	}
+
+	qrCode, err := utils.TOTPQRCode(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", qrCode)
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their authenticator app
+// is set up, and returns the recovery codes. They are only shown this once.
+func (uh *UserHandler) ConfirmTOTP(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if user.PendingTOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No two-factor authentication enrollment in progress"})
+		return
+	}
+
+	// Verify the code against the pending secret
+	totpStep, valid := utils.VerifyTOTP(req.Code, user.PendingTOTPSecret)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	// Generate recovery codes, only their hashes are stored
+	recoveryCodes, recoveryCodeHashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	if err := uh.userRepository.EnableTOTP(context.Background(), user.ID, user.PendingTOTPSecret, recoveryCodeHashes, totpStep); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	uh.recordMFAEvent(c, user, models.AuditActionUserMFAEnabled, map[string]models.AuditChange{
+		"mfa_enabled": {Before: false, After: true},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTOTP turns two-factor authentication off after checking a current code.
+func (uh *UserHandler) DisableTOTP(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !user.MFAEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// Codes are only accepted once
+	totpStep, valid := utils.VerifyTOTP(req.Code, user.TOTPSecret)
+	if valid {
+		var err error
+		if valid, err = uh.userRepository.UseTOTPStep(context.Background(), user.ID, totpStep); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := uh.userRepository.DisableTOTP(context.Background(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	uh.recordMFAEvent(c, user, models.AuditActionUserMFADisabled, map[string]models.AuditChange{
+		"mfa_enabled": {Before: true, After: false},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// SigninMFA completes a signin started with Signin by exchanging the MFA token and a TOTP or
+// recovery code for an access token and a refresh token.
+func (uh *UserHandler) SigninMFA(c *gin.Context) {
+	var req mfaSigninRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Verify the MFA token issued by Signin
+	userID, err := utils.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Codes are short, so guessing them is limited by the same lockout as passwords
+	accountKey := "mfa:" + userID
+	lockedFor, err := uh.signinLockout.Locked(context.Background(), accountKey)
+	if err != nil {
+		log.Println("Error checking MFA lockout:", err)
+	}
+	if lockedFor > 0 {
+		c.Header("Retry-After", strconv.FormatInt(int64(lockedFor.Seconds())+1, 10))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+		return
+	}
+
+	foundUser, err := uh.userRepository.GetUserByID(context.Background(), objectID)
+	if err != nil || !foundUser.MFAEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Accept either a code from the authenticator app or an unused recovery code
+	valid := false
+	switch {
+	case req.Code != "":
+		// Codes are only accepted once
+		var totpStep int64
+		if totpStep, valid = utils.VerifyTOTP(req.Code, foundUser.TOTPSecret); valid {
+			valid, err = uh.userRepository.UseTOTPStep(context.Background(), foundUser.ID, totpStep)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+				return
+			}
+		}
+	case req.RecoveryCode != "":
+		var recoveryCodeHash string
+		recoveryCodeHash, err = utils.HashRecoveryCode(req.RecoveryCode)
+		if err == nil {
+			valid, err = uh.userRepository.UseRecoveryCode(context.Background(), foundUser.ID, recoveryCodeHash)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify recovery code"})
+			return
+		}
+	}
+
+	if !valid {
+		uh.recordSigninFailure(accountKey)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := uh.signinLockout.Reset(context.Background(), accountKey); err != nil {
+		log.Println("Error resetting MFA lockout:", err)
+	}
+
+	completeSignin(c, uh.userRepository, uh.auditEventRepository, foundUser)
+}
+
+func (uh *UserHandler) recordMFAEvent(c *gin.Context, user *models.User, action string, changes map[string]models.AuditChange) {
+	recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.Hex(),
+		Changes:    changes,
+	})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	AccessLevel  string   `json:"access_level"`
+	Public       bool     `json:"public"`
+}
+
+func (ah *AuthorizationServerHandler) GetClients(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	clients, err := ah.clientRepository.GetOrganizationClients(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve OAuth clients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// CreateClient registers a third-party application of the organization. Confidential clients
+// get a secret, which is only returned once.
+func (ah *AuthorizationServerHandler) CreateClient(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var req createOAuthClientRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	client := &models.OAuthClient{
+		Name:           strings.TrimSpace(req.Name),
+		OrganizationID: organizationID,
+		RedirectURIs:   req.RedirectURIs,
+		GrantTypes:     req.GrantTypes,
+		AccessLevel:    req.AccessLevel,
+		Public:         req.Public,
+		CreatedBy:      userID,
+	}
+	if client.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	// Clients act on behalf of users unless other grant types are requested
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = []string{models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken}
+	}
+	for _, grantType := range client.GrantTypes {
+		switch grantType {
+		case models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken:
+		case models.GrantTypeClientCredentials:
+			if client.Public {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Public clients can't use the client credentials grant"})
+				return
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported grant type " + grantType})
+			return
+		}
+	}
+
+	if client.AllowsGrant(models.GrantTypeAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one redirect URI is required"})
+		return
+	}
+	for _, redirectURI := range client.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect URI " + redirectURI})
+			return
+		}
+	}
+
+	// The client credentials grant acts on the organization, as a member unless admin is requested
+	if client.AccessLevel == "" {
+		client.AccessLevel = models.AccessLevelMember
+	}
+	if client.AccessLevel != models.AccessLevelMember && client.AccessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+
+	// Clients can't be granted more than the scopes they were registered with
+	client.Scopes, err = scopes.Normalize(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(client.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+
+	client.ClientID, err = utils.GenerateOpaqueToken(utils.OAuthClientIDPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate client ID"})
+		return
+	}
+
+	var secret string
+	if !client.Public {
+		secret, err = utils.GenerateOpaqueToken(utils.OAuthClientSecretPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate client secret"})
+			return
+		}
+		client.SecretHash = utils.HashOpaqueToken(secret)
+	}
+
+	client.ID = primitive.NewObjectID()
+	client.CreatedAt = time.Now()
+	if err := ah.clientRepository.CreateClient(context.Background(), client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save OAuth client"})
+		return
+	}
+
+	// The secret is only stored hashed, which is left out of the audit log
+	recordAuditEvent(c, ah.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionOAuthClientCreated,
+		TargetType:     models.AuditTargetOAuthClient,
+		TargetID:       client.ClientID,
+		Changes:        auditDiff(nil, client),
+	})
+
+	response := gin.H{
+		"message": "OAuth client created successfully",
+		"client":  client,
+	}
+	if secret != "" {
+		response["client_secret"] = secret
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteClient removes a client along with every token issued to it.
+func (ah *AuthorizationServerHandler) DeleteClient(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	clientID := c.Param("client_id")
+	if err := ah.clientRepository.DeleteOrganizationClient(context.Background(), clientID, organizationID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "OAuth client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete OAuth client"})
+		return
+	}
+
+	if err := ah.apiTokenRepository.DeleteClientTokens(context.Background(), clientID); err != nil {
+		log.Println("Error revoking tokens of OAuth client:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens of OAuth client"})
+		return
+	}
+
+	recordAuditEvent(c, ah.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionOAuthClientDeleted,
+		TargetType:     models.AuditTargetOAuthClient,
+		TargetID:       clientID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "OAuth client deleted successfully"})
+}
+
+// validRedirectURI reports whether uri is absolute and without a fragment. Plain HTTP is only
+// allowed for loopback addresses, used by native applications.
+func validRedirectURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" || parsed.Fragment != "" {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return true
+	case "http":
+		hostname := parsed.Hostname()
+		return hostname == "localhost" || hostname == "127.0.0.1" || hostname == "::1"
+	default:
+		return false
+	}
+}
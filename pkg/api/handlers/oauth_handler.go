@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/oauth"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/oauth2"
+)
+
+// loginStateTTL is how long a user has to complete signin at the provider, and to confirm
+// their password when the identity is linked to an existing account.
+const loginStateTTL = 10 * time.Minute
+
+// errPasswordRequired is returned when an identity would be linked to an account whose email
+// address wasn't verified, which only its password can prove the user owns.
+var errPasswordRequired = errors.New("password required to link the identity")
+
+// pendingIdentityLink is kept until the user confirms the password of the account an identity
+// is linked to.
+type pendingIdentityLink struct {
+	UserID   primitive.ObjectID  `bson:"user_id"`
+	Identity models.UserIdentity `bson:"identity"`
+}
+
+// GetOAuthProviders lists the external identity providers users can sign in with.
+func (uh *UserHandler) GetOAuthProviders(c *gin.Context) {
+	names := make([]string, 0, len(uh.oauthProviders))
+	for name := range uh.oauthProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+// OAuthLogin redirects the user to the provider's signin page, starting an authorization code
+// flow protected by PKCE.
+func (uh *UserHandler) OAuthLogin(c *gin.Context) {
+	provider, ok := uh.oauthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown OAuth provider"})
+		return
+	}
+
+	// Remember the PKCE verifier and nonce until the provider redirects back with the state
+	state := oauth2.GenerateVerifier()
+	loginState := oauth.LoginState{
+		Provider:     provider.Name(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        oauth2.GenerateVerifier(),
+	}
+	if err := uh.loginStates.Set(context.Background(), loginStateKey(state), &loginState, loginStateTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start signin"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state, loginState.CodeVerifier, loginState.Nonce))
+}
+
+// OAuthCallback completes the authorization code flow and signs the user in, linking the
+// provider's account to an existing user with the same verified email address or creating
+// a new user. Existing users whose email address wasn't verified must confirm their password
+// with LinkOAuthIdentity first.
+func (uh *UserHandler) OAuthCallback(c *gin.Context) {
+	provider, ok := uh.oauthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown OAuth provider"})
+		return
+	}
+
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Signin was denied by the provider"})
+		return
+	}
+
+	// Load the state saved by OAuthLogin, it can only be used once
+	state := c.Query("state")
+	var loginState oauth.LoginState
+	found, err := uh.loginStates.Get(context.Background(), loginStateKey(state), &loginState)
+	if err != nil || !found || loginState.Provider != provider.Name() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired signin state"})
+		return
+	}
+	if err := uh.loginStates.Delete(context.Background(), loginStateKey(state)); err != nil {
+		log.Println("Error deleting OAuth login state:", err)
+	}
+
+	// Exchange the authorization code for the user's identity
+	identity, err := provider.Exchange(context.Background(), c.Query("code"), loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Println("Error exchanging OAuth authorization code:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to sign in with the provider"})
+		return
+	}
+
+	// Members of organizations enforcing single sign-on must use their identity provider
+	if organization := ssoOrganizationFor(context.Background(), uh.organizationRepository, identity.Email); organization != nil {
+		respondSSORequired(c, organization)
+		return
+	}
+
+	user, linked, err := uh.userForIdentity(context.Background(), identity)
+	if err != nil {
+		if errors.Is(err, oauth.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The provider did not verify your email address"})
+			return
+		}
+		if errors.Is(err, errPasswordRequired) {
+			uh.requirePassword(c, provider.Name(), user, identity)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in with the provider"})
+		return
+	}
+
+	// The provider verified the user's email, the organization owning its domain may let them in
+	if linked {
+		uh.autoJoinDomainOrganization(c, user)
+	}
+
+	finishSignin(c, uh.userRepository, uh.auditEventRepository, user)
+}
+
+// userForIdentity finds the user linked to an external identity, links it to the user with
+// the same verified email address, or creates a new user. It reports whether the identity was
+// linked by this signin. Anyone may sign up with a password for an email address they don't
+// own, so users whose address wasn't verified are returned with errPasswordRequired instead of
+// being linked.
+func (uh *UserHandler) userForIdentity(ctx context.Context, identity *oauth.Identity) (*models.User, bool, error) {
+	// Sign in users who already linked this identity
+	user, err := uh.userRepository.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, false, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, false, oauth.ErrEmailNotVerified
+	}
+
+	linkedIdentity := models.UserIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: time.Now(),
+	}
+
+	// Link the identity to an existing user with the same email address
+	user, err = uh.userRepository.GetUserByEmail(ctx, identity.Email)
+	if err == nil {
+		if !emailVerified(user) {
+			return user, false, errPasswordRequired
+		}
+		if err := uh.userRepository.AddIdentity(ctx, user.ID, linkedIdentity); err != nil {
+			return nil, false, err
+		}
+		user.Identities = append(user.Identities, linkedIdentity)
+		return user, true, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, err
+	}
+
+	// Otherwise create a user without a password, who can only sign in through providers
+	user = &models.User{
+		ID:         primitive.NewObjectID(),
+		Name:       identity.Name,
+		Email:      identity.Email,
+		Identities: []models.UserIdentity{linkedIdentity},
+	}
+	if err := uh.userRepository.CreateUser(ctx, user); err != nil {
+		return nil, false, err
+	}
+
+	return user, true, nil
+}
+
+// requirePassword keeps the identity to link until the user confirms the password of the
+// account with the same email address, and responds with the token to confirm it with.
+func (uh *UserHandler) requirePassword(c *gin.Context, provider string, user *models.User, identity *oauth.Identity) {
+	// Accounts without a password must first be linked to a provider verifying their address
+	if user.Password == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account already uses this email address"})
+		return
+	}
+
+	linkToken := oauth2.GenerateVerifier()
+	pending := pendingIdentityLink{
+		UserID: user.ID,
+		Identity: models.UserIdentity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		},
+	}
+	if err := uh.loginStates.Set(context.Background(), identityLinkKey(provider, linkToken), &pending, loginStateTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in with the provider"})
+		return
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":      "Sign in with the password of your account to link it to the provider",
+		"code":       "password_required",
+		"link_token": linkToken,
+	})
+}
+
+// LinkOAuthIdentity links the identity of a provider to the existing account with the same
+// email address once the user confirmed its password, then signs them in. Attempts count
+// towards the same limits as signing in with the password.
+func (uh *UserHandler) LinkOAuthIdentity(c *gin.Context) {
+	provider, ok := uh.oauthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown OAuth provider"})
+		return
+	}
+
+	var req struct {
+		LinkToken string `json:"link_token"`
+		Password  string `json:"password"`
+	}
+	if err := c.BindJSON(&req); err != nil || req.LinkToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var pending pendingIdentityLink
+	key := identityLinkKey(provider.Name(), req.LinkToken)
+	found, err := uh.loginStates.Get(context.Background(), key, &pending)
+	if err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired link token"})
+		return
+	}
+
+	user, err := uh.userRepository.GetUserByID(context.Background(), pending.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired link token"})
+		return
+	}
+
+	accountKey := strings.ToLower(strings.TrimSpace(user.Email))
+	if !uh.allowSignin(c, accountKey) {
+		return
+	}
+	if !utils.VerifyPassword(req.Password, user.Password) {
+		uh.recordSigninFailure(accountKey)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+	if err := uh.signinLockout.Reset(context.Background(), accountKey); err != nil {
+		log.Println("Error resetting signin lockout:", err)
+	}
+
+	// The token is only used once
+	if err := uh.loginStates.Delete(context.Background(), key); err != nil {
+		log.Println("Error deleting pending identity link:", err)
+	}
+
+	pending.Identity.LinkedAt = time.Now()
+	if err := uh.userRepository.AddIdentity(context.Background(), user.ID, pending.Identity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link the provider"})
+		return
+	}
+	user.Identities = append(user.Identities, pending.Identity)
+
+	uh.autoJoinDomainOrganization(c, user)
+	finishSignin(c, uh.userRepository, uh.auditEventRepository, user)
+}
+
+func identityLinkKey(provider, linkToken string) string {
+	return "oauth-link:" + provider + ":" + linkToken
+}
+
+func loginStateKey(state string) string {
+	return "oauth-state:" + state
+}
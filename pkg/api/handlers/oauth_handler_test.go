@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/oauth"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+)
+
+// testProvider records the signins it is asked for and fails every exchange.
+type testProvider struct {
+	name      string
+	exchanges int
+}
+
+func (tp *testProvider) Name() string {
+	return tp.name
+}
+
+func (tp *testProvider) AuthCodeURL(state, codeVerifier, nonce string) string {
+	return "https://idp.example.com/authorize?" + url.Values{
+		"state":         {state},
+		"code_verifier": {codeVerifier},
+		"nonce":         {nonce},
+	}.Encode()
+}
+
+func (tp *testProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oauth.Identity, error) {
+	tp.exchanges++
+	return nil, errors.New("invalid_grant")
+}
+
+func newOAuthTestRouter(providers ...oauth.Provider) (*gin.Engine, *UserHandler) {
+	gin.SetMode(gin.TestMode)
+
+	uh := &UserHandler{
+		oauthProviders: map[string]oauth.Provider{},
+		loginStates:    cache.NewLRU(16),
+	}
+	for _, provider := range providers {
+		uh.oauthProviders[provider.Name()] = provider
+	}
+
+	router := gin.New()
+	router.GET("/users/oauth/:provider/login", uh.OAuthLogin)
+	router.GET("/users/oauth/:provider/callback", uh.OAuthCallback)
+	return router, uh
+}
+
+func serve(router *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+// startOAuthLogin starts a signin with the provider and returns the query of the redirect to it.
+func startOAuthLogin(t *testing.T, router *gin.Engine, provider string) url.Values {
+	t.Helper()
+
+	recorder := serve(router, http.MethodGet, "/users/oauth/"+provider+"/login")
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("login returned %d, want %d", recorder.Code, http.StatusFound)
+	}
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
+
+func TestOAuthLoginSavesState(t *testing.T) {
+	router, uh := newOAuthTestRouter(&testProvider{name: "corp"})
+
+	query := startOAuthLogin(t, router, "corp")
+	if query.Get("state") == "" || query.Get("code_verifier") == "" || query.Get("nonce") == "" {
+		t.Fatalf("redirect %v is missing the state, code verifier or nonce", query)
+	}
+
+	var loginState oauth.LoginState
+	found, err := uh.loginStates.Get(context.Background(), loginStateKey(query.Get("state")), &loginState)
+	if err != nil || !found {
+		t.Fatalf("login state not saved: found %v, error %v", found, err)
+	}
+	expected := oauth.LoginState{Provider: "corp", CodeVerifier: query.Get("code_verifier"), Nonce: query.Get("nonce")}
+	if loginState != expected {
+		t.Errorf("login state = %+v, want %+v", loginState, expected)
+	}
+
+	// Every signin gets its own state and PKCE verifier
+	other := startOAuthLogin(t, router, "corp")
+	if other.Get("state") == query.Get("state") || other.Get("code_verifier") == query.Get("code_verifier") {
+		t.Error("two signins share their state or code verifier")
+	}
+}
+
+func TestOAuthLoginRejectsUnknownProvider(t *testing.T) {
+	router, _ := newOAuthTestRouter(&testProvider{name: "corp"})
+
+	if recorder := serve(router, http.MethodGet, "/users/oauth/other/login"); recorder.Code != http.StatusNotFound {
+		t.Errorf("login returned %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestOAuthCallbackValidatesState(t *testing.T) {
+	corp := &testProvider{name: "corp"}
+	other := &testProvider{name: "other"}
+	router, _ := newOAuthTestRouter(corp, other)
+
+	state := startOAuthLogin(t, router, "corp").Get("state")
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"unknown provider", "/users/oauth/unknown/callback?code=code&state=" + state, http.StatusNotFound},
+		{"denied by the provider", "/users/oauth/corp/callback?error=access_denied&state=" + state, http.StatusUnauthorized},
+		{"missing state", "/users/oauth/corp/callback?code=code", http.StatusBadRequest},
+		{"unknown state", "/users/oauth/corp/callback?code=code&state=forged", http.StatusBadRequest},
+		{"state of another provider", "/users/oauth/other/callback?code=code&state=" + state, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if recorder := serve(router, http.MethodGet, test.target); recorder.Code != test.status {
+				t.Errorf("callback returned %d, want %d", recorder.Code, test.status)
+			}
+		})
+	}
+	if corp.exchanges != 0 || other.exchanges != 0 {
+		t.Fatalf("authorization code exchanged without a valid state")
+	}
+
+	// A valid state is used up by the callback, whether or not the signin succeeds
+	if recorder := serve(router, http.MethodGet, "/users/oauth/corp/callback?code=code&state="+state); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("callback returned %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if corp.exchanges != 1 {
+		t.Errorf("authorization code exchanged %d times, want once", corp.exchanges)
+	}
+	if recorder := serve(router, http.MethodGet, "/users/oauth/corp/callback?code=code&state="+state); recorder.Code != http.StatusBadRequest {
+		t.Errorf("replayed callback returned %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUserForIdentity(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	userID := primitive.NewObjectID()
+	identity := &oauth.Identity{Provider: "corp", Subject: "subject-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane"}
+	namespace := "test.users"
+
+	newHandler := func(mt *mtest.T) *UserHandler {
+		return &UserHandler{userRepository: repository.NewUserRepository(mt.DB, cache.NewLRU(16))}
+	}
+
+	mt.Run("signs in the linked user", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: userID},
+			{Key: "email", Value: "jane@example.com"},
+		}))
+
+		user, linked, err := newHandler(mt).userForIdentity(context.Background(), identity)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if user.ID != userID || linked {
+			mt.Errorf("got user %s, linked %v, want the linked user", user.ID.Hex(), linked)
+		}
+	})
+
+	mt.Run("links the user with the verified email", func(mt *mtest.T) {
+		// Another provider already vouched for the user's email
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: userID},
+				{Key: "email", Value: "jane@example.com"},
+				{Key: "identities", Value: bson.A{bson.D{
+					{Key: "provider", Value: "other"},
+					{Key: "subject", Value: "subject-2"},
+					{Key: "email", Value: "jane@example.com"},
+				}}},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		user, linked, err := newHandler(mt).userForIdentity(context.Background(), identity)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if user.ID != userID || !linked {
+			mt.Fatalf("got user %s, linked %v, want the user with the email, newly linked", user.ID.Hex(), linked)
+		}
+		if len(user.Identities) != 2 || user.Identities[1].Provider != "corp" || user.Identities[1].Subject != "subject-1" {
+			mt.Errorf("identities = %+v, want the identity of the provider", user.Identities)
+		}
+
+		update := mt.GetAllStartedEvents()[2]
+		if update.CommandName != "update" {
+			mt.Fatalf("third command is %s, want the update linking the identity", update.CommandName)
+		}
+		filter := update.Command.Lookup("updates", "0", "q", "_id")
+		if id, ok := filter.ObjectIDOK(); !ok || id != userID {
+			mt.Errorf("identity linked to %v, want user %s", filter, userID.Hex())
+		}
+	})
+
+	mt.Run("requires the password of a user with an unverified email", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: userID},
+				{Key: "email", Value: "jane@example.com"},
+				{Key: "password", Value: "hashed"},
+			}),
+		)
+
+		user, _, err := newHandler(mt).userForIdentity(context.Background(), identity)
+		if !errors.Is(err, errPasswordRequired) {
+			mt.Fatalf("error = %v, want %v", err, errPasswordRequired)
+		}
+		if user == nil || user.ID != userID {
+			mt.Errorf("got user %v, want the user with the email", user)
+		}
+		if started := mt.GetAllStartedEvents(); len(started) != 2 {
+			mt.Errorf("commands %v, want the identity left unlinked", commandNames(mt))
+		}
+	})
+
+	mt.Run("creates a user for a new verified email", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		user, linked, err := newHandler(mt).userForIdentity(context.Background(), identity)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if !linked || user.Email != "jane@example.com" || user.Password != "" || len(user.Identities) != 1 {
+			mt.Errorf("got user %+v, linked %v, want a new passwordless user with the identity", user, linked)
+		}
+	})
+
+	for name, unverified := range map[string]*oauth.Identity{
+		"rejects an unverified email": {Provider: "corp", Subject: "subject-1", Email: "jane@example.com"},
+		"rejects a missing email":     {Provider: "corp", Subject: "subject-1", EmailVerified: true},
+	} {
+		unverified := unverified
+		mt.Run(name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch))
+
+			_, _, err := newHandler(mt).userForIdentity(context.Background(), unverified)
+			if !errors.Is(err, oauth.ErrEmailNotVerified) {
+				mt.Fatalf("error = %v, want %v", err, oauth.ErrEmailNotVerified)
+			}
+			// The user with the same email must not even be looked up
+			if started := mt.GetAllStartedEvents(); len(started) != 1 {
+				mt.Errorf("%d commands sent, want only the identity lookup", len(started))
+			}
+		})
+	}
+}
+
+func TestLinkOAuthIdentity(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	password, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The user has two-factor authentication, so linking ends with the MFA challenge
+	user := &models.User{ID: primitive.NewObjectID(), Email: "jane@example.com", Password: string(password), MFAEnabled: true}
+	provider := &testProvider{name: "corp"}
+
+	// link confirms the password, with the given replies to the commands after the user is read
+	link := func(mt *mtest.T, uh *UserHandler, password string, replies ...bson.D) *httptest.ResponseRecorder {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user)))
+		mt.AddMockResponses(replies...)
+
+		router := gin.New()
+		router.POST("/users/oauth/:provider/link", uh.LinkOAuthIdentity)
+		return serveJSON(router, http.MethodPost, "/users/oauth/corp/link", `{"link_token": "the token", "password": "`+password+`"}`, nil)
+	}
+
+	mt.Run("links the identity once the password is confirmed", func(mt *mtest.T) {
+		store := ratelimit.NewMemoryStore()
+		uh := &UserHandler{
+			userRepository:         repository.NewUserRepository(mt.DB, cache.NewLRU(16)),
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			auditEventRepository:   repository.NewAuditEventRepository(mt.DB),
+			signinLimiter:          ratelimit.NewLimiter(store, "signin:account", 10, time.Minute),
+			signinLockout:          ratelimit.NewLockout(store, "signin", 5, time.Minute, time.Hour),
+			oauthProviders:         map[string]oauth.Provider{"corp": provider},
+			loginStates:            cache.NewLRU(16),
+		}
+		pending := pendingIdentityLink{UserID: user.ID, Identity: models.UserIdentity{Provider: "corp", Subject: "subject-1", Email: user.Email}}
+		if err := uh.loginStates.Set(context.Background(), identityLinkKey("corp", "the token"), &pending, loginStateTTL); err != nil {
+			mt.Fatal(err)
+		}
+
+		if recorder := link(mt, uh, "wrong"); recorder.Code != http.StatusUnauthorized {
+			mt.Fatalf("link with a wrong password returned %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+		for _, name := range commandNames(mt) {
+			if name == "update" {
+				mt.Fatalf("commands %v, want the identity left unlinked", commandNames(mt))
+			}
+		}
+
+		mt.ClearEvents()
+		linked := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+		noDomainOrganization := mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch)
+		if recorder := link(mt, uh, "correct horse", linked, noDomainOrganization); recorder.Code != http.StatusOK {
+			mt.Fatalf("link returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		update := mt.GetAllStartedEvents()[1]
+		if subject, err := update.Command.LookupErr("updates", "0", "u", "$push", "identities", "subject"); err != nil || subject.StringValue() != "subject-1" {
+			mt.Errorf("commands %v, want the identity linked", commandNames(mt))
+		}
+
+		if recorder := link(mt, uh, "correct horse"); recorder.Code != http.StatusBadRequest {
+			mt.Errorf("link token reused, returned %d", recorder.Code)
+		}
+	})
+}
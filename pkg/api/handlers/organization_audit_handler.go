@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Number of audit events returned per page, by default and at most
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 200
+)
+
+// GetAuditLog lists the audit events of the organization, most recent first. Events can be
+// filtered by action, actor, target and time range (RFC 3339). Further pages are requested
+// with the next_cursor of the previous page.
+func (oh *OrganizationHandler) GetAuditLog(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	filter := repository.AuditEventFilter{
+		OrganizationID: organizationID,
+		Action:         c.Query("action"),
+		TargetType:     c.Query("target_type"),
+		TargetID:       c.Query("target_id"),
+		Limit:          defaultAuditLogLimit,
+	}
+
+	if actorID := c.Query("actor_id"); actorID != "" {
+		if filter.ActorID, err = primitive.ObjectIDFromHex(actorID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor ID"})
+			return
+		}
+	}
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since time"})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until time"})
+			return
+		}
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if filter.Before, err = primitive.ObjectIDFromHex(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxAuditLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxAuditLogLimit)})
+			return
+		}
+	}
+
+	events, err := oh.auditEventRepository.GetEvents(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+
+	// A full page may be followed by more events
+	response := gin.H{"events": events}
+	if int64(len(events)) == filter.Limit {
+		response["next_cursor"] = events[len(events)-1].ID.Hex()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (oh *OrganizationHandler) GetDomains(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, domain := range organization.Domains {
+		response = append(response, domainResponse(domain))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddDomain claims an email domain for the organization and returns the DNS TXT record that
+// proves its ownership.
+func (oh *OrganizationHandler) AddDomain(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req struct {
+		Domain string `json:"domain"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	domainName := dnsverify.NormalizeDomain(req.Domain)
+	if domainName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid domain"})
+		return
+	}
+
+	// Generate the token to publish in DNS
+	token, err := dnsverify.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate verification token"})
+		return
+	}
+
+	domain := models.OrganizationDomain{
+		Domain:            domainName,
+		VerificationToken: token,
+	}
+	if err := oh.organizationRepository.AddDomain(context.Background(), organizationID, domain); err != nil {
+		if errors.Is(err, repository.ErrDomainExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain already added"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
+		return
+	}
+
+	oh.recordDomainEvent(c, organizationID, models.AuditActionDomainAdded, domain.Domain, nil)
+
+	c.JSON(http.StatusOK, domainResponse(domain))
+}
+
+// VerifyDomain checks the DNS TXT record of a domain and marks it verified when it matches.
+func (oh *OrganizationHandler) VerifyDomain(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	domain, ok := findDomain(organization, dnsverify.NormalizeDomain(c.Param("domain")))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+	if domain.Verified {
+		c.JSON(http.StatusOK, domainResponse(domain))
+		return
+	}
+
+	// A domain can only belong to one organization
+	owner, err := oh.organizationRepository.GetOrganizationByVerifiedDomain(context.Background(), domain.Domain)
+	if err == nil && owner.ID != organizationID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Domain already verified by another organization"})
+		return
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify domain"})
+		return
+	}
+
+	// Look up the TXT record
+	if err := dnsverify.Verify(context.Background(), oh.resolver, domain.Domain, domain.VerificationToken); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":        "Verification TXT record not found",
+			"record_name":  dnsverify.RecordName(domain.Domain),
+			"record_value": dnsverify.RecordValue(domain.VerificationToken),
+		})
+		return
+	}
+
+	if err := oh.organizationRepository.MarkDomainVerified(context.Background(), organizationID, domain.Domain); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify domain"})
+		return
+	}
+
+	domain.Verified = true
+	oh.recordDomainEvent(c, organizationID, models.AuditActionDomainVerified, domain.Domain, map[string]models.AuditChange{
+		"verified": {Before: false, After: true},
+	})
+	c.JSON(http.StatusOK, domainResponse(domain))
+}
+
+func (oh *OrganizationHandler) RemoveDomain(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	domainName := dnsverify.NormalizeDomain(c.Param("domain"))
+	if err := oh.organizationRepository.RemoveDomain(context.Background(), organizationID, domainName); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove domain"})
+		return
+	}
+
+	oh.recordDomainEvent(c, organizationID, models.AuditActionDomainRemoved, domainName, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Domain removed successfully"})
+}
+
+// SetDomainJoinPolicy sets whether users with a verified email at a verified domain of the
+// organization are offered to join it, added to it automatically, or neither, and the access
+// level they join with, member unless given.
+func (oh *OrganizationHandler) SetDomainJoinPolicy(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req struct {
+		Policy      string `json:"policy"`
+		AccessLevel string `json:"access_level"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Policy != models.DomainJoinNone && req.Policy != models.DomainJoinOffer && req.Policy != models.DomainJoinAuto {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Policy must be none, offer or auto"})
+		return
+	}
+	if req.AccessLevel == "" {
+		req.AccessLevel = models.AccessLevelMember
+	}
+	if req.AccessLevel != models.AccessLevelMember && req.AccessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	domain, ok := findDomain(organization, dnsverify.NormalizeDomain(c.Param("domain")))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+
+	// Only the owner of a domain may let its users in
+	if req.Policy != models.DomainJoinNone && !domain.Verified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Domain must be verified first"})
+		return
+	}
+
+	if err := oh.organizationRepository.SetDomainJoinPolicy(context.Background(), organizationID, domain.Domain, req.Policy, req.AccessLevel); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set domain join policy"})
+		return
+	}
+
+	before := domain
+	domain.JoinPolicy = req.Policy
+	domain.JoinAccessLevel = req.AccessLevel
+	oh.recordDomainEvent(c, organizationID, models.AuditActionDomainJoinPolicy, domain.Domain, auditDiff(before, domain))
+	c.JSON(http.StatusOK, domainResponse(domain))
+}
+
+func (oh *OrganizationHandler) recordDomainEvent(c *gin.Context, organizationID primitive.ObjectID, action, domainName string, changes map[string]models.AuditChange) {
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         action,
+		TargetType:     models.AuditTargetDomain,
+		TargetID:       domainName,
+		Changes:        changes,
+	})
+}
+
+func findDomain(organization *models.Organization, domainName string) (models.OrganizationDomain, bool) {
+	for _, domain := range organization.Domains {
+		if domain.Domain == domainName {
+			return domain, true
+		}
+	}
+	return models.OrganizationDomain{}, false
+}
+
+func domainResponse(domain models.OrganizationDomain) gin.H {
+	joinPolicy := domain.JoinPolicy
+	if joinPolicy == "" {
+		joinPolicy = models.DomainJoinNone
+	}
+	response := gin.H{
+		"domain":       domain.Domain,
+		"verified":     domain.Verified,
+		"join_policy":  joinPolicy,
+		"record_name":  dnsverify.RecordName(domain.Domain),
+		"record_value": dnsverify.RecordValue(domain.VerificationToken),
+	}
+	if joinPolicy != models.DomainJoinNone {
+		response["join_access_level"] = domain.JoinAccessLevel
+	}
+	return response
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// stubResolver answers TXT lookups from a fixed set of records.
+type stubResolver map[string][]string
+
+func (sr stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := sr[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+// jsonResponse is the status and JSON body of a response.
+type jsonResponse struct {
+	Status int
+	Body   map[string]interface{}
+}
+
+// mockDocument converts a model to the document the mock deployment returns for it.
+func mockDocument(t testing.TB, value interface{}) bson.D {
+	t.Helper()
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// commandNames returns the names of the commands sent to the mock deployment, in order.
+func commandNames(mt *mtest.T) []string {
+	names := []string{}
+	for _, started := range mt.GetAllStartedEvents() {
+		names = append(names, started.CommandName)
+	}
+	return names
+}
+
+func TestVerifyDomain(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organization := &models.Organization{
+		ID:   primitive.NewObjectID(),
+		Name: "Example",
+		Domains: []models.OrganizationDomain{
+			{Domain: "example.com", VerificationToken: "token"},
+		},
+	}
+	organizations := "test.organizations"
+
+	verifyDomain := func(mt *mtest.T, resolver stubResolver) *jsonResponse {
+		oh := &OrganizationHandler{
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			auditEventRepository:   repository.NewAuditEventRepository(mt.DB),
+			resolver:               resolver,
+		}
+		router := gin.New()
+		router.POST("/organizations/:id/domains/:domain/verify", oh.VerifyDomain)
+
+		recorder := serve(router, http.MethodPost, "/organizations/"+organization.ID.Hex()+"/domains/Example.com/verify")
+		response := &jsonResponse{Status: recorder.Code}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response.Body); err != nil {
+			mt.Fatal(err)
+		}
+		return response
+	}
+
+	mt.Run("TXT record found", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		response := verifyDomain(mt, stubResolver{
+			"_organizationhub-challenge.example.com": {"organizationhub-verification=token"},
+		})
+		if response.Status != http.StatusOK || response.Body["verified"] != true {
+			mt.Fatalf("got %d %v, want the verified domain", response.Status, response.Body)
+		}
+
+		update := mt.GetAllStartedEvents()[2]
+		if update.CommandName != "update" {
+			mt.Fatalf("commands %v, want the domain marked verified", commandNames(mt))
+		}
+		if domain := update.Command.Lookup("updates", "0", "q", "domains.domain").StringValue(); domain != "example.com" {
+			mt.Errorf("marked %q verified, want example.com", domain)
+		}
+	})
+
+	for name, resolver := range map[string]stubResolver{
+		"TXT record missing":            {},
+		"TXT record with another token": {"_organizationhub-challenge.example.com": {"organizationhub-verification=other"}},
+	} {
+		resolver := resolver
+		mt.Run(name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+				mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch),
+			)
+
+			response := verifyDomain(mt, resolver)
+			if response.Status != http.StatusUnprocessableEntity {
+				mt.Fatalf("got %d %v, want %d", response.Status, response.Body, http.StatusUnprocessableEntity)
+			}
+			if response.Body["record_name"] != "_organizationhub-challenge.example.com" || response.Body["record_value"] != "organizationhub-verification=token" {
+				mt.Errorf("got %v, want the record to publish", response.Body)
+			}
+			if names := commandNames(mt); len(names) != 2 {
+				mt.Errorf("commands %v, want the domain left unverified", names)
+			}
+		})
+	}
+
+	mt.Run("domain verified by another organization", func(mt *mtest.T) {
+		other := &models.Organization{
+			ID:      primitive.NewObjectID(),
+			Domains: []models.OrganizationDomain{{Domain: "example.com", Verified: true}},
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, other)),
+		)
+
+		response := verifyDomain(mt, stubResolver{
+			"_organizationhub-challenge.example.com": {"organizationhub-verification=token"},
+		})
+		if response.Status != http.StatusConflict {
+			mt.Errorf("got %d %v, want %d", response.Status, response.Body, http.StatusConflict)
+		}
+	})
+}
@@ -2,22 +2,46 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type OrganizationHandler struct {
-	organizationRepository *repository.OrganizationRepository
+	organizationRepository  *repository.OrganizationRepository
+	userRepository          *repository.UserRepository
+	membershipRepository    *repository.MembershipRepository
+	invitationRepository    *repository.InvitationRepository
+	invitationJobRepository *repository.InvitationJobRepository
+	auditEventRepository    *repository.AuditEventRepository
+	outboxRepository        *repository.OutboxRepository
+	transactions            *repository.Transactions
+	resolver                dnsverify.Resolver
+	retention               time.Duration
 }
 
-func NewOrganizationHandler(organizationRepository *repository.OrganizationRepository) *OrganizationHandler {
+func NewOrganizationHandler(organizationRepository *repository.OrganizationRepository, userRepository *repository.UserRepository, membershipRepository *repository.MembershipRepository, invitationRepository *repository.InvitationRepository, invitationJobRepository *repository.InvitationJobRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions, resolver dnsverify.Resolver, retention time.Duration) *OrganizationHandler {
 	return &OrganizationHandler{
-		organizationRepository: organizationRepository,
+		organizationRepository:  organizationRepository,
+		userRepository:          userRepository,
+		membershipRepository:    membershipRepository,
+		invitationRepository:    invitationRepository,
+		invitationJobRepository: invitationJobRepository,
+		auditEventRepository:    auditEventRepository,
+		outboxRepository:        outboxRepository,
+		transactions:            transactions,
+		resolver:                resolver,
+		retention:               retention,
 	}
 }
 
@@ -28,16 +52,65 @@ func (oh *OrganizationHandler) CreateOrganization(c *gin.Context) {
 		return
 	}
 
+	// Domains, SSO, deletion, nesting and ownership are managed through their own endpoints
+	organization.Domains = nil
+	organization.SAML = nil
+	organization.DeletedAt = nil
+	organization.ParentID = primitive.NilObjectID
+	organization.ParentAdminAccess = ""
+	organization.OwnerID = primitive.NilObjectID
+	organization.OwnershipTransfer = nil
+
 	// Set up organization creation timestamp
 	organization.CreatedAt = time.Now()
 	organization.UpdatedAt = time.Now()
 
-	// Create the organization in the database
-	id, err := oh.organizationRepository.CreateOrganization(context.Background(), &organization)
+	// The creator becomes the owner and first admin of the organization
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Organizations must be created by a user"})
+		return
+	}
+	organization.OwnerID = userID
+	user, err := oh.userRepository.GetUserProfile(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+	creator := models.OrganizationMember{
+		UserID:      user.ID,
+		Name:        user.Name,
+		Email:       strings.ToLower(user.Email),
+		AccessLevel: models.AccessLevelAdmin,
+	}
+
+	// Create the organization in the database, along with its first member
+	var id primitive.ObjectID
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		var err error
+		id, err = oh.organizationRepository.CreateOrganization(ctx, &organization)
+		if err != nil {
+			return err
+		}
+		if err := oh.membershipRepository.AddMember(ctx, id, creator); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, id, models.EventMemberAdded, gin.H{"member": creator})
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 		return
 	}
+	organization.ID = id
+
+	// Record the creation in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: id,
+		Action:         models.AuditActionOrganizationCreated,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       id.Hex(),
+		Changes:        auditDiff(nil, &organization),
+	})
 
 	// Respond with the created organization ID
 	c.JSON(http.StatusOK, gin.H{
@@ -62,13 +135,24 @@ func (oh *OrganizationHandler) GetOrganizationByID(c *gin.Context) {
 		return
 	}
 
+	// Clients which have the current version don't need it again
+	c.Header("ETag", organizationETag(organization))
+	c.Header("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+	if matchesETag(c.GetHeader("If-None-Match"), organization) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	// Respond with the retrieved organization
-	c.JSON(http.StatusOK, gin.H{
-		"organization_id":   organization.ID.Hex(),
-		"name":              organization.Name,
-		"description":       organization.Description,
-		"organization_members": organization.Members,
-	})
+	response := gin.H{
+		"organization_id": organization.ID.Hex(),
+		"name":            organization.Name,
+		"description":     organization.Description,
+	}
+	if !organization.OwnerID.IsZero() {
+		response["owner_id"] = organization.OwnerID.Hex()
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (oh *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
@@ -83,15 +167,20 @@ func (oh *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
 	var response []gin.H
 	for _, org := range organizations {
 		response = append(response, gin.H{
-			"organization_id":   org.ID.Hex(),
-			"name":              org.Name,
-			"description":       org.Description,
-			"organization_members": org.Members,
+			"organization_id": org.ID.Hex(),
+			"name":            org.Name,
+			"description":     org.Description,
 		})
 	}
 	c.JSON(http.StatusOK, response)
 }
 
+// updateOrganizationRequest is the body of UpdateOrganization. Empty attributes are left as they are.
+type updateOrganizationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 func (oh *OrganizationHandler) UpdateOrganization(c *gin.Context) {
 	organizationID := c.Param("id")
 
@@ -102,30 +191,60 @@ func (oh *OrganizationHandler) UpdateOrganization(c *gin.Context) {
 		return
 	}
 
-	var organization models.Organization
-	if err := c.BindJSON(&organization); err != nil {
+	// Only the name and description are updated here. Domains, SSO, deletion, nesting,
+	// ownership and the MFA policy are managed through their own endpoints.
+	var req updateOrganizationRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Set up organization update timestamp
-	organization.UpdatedAt = time.Now()
+	// Keep the current state for the audit log
+	before, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// With If-Match, the update only applies to the version the client has seen
+	var expectedVersion *int64
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		if !matchesETag(ifMatch, before) {
+			respondVersionMismatch(c, before)
+			return
+		}
+		expectedVersion = &before.Version
+	}
 
 	// Update the organization in the database
-	id, err := oh.organizationRepository.UpdateOrganization(context.Background(), objectID, &organization)
+	after, err := oh.changeOrganization(before, func(ctx context.Context) error {
+		_, err := oh.organizationRepository.UpdateOrganization(ctx, objectID, req.Name, req.Description, expectedVersion)
+		return err
+	})
 	if err != nil {
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			respondVersionMismatch(c, nil)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
 		return
 	}
 
+	// Record the changes in the audit log
+	oh.recordOrganizationChange(c, models.AuditActionOrganizationUpdated, before, after)
+
 	// Respond with the updated organization
+	c.Header("ETag", organizationETag(after))
 	c.JSON(http.StatusOK, gin.H{
-		"organization_id":   id.Hex(),
-		"name":              organization.Name,
-		"description":       organization.Description,
+		"organization_id": objectID.Hex(),
+		"name":            after.Name,
+		"description":     after.Description,
+		"version":         after.Version,
 	})
 }
 
+// DeleteOrganization deletes an organization, which can be restored with RestoreOrganization
+// until it is purged once the retention elapsed.
 func (oh *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 	organizationID := c.Param("id")
 
@@ -136,21 +255,161 @@ func (oh *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	// Delete the organization from the database
-	if err := oh.organizationRepository.DeleteOrganization(context.Background(), objectID); err != nil {
+	// Keep the deleted state for the audit log
+	before, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// Mark the organization deleted in the database
+	deletedAt := time.Now()
+	purgeAt := deletedAt.Add(oh.retention)
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.organizationRepository.DeleteOrganization(ctx, objectID, deletedAt); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, objectID, models.EventOrganizationDeleted, gin.H{
+			"organization": organizationSummary(before),
+			"purge_at":     purgeAt,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrOrganizationHasChildren) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Organizations nested in the organization must be moved or deleted first"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
 		return
 	}
 
-	// Respond with success message
-	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+	// Record the deletion in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: objectID,
+		Action:         models.AuditActionOrganizationDeleted,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       objectID.Hex(),
+		Changes:        auditDiff(before, nil),
+	})
+
+	// Respond with the time the organization will be purged
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Organization deleted successfully",
+		"deleted_at": deletedAt,
+		"purge_at":   purgeAt,
+	})
+}
+
+// RestoreOrganization undoes the deletion of an organization which wasn't purged yet.
+func (oh *OrganizationHandler) RestoreOrganization(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Keep the deleted state for the audit log
+	before, err := oh.organizationRepository.GetDeletedOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted organization not found"})
+		return
+	}
+
+	// Organizations deleted longer than the retention ago are about to be purged
+	var after *models.Organization
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.organizationRepository.RestoreOrganization(ctx, objectID, time.Now().Add(-oh.retention)); err != nil {
+			return err
+		}
+
+		var err error
+		after, err = oh.organizationRepository.GetOrganizationByID(ctx, objectID)
+		if err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, objectID, models.EventOrganizationRestored, gin.H{"organization": organizationSummary(after)})
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusGone, gin.H{"error": "The organization can no longer be restored"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore organization"})
+		return
+	}
+
+	// Record the restoration in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: objectID,
+		Action:         models.AuditActionOrganizationRestored,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       objectID.Hex(),
+		Changes:        auditDiff(before, after),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Organization restored successfully",
+		"organization_id": objectID.Hex(),
+		"name":            after.Name,
+		"description":     after.Description,
+	})
+}
+
+func (oh *OrganizationHandler) SetMFAPolicy(c *gin.Context) {
+	organizationID := c.Param("id")
+
+	// Convert organization ID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var policy struct {
+		RequireMFA *bool `json:"require_mfa"`
+	}
+	if err := c.BindJSON(&policy); err != nil || policy.RequireMFA == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "require_mfa is required"})
+		return
+	}
+
+	// Keep the current policy for the audit log
+	before, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// Update the MFA policy in the database
+	after, err := oh.changeOrganization(before, func(ctx context.Context) error {
+		return oh.organizationRepository.SetRequireMFA(ctx, objectID, *policy.RequireMFA)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update MFA policy"})
+		return
+	}
+
+	// Record the change in the audit log
+	oh.recordOrganizationChange(c, models.AuditActionMFAPolicyUpdated, before, after)
+
+	// Respond with the updated policy
+	c.JSON(http.StatusOK, gin.H{
+		"organization_id": objectID.Hex(),
+		"require_mfa":     *policy.RequireMFA,
+	})
 }
 
+// InviteUserToOrganization invites a user by email. The invitation token is returned to be
+// passed on to the user, who accepts it with AcceptInvitation.
 func (oh *OrganizationHandler) InviteUserToOrganization(c *gin.Context) {
 	// Parse organization ID from request parameters
 	organizationID := c.Param("id")
 
-	// Bind the request body to the InviteUserRequest model
+	// Bind the request body to the Invitation model
 	var inviteRequest models.Invitation
 	if err := c.BindJSON(&inviteRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
@@ -158,47 +417,279 @@ func (oh *OrganizationHandler) InviteUserToOrganization(c *gin.Context) {
 	}
 
 	// Validate user email
-	if inviteRequest.InvitedEmail == "" {
+	email := strings.ToLower(strings.TrimSpace(inviteRequest.InvitedEmail))
+	if email == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User email is required"})
 		return
 	}
 
+	// Invitees join as members unless an admin invites them as admin
+	accessLevel := inviteRequest.AccessLevel
+	if accessLevel == "" {
+		accessLevel = models.AccessLevelMember
+	}
+	if accessLevel != models.AccessLevelMember && accessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+	if accessLevel == models.AccessLevelAdmin && c.GetString("access_level") != models.AccessLevelAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can invite admins"})
+		return
+	}
+
 	// Check if the organization exists
 	orgID, err := primitive.ObjectIDFromHex(organizationID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
 		return
 	}
-	organization, err := oh.organizationRepository.GetOrganizationByID(c, orgID)
-	if err != nil {
+	if _, err := oh.organizationRepository.GetOrganizationByID(context.Background(), orgID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	// // Check if the user making the request is an admin
-	// if !userIsAdmin(c) {
-	// 	c.JSON(http.StatusUnauthorized, gin.H{"error": "Only admins can invite users"})
-	// 	return
-	// }
+	// Don't invite members or users who were already invited
+	if _, err := oh.membershipRepository.GetMemberByEmail(context.Background(), orgID, email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of the organization"})
+		return
+	}
+	if _, err := oh.invitationRepository.GetPendingInvitation(context.Background(), orgID, email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already has a pending invitation"})
+		return
+	}
 
-	// Invite the user to the organization (add user's email to organization members)
-	organization.Members = append(organization.Members, models.OrganizationMember{
-		Name:        "Test",
-		Email:       inviteRequest.InvitedEmail,
-		AccessLevel: "member",
-	})
+	// Generate the token the invitee accepts the invitation with, only its hash is stored
+	token, err := utils.GenerateOpaqueToken(utils.InvitationTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
 
-	// Update the organization in the database
-	id, err := oh.organizationRepository.UpdateOrganization(c, orgID, organization)
-	
+	invitedBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	invitation := &models.Invitation{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: orgID,
+		InvitedEmail:   email,
+		AccessLevel:    accessLevel,
+		TokenHash:      utils.HashOpaqueToken(token),
+		InvitedBy:      invitedBy,
+		ExpiresAt:      time.Now().Add(models.InvitationLifetime),
+	}
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.invitationRepository.CreateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, orgID, models.EventInvitationCreated, gin.H{"invitation": invitation})
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user to organization"})
 		return
 	}
 
-	// Respond with success message
+	// Record the invitation in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: orgID,
+		Action:         models.AuditActionInvitationCreated,
+		TargetType:     models.AuditTargetInvitation,
+		TargetID:       invitation.ID.Hex(),
+		Changes:        auditDiff(nil, invitation),
+	})
+
+	// Respond with the invitation and its token
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "User invited to organization successfully",
+		"invitation":       invitation,
+		"invitation_token": token,
+	})
+}
+
+// AcceptInvitation makes the signed in user a member of the organization they were invited to.
+// The invitation must have been sent to the user's email address, or be a link allowing the
+// domain of the user's verified email, if it only allows one.
+func (oh *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation token is required"})
+		return
+	}
+
+	invitation, err := oh.invitationRepository.GetInvitationByTokenHash(context.Background(), utils.HashOpaqueToken(req.Token))
+	if err != nil || !invitation.Pending() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	email := c.GetString("user_email")
+	if !invitation.IsLink() && !strings.EqualFold(email, invitation.InvitedEmail) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The invitation was sent to another email address"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invitations are accepted by the invited user"})
+		return
+	}
+	user, err := oh.userRepository.GetUserProfile(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	// Anyone can sign up with an address at the domain, only a verified one proves they belong to it
+	if invitation.IsLink() && invitation.AllowedDomain != "" {
+		if dnsverify.EmailDomain(user.Email) != invitation.AllowedDomain {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The invitation link is restricted to another email domain"})
+			return
+		}
+		if !emailVerified(user) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The invitation link requires an email address verified by an identity provider"})
+			return
+		}
+	}
+
+	// Add the user to the organization
+	member := models.OrganizationMember{
+		UserID:      user.ID,
+		Name:        user.Name,
+		Email:       strings.ToLower(email),
+		AccessLevel: invitation.AccessLevel,
+	}
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.membershipRepository.AddMember(ctx, invitation.OrganizationID, member); err != nil {
+			return err
+		}
+
+		// Invitations can only be used once, links as many times as they allow
+		if invitation.IsLink() {
+			err := oh.invitationRepository.UseLink(ctx, &models.InvitationUse{
+				InvitationID:   invitation.ID,
+				OrganizationID: invitation.OrganizationID,
+				UserID:         user.ID,
+				Email:          member.Email,
+				AccessLevel:    member.AccessLevel,
+			})
+			if err != nil {
+				return err
+			}
+		} else if err := oh.invitationRepository.MarkAccepted(ctx, invitation.ID); err != nil {
+			return err
+		}
+
+		if err := oh.outboxRepository.AddEvent(ctx, invitation.OrganizationID, models.EventInvitationAccepted, gin.H{"invitation": invitation}); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, invitation.OrganizationID, models.EventMemberAdded, gin.H{"member": member})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of the organization"})
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	// Record the new member in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: invitation.OrganizationID,
+		Action:         models.AuditActionInvitationAccepted,
+		TargetType:     models.AuditTargetMember,
+		TargetID:       member.Email,
+		Changes:        auditDiff(nil, &member),
+	})
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "User invited to organization successfully",
-		"id": id.Hex(),
+		"message":         "Invitation accepted successfully",
+		"organization_id": invitation.OrganizationID.Hex(),
+		"access_level":    member.AccessLevel,
 	})
-}
\ No newline at end of file
+}
+
+// changeOrganization applies a change to an organization and records an organization.updated
+// event with the differences, in the same transaction. It returns the changed organization.
+func (oh *OrganizationHandler) changeOrganization(before *models.Organization, change func(ctx context.Context) error) (*models.Organization, error) {
+	return changeOrganization(oh.transactions, oh.organizationRepository, oh.outboxRepository, before, change)
+}
+
+// changeOrganization is OrganizationHandler.changeOrganization, for the handlers of other APIs
+// changing organizations.
+func changeOrganization(transactions *repository.Transactions, organizationRepository *repository.OrganizationRepository, outboxRepository *repository.OutboxRepository, before *models.Organization, change func(ctx context.Context) error) (*models.Organization, error) {
+	var after *models.Organization
+	err := transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := change(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		after, err = organizationRepository.GetOrganizationByID(ctx, before.ID)
+		if err != nil {
+			return err
+		}
+
+		return outboxRepository.AddEvent(ctx, before.ID, models.EventOrganizationUpdated, gin.H{
+			"organization": organizationSummary(after),
+			"changes":      auditDiff(before, after),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return after, nil
+}
+
+// recordOrganizationChange records the difference between the organization before and after a
+// change in the audit log.
+func (oh *OrganizationHandler) recordOrganizationChange(c *gin.Context, action string, before, after *models.Organization) {
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: before.ID,
+		Action:         action,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       before.ID.Hex(),
+		Changes:        auditDiff(before, after),
+	})
+}
+
+// organizationSummary returns the attributes of an organization included in its events.
+func organizationSummary(organization *models.Organization) gin.H {
+	return gin.H{
+		"_id":         organization.ID,
+		"name":        organization.Name,
+		"description": organization.Description,
+		"require_mfa": organization.RequireMFA,
+	}
+}
+
+// organizationETag returns the entity tag of the current version of an organization.
+func organizationETag(organization *models.Organization) string {
+	return `"` + strconv.FormatInt(organization.Version, 10) + `"`
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists the entity tag of the
+// organization, or is "*".
+func matchesETag(header string, organization *models.Organization) bool {
+	etag := organizationETag(organization)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondVersionMismatch rejects an update based on an outdated version of the organization,
+// with the entity tag of the current version when it is known.
+func respondVersionMismatch(c *gin.Context, current *models.Organization) {
+	if current != nil {
+		c.Header("ETag", organizationETag(current))
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The organization was modified, fetch it again before updating"})
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+)
+
+// serveJSON serves a request with a JSON body and the given headers.
+func serveJSON(router *gin.Engine, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// newTestOrganizationHandler returns a handler whose repositories use the mock deployment.
+func newTestOrganizationHandler(mt *mtest.T) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+		membershipRepository:   repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+		userRepository:         repository.NewUserRepository(mt.DB, cache.NewLRU(16)),
+		auditEventRepository:   repository.NewAuditEventRepository(mt.DB),
+		outboxRepository:       repository.NewOutboxRepository(mt.DB),
+		transactions:           repository.NewTransactions(mt.DB),
+	}
+}
+
+// updateSucceeds is the reply to an update which modified one document.
+var updateSucceeds = mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+func TestUpdateOrganization(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", Description: "Before", Version: 3}
+	updated := *organization
+	updated.Name = "Renamed"
+	updated.Version = 4
+	organizations := "test.organizations"
+
+	updateOrganization := func(mt *mtest.T, body string, header map[string]string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.PUT("/organizations/:id", newTestOrganizationHandler(mt).UpdateOrganization)
+		return serveJSON(router, http.MethodPut, "/organizations/"+organization.ID.Hex(), body, header)
+	}
+	updated200 := func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			updateSucceeds,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, &updated)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+	}
+
+	mt.Run("only sets the name and description", func(mt *mtest.T) {
+		updated200(mt)
+
+		body := `{"name": "Renamed", "_id": "` + primitive.NewObjectID().Hex() + `", "created_at": "2000-01-01T00:00:00Z", "owner_id": "` + primitive.NewObjectID().Hex() + `", "require_mfa": true, "version": 1}`
+		recorder := updateOrganization(mt, body, nil)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("update returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		if etag := recorder.Header().Get("ETag"); etag != `"4"` {
+			mt.Errorf("ETag = %s, want the new version", etag)
+		}
+
+		update := mt.GetAllStartedEvents()[1]
+		if update.CommandName != "update" {
+			mt.Fatalf("commands %v, want the organization updated", commandNames(mt))
+		}
+		elements, err := update.Command.Lookup("updates", "0", "u", "$set").Document().Elements()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		fields := []string{}
+		for _, element := range elements {
+			fields = append(fields, element.Key())
+		}
+		sort.Strings(fields)
+		if strings.Join(fields, ",") != "name,updated_at" {
+			mt.Errorf("set %v, want only the name and update time", fields)
+		}
+		if _, err := update.Command.LookupErr("updates", "0", "q", "version"); err == nil {
+			mt.Error("update without If-Match checks the version")
+		}
+	})
+
+	mt.Run("If-Match with the current version", func(mt *mtest.T) {
+		updated200(mt)
+
+		recorder := updateOrganization(mt, `{"name": "Renamed"}`, map[string]string{"If-Match": `"3"`})
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("update returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		version := mt.GetAllStartedEvents()[1].Command.Lookup("updates", "0", "q", "version")
+		if version.Int64() != 3 {
+			mt.Errorf("update filters on version %v, want 3", version)
+		}
+	})
+
+	mt.Run("If-Match with an outdated version", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)))
+
+		recorder := updateOrganization(mt, `{"name": "Renamed"}`, map[string]string{"If-Match": `"2"`})
+		if recorder.Code != http.StatusPreconditionFailed {
+			mt.Fatalf("update returned %d, want %d", recorder.Code, http.StatusPreconditionFailed)
+		}
+		if etag := recorder.Header().Get("ETag"); etag != `"3"` {
+			mt.Errorf("ETag = %s, want the current version", etag)
+		}
+		if names := commandNames(mt); len(names) != 1 {
+			mt.Errorf("commands %v, want the organization left as is", names)
+		}
+	})
+
+	mt.Run("modified since it was read", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		recorder := updateOrganization(mt, `{"name": "Renamed"}`, map[string]string{"If-Match": `"3"`})
+		if recorder.Code != http.StatusPreconditionFailed {
+			mt.Errorf("update returned %d, want %d", recorder.Code, http.StatusPreconditionFailed)
+		}
+	})
+}
+
+func TestRestoreOrganization(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	retention := 30 * 24 * time.Hour
+	deletedAt := time.Now().Add(-time.Hour)
+	deleted := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", DeletedAt: &deletedAt}
+	restored := *deleted
+	restored.DeletedAt = nil
+	organizations := "test.organizations"
+
+	restore := func(mt *mtest.T) *httptest.ResponseRecorder {
+		oh := newTestOrganizationHandler(mt)
+		oh.retention = retention
+
+		router := gin.New()
+		router.POST("/organizations/:id/restore", oh.RestoreOrganization)
+		return serveJSON(router, http.MethodPost, "/organizations/"+deleted.ID.Hex()+"/restore", "", nil)
+	}
+
+	mt.Run("restores an organization deleted within the retention", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, deleted)),
+			updateSucceeds,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, &restored)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		recorder := restore(mt)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("restore returned %d %s", recorder.Code, recorder.Body.String())
+		}
+
+		started := mt.GetAllStartedEvents()
+		update := started[1].Command.Lookup("updates", "0")
+		if _, err := update.Document().LookupErr("u", "$unset", "deleted_at"); err != nil {
+			mt.Errorf("update %v, want the deletion undone", update)
+		}
+		deletedAfter := update.Document().Lookup("q", "deleted_at", "$gt").Time()
+		if cutoff := time.Now().Add(-retention); deletedAfter.Before(cutoff.Add(-time.Minute)) || deletedAfter.After(cutoff) {
+			mt.Errorf("restores organizations deleted after %s, want after %s", deletedAfter, cutoff)
+		}
+		event := started[3].Command.Lookup("documents", "0", "type")
+		if event.StringValue() != models.EventOrganizationRestored {
+			mt.Errorf("outbox event %v, want %s", event, models.EventOrganizationRestored)
+		}
+	})
+
+	mt.Run("organization purged or past the retention", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, deleted)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		if recorder := restore(mt); recorder.Code != http.StatusGone {
+			mt.Errorf("restore returned %d, want %d", recorder.Code, http.StatusGone)
+		}
+	})
+
+	mt.Run("organization not deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch))
+
+		if recorder := restore(mt); recorder.Code != http.StatusNotFound {
+			mt.Errorf("restore returned %d, want %d", recorder.Code, http.StatusNotFound)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter", "deleted_at", "$exists")
+		if !filter.Boolean() {
+			mt.Errorf("filter %v, want only deleted organizations", filter)
+		}
+	})
+}
+
+func TestAcceptInvitationLink(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{ID: primitive.NewObjectID(), Name: "Jane", Email: "jane@example.com"}
+	link := &models.Invitation{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: primitive.NewObjectID(),
+		Type:           models.InvitationTypeLink,
+		AccessLevel:    models.AccessLevelMember,
+		MaxUses:        2,
+		UseCount:       1,
+		TokenHash:      utils.HashOpaqueToken("the token"),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+
+	// accept accepts the invitation as the user, with the given replies to the commands after
+	// the invitation is read
+	accept := func(mt *mtest.T, invitation *models.Invitation, replies ...bson.D) *httptest.ResponseRecorder {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.invitations", mtest.FirstBatch, mockDocument(mt, invitation)))
+		mt.AddMockResponses(replies...)
+
+		oh := newTestOrganizationHandler(mt)
+		oh.invitationRepository = repository.NewInvitationRepository(mt.DB)
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", user.ID.Hex())
+			c.Set("user_email", user.Email)
+		})
+		router.POST("/invitations/accept", oh.AcceptInvitation)
+		return serveJSON(router, http.MethodPost, "/invitations/accept", `{"token": "the token"}`, nil)
+	}
+	userFound := func(mt *mtest.T, user *models.User) bson.D {
+		return mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user))
+	}
+	inserted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+
+	mt.Run("link with uses left", func(mt *mtest.T) {
+		recorder := accept(mt, link,
+			userFound(mt, user),
+			updateSucceeds,
+			inserted,
+			updateSucceeds,
+			inserted,
+			inserted,
+			inserted,
+			mtest.CreateSuccessResponse(),
+			inserted,
+		)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("accept returned %d %s", recorder.Code, recorder.Body.String())
+		}
+
+		use := mt.GetAllStartedEvents()[4]
+		if _, err := use.Command.LookupErr("updates", "0", "q", "$or"); err != nil {
+			mt.Errorf("commands %v, want the use counted only while the link has uses left", commandNames(mt))
+		}
+		if count := use.Command.Lookup("updates", "0", "u", "$inc", "use_count"); count.Int32() != 1 {
+			mt.Errorf("use count incremented by %v, want 1", count)
+		}
+	})
+
+	mt.Run("link used up", func(mt *mtest.T) {
+		usedUp := *link
+		usedUp.UseCount = usedUp.MaxUses
+
+		if recorder := accept(mt, &usedUp); recorder.Code != http.StatusNotFound {
+			mt.Errorf("accept returned %d, want %d", recorder.Code, http.StatusNotFound)
+		}
+		if names := commandNames(mt); len(names) != 1 {
+			mt.Errorf("commands %v, want the user left out of the organization", names)
+		}
+	})
+
+	mt.Run("last use taken concurrently", func(mt *mtest.T) {
+		recorder := accept(mt, link,
+			userFound(mt, user),
+			updateSucceeds,
+			inserted,
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(),
+		)
+		if recorder.Code != http.StatusNotFound {
+			mt.Errorf("accept returned %d, want %d", recorder.Code, http.StatusNotFound)
+		}
+		if names := commandNames(mt); names[len(names)-1] != "abortTransaction" {
+			mt.Errorf("commands %v, want the membership rolled back", names)
+		}
+	})
+
+	mt.Run("domain-restricted link with an unverified email", func(mt *mtest.T) {
+		restricted := *link
+		restricted.AllowedDomain = "example.com"
+
+		if recorder := accept(mt, &restricted, userFound(mt, user)); recorder.Code != http.StatusForbidden {
+			mt.Errorf("accept returned %d, want %d", recorder.Code, http.StatusForbidden)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// errOrganizationCycle is returned when an organization would be nested in itself or in one of
+// its child organizations.
+var errOrganizationCycle = errors.New("organization would be nested in itself")
+
+// errOrganizationTooDeep is returned when organizations would be nested deeper than
+// models.MaxOrganizationDepth.
+var errOrganizationTooDeep = errors.New("organizations would be nested too deep")
+
+// GetOrganizationTree returns an organization and the organizations nested in it, at any depth.
+func (oh *OrganizationHandler) GetOrganizationTree(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	children, _, err := oh.organizationSubtree(context.Background(), organization)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization tree"})
+		return
+	}
+
+	tree := organizationTreeNode(organization, children)
+	if !organization.ParentID.IsZero() {
+		tree["parent_id"] = organization.ParentID.Hex()
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// SetOrganizationParent moves an organization, along with the organizations nested in it, into
+// another organization, or out of its parent when no parent is given. Admins of the parent are
+// granted the given parent admin access in the organization, none by default. Both the current
+// and the new parent must be administered by the caller, so that neither loses or gains
+// organizations without their admins' consent.
+func (oh *OrganizationHandler) SetOrganizationParent(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req struct {
+		ParentID          string `json:"parent_id"`
+		ParentAdminAccess string `json:"parent_admin_access"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var parentID primitive.ObjectID
+	if req.ParentID != "" {
+		if parentID, err = primitive.ObjectIDFromHex(req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent organization ID"})
+			return
+		}
+	}
+	switch req.ParentAdminAccess {
+	case "", "none":
+		req.ParentAdminAccess = ""
+	case models.AccessLevelMember, models.AccessLevelAdmin:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent admin access"})
+		return
+	}
+
+	before, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// Only admins of the current and the new parent may move organizations out of and into
+	// them. Organizations whose parent was deleted may be moved by their own admins.
+	if !before.ParentID.IsZero() && !oh.administersParent(c, before.ParentID, false) {
+		return
+	}
+	if !parentID.IsZero() && !oh.administersParent(c, parentID, true) {
+		return
+	}
+
+	after, err := oh.changeOrganization(before, func(ctx context.Context) error {
+		if !parentID.IsZero() {
+			// The organizations nested in the organization move along with it. Their height is
+			// read in the transaction: organizations moved into them concurrently lock the
+			// organization as an ancestor, which conflicts with the move below.
+			_, height, err := oh.organizationSubtree(ctx, before)
+			if err != nil {
+				return err
+			}
+
+			ancestors, err := oh.organizationRepository.LockAncestors(ctx, parentID)
+			if err != nil {
+				return err
+			}
+			for _, ancestor := range ancestors {
+				if ancestor.ID == objectID {
+					return errOrganizationCycle
+				}
+			}
+			if len(ancestors)+height > models.MaxOrganizationDepth {
+				return errOrganizationTooDeep
+			}
+		}
+		return oh.organizationRepository.SetParent(ctx, objectID, parentID, req.ParentAdminAccess)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errOrganizationCycle):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An organization can't be nested in itself or in one of its child organizations"})
+		case errors.Is(err, errOrganizationTooDeep):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Organizations can't be nested more than %d levels deep", models.MaxOrganizationDepth)})
+		case errors.Is(err, mongo.ErrNoDocuments):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move organization"})
+		}
+		return
+	}
+
+	// Record the move in the audit log
+	oh.recordOrganizationChange(c, models.AuditActionOrganizationMoved, before, after)
+
+	response := gin.H{
+		"organization_id":     objectID.Hex(),
+		"parent_id":           nil,
+		"parent_admin_access": after.ParentAdminAccess,
+		"version":             after.Version,
+	}
+	if !after.ParentID.IsZero() {
+		response["parent_id"] = after.ParentID.Hex()
+	}
+	c.Header("ETag", organizationETag(after))
+	c.JSON(http.StatusOK, response)
+}
+
+// administersParent reports whether the signed in user is an admin of a parent organization,
+// responding with an error otherwise. A parent which doesn't exist or was deleted is only
+// rejected when it must exist. Tokens issued to an organization can't act on another.
+func (oh *OrganizationHandler) administersParent(c *gin.Context, parentID primitive.ObjectID, mustExist bool) bool {
+	parent, err := oh.organizationRepository.GetOrganizationByID(context.Background(), parentID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if !mustExist {
+				return true
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent organization not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move organization"})
+		return false
+	}
+
+	if c.GetString("token_organization_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token does not have access to the parent organization"})
+		return false
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of the parent organization can move organizations into or out of it"})
+		return false
+	}
+	accessLevel, err := oh.membershipRepository.GetAccessLevel(context.Background(), parent, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move organization"})
+		return false
+	}
+	if accessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of the parent organization can move organizations into or out of it"})
+		return false
+	}
+
+	return true
+}
+
+// organizationSubtree returns the organizations nested in an organization, at any depth, by
+// parent, and the number of levels of the subtree, counting the organization.
+func (oh *OrganizationHandler) organizationSubtree(ctx context.Context, organization *models.Organization) (map[primitive.ObjectID][]*models.Organization, int, error) {
+	children := map[primitive.ObjectID][]*models.Organization{}
+	seen := map[primitive.ObjectID]bool{organization.ID: true}
+
+	height := 1
+	level := []primitive.ObjectID{organization.ID}
+	for height <= models.MaxOrganizationDepth {
+		organizations, err := oh.organizationRepository.GetChildOrganizations(ctx, level)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		level = nil
+		for _, child := range organizations {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			children[child.ParentID] = append(children[child.ParentID], child)
+			level = append(level, child.ID)
+		}
+		if len(level) == 0 {
+			break
+		}
+		height++
+	}
+
+	return children, height, nil
+}
+
+// organizationTreeNode returns an organization and, recursively, the organizations nested in it.
+func organizationTreeNode(organization *models.Organization, children map[primitive.ObjectID][]*models.Organization) gin.H {
+	nodes := []gin.H{}
+	for _, child := range children[organization.ID] {
+		nodes = append(nodes, organizationTreeNode(child, children))
+	}
+
+	node := gin.H{
+		"organization_id": organization.ID.Hex(),
+		"name":            organization.Name,
+		"description":     organization.Description,
+		"children":        nodes,
+	}
+	if organization.ParentAdminAccess != "" {
+		node["parent_admin_access"] = organization.ParentAdminAccess
+	}
+	return node
+}
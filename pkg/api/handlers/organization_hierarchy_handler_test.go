@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+func TestSetOrganizationParent(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example"}
+	child := &models.Organization{ID: primitive.NewObjectID(), Name: "Child", ParentID: organization.ID}
+	organizations := "test.organizations"
+
+	// moveInto moves the organization into parent, which the user administers, with the given
+	// replies to the commands of the transaction
+	moveInto := func(mt *mtest.T, parent *models.Organization, replies ...bson.D) *httptest.ResponseRecorder {
+		admin := &models.OrganizationMember{OrganizationID: parent.ID, UserID: userID, AccessLevel: models.AccessLevelAdmin}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, parent)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, admin)),
+		)
+		mt.AddMockResponses(replies...)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", userID.Hex()) })
+		router.PUT("/organizations/:id/parent", newTestOrganizationHandler(mt).SetOrganizationParent)
+		return serveJSON(router, http.MethodPut, "/organizations/"+organization.ID.Hex()+"/parent", `{"parent_id": "`+parent.ID.Hex()+`"}`, nil)
+	}
+	// locked is the reply to LockAncestors for one organization
+	locked := func(mt *mtest.T, organization *models.Organization) bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, organization)})
+	}
+
+	mt.Run("reads the subtree in the transaction", func(mt *mtest.T) {
+		parent := &models.Organization{ID: primitive.NewObjectID(), Name: "Parent"}
+		moved := *organization
+		moved.ParentID = parent.ID
+		recorder := moveInto(mt, parent,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch),
+			locked(mt, parent),
+			updateSucceeds,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, &moved)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("move returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		subtree := mt.GetAllStartedEvents()[3]
+		if _, err := subtree.Command.LookupErr("filter", "parent_id"); err != nil {
+			mt.Fatalf("commands %v, want the child organizations read", commandNames(mt))
+		}
+		if _, err := subtree.Command.LookupErr("txnNumber"); err != nil {
+			mt.Error("child organizations read outside of the transaction")
+		}
+	})
+
+	mt.Run("rejects cycles", func(mt *mtest.T) {
+		recorder := moveInto(mt, child,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, child)),
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch),
+			locked(mt, child),
+			locked(mt, organization),
+			mtest.CreateSuccessResponse(),
+		)
+		if recorder.Code != http.StatusBadRequest {
+			mt.Errorf("move returned %d, want %d", recorder.Code, http.StatusBadRequest)
+		}
+		for _, name := range commandNames(mt) {
+			if name == "update" {
+				mt.Errorf("commands %v, want the organization left as is", commandNames(mt))
+			}
+		}
+	})
+
+	mt.Run("rejects nesting too deep", func(mt *mtest.T) {
+		// The parent is nested as deep as organizations can be, so the organization would fit
+		// but not its child
+		ancestors := make([]*models.Organization, models.MaxOrganizationDepth-1)
+		for i := range ancestors {
+			ancestors[i] = &models.Organization{ID: primitive.NewObjectID(), Name: "Ancestor"}
+			if i > 0 {
+				ancestors[i-1].ParentID = ancestors[i].ID
+			}
+		}
+		replies := []bson.D{
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, child)),
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch),
+		}
+		for _, ancestor := range ancestors {
+			replies = append(replies, locked(mt, ancestor))
+		}
+		replies = append(replies, mtest.CreateSuccessResponse())
+
+		recorder := moveInto(mt, ancestors[0], replies...)
+		if recorder.Code != http.StatusBadRequest {
+			mt.Errorf("move returned %d, want %d", recorder.Code, http.StatusBadRequest)
+		}
+	})
+}
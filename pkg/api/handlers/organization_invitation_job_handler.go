@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/bulkinvite"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// maxBulkInvitationSize caps the size of the files bulk invitations are read from.
+const maxBulkInvitationSize = 1 << 20
+
+// BulkInviteUsers queues invitations for many users at once, read from a CSV file or a JSON
+// array sent as the body or as the "file" field of a form. The invitations are sent in the
+// background, the job returned is polled with GetInvitationJob until its report is ready.
+func (oh *OrganizationHandler) BulkInviteUsers(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	rows, err := readBulkInvitations(c)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitations: " + err.Error()})
+		return
+	}
+
+	createdBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	now := time.Now()
+	job := &models.InvitationJob{
+		ID:                 primitive.NewObjectID(),
+		OrganizationID:     organizationID,
+		CreatedBy:          createdBy,
+		CreatedByEmail:     c.GetString("user_email"),
+		InviterAccessLevel: c.GetString("access_level"),
+		Rows:               rows,
+		CreatedAt:          now,
+		ExpiresAt:          now.Add(models.InvitationLifetime),
+	}
+	if err := oh.invitationJobRepository.CreateJob(context.Background(), job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation job"})
+		return
+	}
+
+	jobURL := "/organizations/" + organizationID.Hex() + "/invitations/bulk/" + job.ID.Hex()
+	c.Header("Location", jobURL)
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":     job.ID.Hex(),
+		"status":     job.Status,
+		"rows":       len(rows),
+		"status_url": jobURL,
+		"report_url": jobURL + "/report",
+	})
+}
+
+// readBulkInvitations reads the invitations of the request, from a CSV file unless the body
+// or the uploaded file is JSON.
+func readBulkInvitations(c *gin.Context) ([]models.InvitationJobRow, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkInvitationSize)
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	var body []byte
+	switch contentType {
+	case "multipart/form-data":
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return nil, err
+			}
+			return nil, errors.New("a file is required")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, errors.New("invalid file")
+		}
+		defer file.Close()
+		if body, err = io.ReadAll(file); err != nil {
+			return nil, errors.New("invalid file")
+		}
+		contentType, _, _ = mime.ParseMediaType(fileHeader.Header.Get("Content-Type"))
+	default:
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	if contentType == "application/json" {
+		return bulkinvite.ParseJSON(body)
+	}
+	return bulkinvite.ParseCSV(bytes.NewReader(body))
+}
+
+// GetInvitationJob returns the status of a bulk invitation job, with the number of rows per
+// result. Jobs are only visible to their creator and to admins.
+func (oh *OrganizationHandler) GetInvitationJob(c *gin.Context) {
+	job, ok := oh.findInvitationJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":       job.ID.Hex(),
+		"status":       job.Status,
+		"error":        job.Error,
+		"rows":         len(job.Rows),
+		"counts":       job.Counts(),
+		"created_at":   job.CreatedAt,
+		"completed_at": job.CompletedAt,
+		"expires_at":   job.ExpiresAt,
+	})
+}
+
+// GetInvitationJobReport returns the result of every row of a finished bulk invitation job,
+// as a CSV file or as JSON with format=json. The tokens of the invitations sent are only in
+// the first report downloaded, they are deleted from the job once returned.
+func (oh *OrganizationHandler) GetInvitationJobReport(c *gin.Context) {
+	job, ok := oh.findInvitationJob(c)
+	if !ok {
+		return
+	}
+
+	if job.Status != models.InvitationJobCompleted && job.Status != models.InvitationJobFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation job is not done yet"})
+		return
+	}
+
+	job, err := oh.invitationJobRepository.TakeInvitationTokens(context.Background(), job.OrganizationID, job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitation job report"})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"job_id": job.ID.Hex(), "status": job.Status, "rows": job.Rows})
+		return
+	}
+
+	var report bytes.Buffer
+	writer := csv.NewWriter(&report)
+	writer.Write([]string{"row", "email", "access_level", "status", "reason", "invitation_id", "invitation_token"})
+	for _, row := range job.Rows {
+		invitationID := ""
+		if !row.InvitationID.IsZero() {
+			invitationID = row.InvitationID.Hex()
+		}
+		writer.Write([]string{strconv.Itoa(row.Row), row.Email, row.AccessLevel, row.Status, row.Reason, invitationID, row.InvitationToken})
+	}
+	writer.Flush()
+
+	c.Header("Content-Disposition", `attachment; filename="invitations-`+job.ID.Hex()+`.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", report.Bytes())
+}
+
+// findInvitationJob retrieves the job of the request, responding with an error when it
+// doesn't exist or the user may not see it.
+func (oh *OrganizationHandler) findInvitationJob(c *gin.Context) (*models.InvitationJob, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, false
+	}
+	jobID, err := primitive.ObjectIDFromHex(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return nil, false
+	}
+
+	job, err := oh.invitationJobRepository.GetJob(context.Background(), organizationID, jobID)
+	if err != nil || (job.CreatedBy.Hex() != c.GetString("user_id") && c.GetString("access_level") != models.AccessLevelAdmin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation job not found"})
+		return nil, false
+	}
+	return job, true
+}
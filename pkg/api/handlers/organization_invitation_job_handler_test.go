@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+func TestGetInvitationJobReport(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	creator := primitive.NewObjectID()
+	job := &models.InvitationJob{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: primitive.NewObjectID(),
+		CreatedBy:      creator,
+		Status:         models.InvitationJobCompleted,
+		Rows: []models.InvitationJobRow{
+			{Row: 1, Email: "jane@example.com", Status: models.InvitationRowInvited, InvitationID: primitive.NewObjectID(), InvitationToken: "ohi_token"},
+			{Row: 2, Email: "john@example.com", Status: models.InvitationRowSkipped, Reason: "Already a member of the organization"},
+		},
+		CreatedAt: time.Now(),
+	}
+	withoutTokens := *job
+	withoutTokens.Rows = []models.InvitationJobRow{job.Rows[0], job.Rows[1]}
+	withoutTokens.Rows[0].InvitationToken = ""
+	jobs := "test.invitation_jobs"
+
+	getReport := func(mt *mtest.T) []models.InvitationJobRow {
+		oh := &OrganizationHandler{invitationJobRepository: repository.NewInvitationJobRepository(mt.DB)}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", creator.Hex())
+			c.Set("access_level", models.AccessLevelMember)
+		})
+		router.GET("/organizations/:id/invitations/bulk/:jobId/report", oh.GetInvitationJobReport)
+
+		recorder := serve(router, http.MethodGet, "/organizations/"+job.OrganizationID.Hex()+"/invitations/bulk/"+job.ID.Hex()+"/report?format=json")
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("report returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		var report struct {
+			Rows []models.InvitationJobRow `json:"rows"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+			mt.Fatal(err)
+		}
+		return report.Rows
+	}
+
+	mt.Run("first download returns the tokens and clears them", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, jobs, mtest.FirstBatch, mockDocument(mt, job)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, job)}),
+		)
+
+		rows := getReport(mt)
+		if len(rows) != 2 || rows[0].InvitationToken != "ohi_token" {
+			mt.Fatalf("rows = %+v, want the invitation token", rows)
+		}
+
+		update := mt.GetAllStartedEvents()[1]
+		if update.CommandName != "findAndModify" {
+			mt.Fatalf("commands %v, want the tokens cleared", commandNames(mt))
+		}
+		if _, err := update.Command.LookupErr("update", "$unset", "rows.$[].invitation_token"); err != nil {
+			mt.Errorf("update %v doesn't clear the tokens", update.Command.Lookup("update"))
+		}
+	})
+
+	mt.Run("later downloads have no tokens", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, jobs, mtest.FirstBatch, mockDocument(mt, &withoutTokens)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, &withoutTokens)}),
+		)
+
+		rows := getReport(mt)
+		if len(rows) != 2 || rows[0].InvitationToken != "" {
+			mt.Errorf("rows = %+v, want no invitation token", rows)
+		}
+	})
+
+	mt.Run("unfinished job", func(mt *mtest.T) {
+		running := *job
+		running.Status = models.InvitationJobRunning
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, jobs, mtest.FirstBatch, mockDocument(mt, &running)))
+
+		oh := &OrganizationHandler{invitationJobRepository: repository.NewInvitationJobRepository(mt.DB)}
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", creator.Hex()) })
+		router.GET("/organizations/:id/invitations/bulk/:jobId/report", oh.GetInvitationJobReport)
+
+		recorder := serve(router, http.MethodGet, "/organizations/"+job.OrganizationID.Hex()+"/invitations/bulk/"+job.ID.Hex()+"/report")
+		if recorder.Code != http.StatusConflict {
+			mt.Errorf("report returned %d, want %d", recorder.Code, http.StatusConflict)
+		}
+		if names := commandNames(mt); len(names) != 1 {
+			mt.Errorf("commands %v, want the tokens kept until the job is done", names)
+		}
+	})
+}
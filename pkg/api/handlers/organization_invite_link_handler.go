@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+)
+
+// maxInviteLinkLifetime is how long invitation links can be valid at most.
+const maxInviteLinkLifetime = 90 * 24 * time.Hour
+
+// CreateInviteLink creates an invitation link anyone can join the organization with, as a
+// member unless another access level is given. The link can be limited to a number of uses
+// and to verified emails of a domain, and expires after a week unless another expiry is given.
+// Its token is only returned once, and is accepted with AcceptInvitation.
+func (oh *OrganizationHandler) CreateInviteLink(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req struct {
+		AccessLevel   string     `json:"access_level"`
+		MaxUses       int        `json:"max_uses"`
+		ExpiresAt     *time.Time `json:"expires_at"`
+		AllowedDomain string     `json:"allowed_domain"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.AccessLevel == "" {
+		req.AccessLevel = models.AccessLevelMember
+	}
+	if req.AccessLevel != models.AccessLevelMember && req.AccessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+	if req.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Max uses can't be negative"})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(models.InvitationLifetime)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxInviteLinkLifetime)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the next 90 days"})
+			return
+		}
+	}
+
+	allowedDomain := ""
+	if req.AllowedDomain != "" {
+		if allowedDomain = dnsverify.NormalizeDomain(req.AllowedDomain); allowedDomain == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allowed domain"})
+			return
+		}
+	}
+
+	// Generate the token of the link, only its hash is stored
+	token, err := utils.GenerateOpaqueToken(utils.InvitationTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+
+	invitedBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	link := &models.Invitation{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		Type:           models.InvitationTypeLink,
+		AccessLevel:    req.AccessLevel,
+		AllowedDomain:  allowedDomain,
+		MaxUses:        req.MaxUses,
+		TokenHash:      utils.HashOpaqueToken(token),
+		InvitedBy:      invitedBy,
+		ExpiresAt:      expiresAt,
+	}
+	err = oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.invitationRepository.CreateInvitation(ctx, link); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, organizationID, models.EventInvitationCreated, gin.H{"invitation": link})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation link"})
+		return
+	}
+
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionInviteLinkCreated,
+		TargetType:     models.AuditTargetInvitation,
+		TargetID:       link.ID.Hex(),
+		Changes:        auditDiff(nil, link),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation":       inviteLinkResponse(link),
+		"invitation_token": token,
+	})
+}
+
+// GetInviteLinks lists the invitation links of the organization, most recent first, with how
+// many times each was used.
+func (oh *OrganizationHandler) GetInviteLinks(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	links, err := oh.invitationRepository.GetOrganizationLinks(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitation links"})
+		return
+	}
+
+	response := make([]gin.H, len(links))
+	for i, link := range links {
+		response[i] = inviteLinkResponse(link)
+	}
+	c.JSON(http.StatusOK, gin.H{"invitation_links": response})
+}
+
+// GetInviteLinkUses lists the users who joined the organization with an invitation link,
+// oldest first.
+func (oh *OrganizationHandler) GetInviteLinkUses(c *gin.Context) {
+	link, ok := oh.findInviteLink(c)
+	if !ok {
+		return
+	}
+
+	uses, err := oh.invitationRepository.GetInvitationUses(context.Background(), link.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitation link uses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitation_link": inviteLinkResponse(link), "uses": uses})
+}
+
+// RevokeInviteLink prevents an invitation link from being used any more. Users who already
+// joined with it stay members.
+func (oh *OrganizationHandler) RevokeInviteLink(c *gin.Context) {
+	link, ok := oh.findInviteLink(c)
+	if !ok {
+		return
+	}
+
+	err := oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.invitationRepository.RevokeInvitation(ctx, link.OrganizationID, link.ID); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, link.OrganizationID, models.EventInvitationRevoked, gin.H{"invitation": link})
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Invitation link already revoked"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation link"})
+		return
+	}
+
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: link.OrganizationID,
+		Action:         models.AuditActionInviteLinkRevoked,
+		TargetType:     models.AuditTargetInvitation,
+		TargetID:       link.ID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation link revoked successfully"})
+}
+
+// findInviteLink retrieves the invitation link of the request, responding with an error when
+// the organization has no such link.
+func (oh *OrganizationHandler) findInviteLink(c *gin.Context) (*models.Invitation, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, false
+	}
+	linkID, err := primitive.ObjectIDFromHex(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation link ID"})
+		return nil, false
+	}
+
+	link, err := oh.invitationRepository.GetOrganizationInvitation(context.Background(), organizationID, linkID)
+	if err != nil || !link.IsLink() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation link not found"})
+		return nil, false
+	}
+	return link, true
+}
+
+// inviteLinkResponse returns an invitation link with whether it can still be used.
+func inviteLinkResponse(link *models.Invitation) gin.H {
+	response := gin.H{
+		"_id":          link.ID.Hex(),
+		"access_level": link.AccessLevel,
+		"max_uses":     link.MaxUses,
+		"use_count":    link.UseCount,
+		"invited_by":   link.InvitedBy,
+		"expires_at":   link.ExpiresAt,
+		"created_at":   link.CreatedAt,
+		"active":       link.Pending(),
+	}
+	if link.AllowedDomain != "" {
+		response["allowed_domain"] = link.AllowedDomain
+	}
+	if !link.RevokedAt.IsZero() {
+		response["revoked_at"] = link.RevokedAt
+	}
+	return response
+}
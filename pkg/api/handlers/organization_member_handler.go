@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// Number of members returned per page, by default and at most
+const (
+	defaultMembersLimit = 50
+	maxMembersLimit     = 200
+)
+
+// GetMembers lists the members of the organization ordered by email, optionally only those
+// whose name or email contains the search query q. Further pages are requested with the
+// next_cursor of the previous page.
+func (oh *OrganizationHandler) GetMembers(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	filter := repository.MemberFilter{
+		OrganizationID: organizationID,
+		Query:          strings.TrimSpace(c.Query("q")),
+		Limit:          defaultMembersLimit,
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		filter.AfterEmail, filter.AfterID, err = parseMemberCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxMembersLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxMembersLimit)})
+			return
+		}
+	}
+
+	members, err := oh.membershipRepository.GetMembers(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+
+	response := make([]gin.H, len(members))
+	for i, member := range members {
+		response[i] = memberResponse(member)
+	}
+
+	// A full page may be followed by more members
+	result := gin.H{"members": response}
+	if int64(len(members)) == filter.Limit {
+		result["next_cursor"] = memberCursor(members[len(members)-1])
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// memberCursor returns the cursor of the page following a member: their ID and email, which
+// the members are ordered by.
+func memberCursor(member *models.OrganizationMember) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(member.ID.Hex() + member.Email))
+}
+
+// parseMemberCursor returns the email and ID of the member a cursor was returned for.
+func parseMemberCursor(cursor string) (string, primitive.ObjectID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(decoded) < 24 {
+		return "", primitive.NilObjectID, errors.New("invalid member cursor")
+	}
+	id, err := primitive.ObjectIDFromHex(string(decoded[:24]))
+	if err != nil {
+		return "", primitive.NilObjectID, err
+	}
+	return string(decoded[24:]), id, nil
+}
+
+// UpdateMember changes the access level of a member. The last active admin and the owner can't
+// be demoted.
+func (oh *OrganizationHandler) UpdateMember(c *gin.Context) {
+	organizationID, member, ok := oh.findMember(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AccessLevel string `json:"access_level"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.AccessLevel != models.AccessLevelAdmin && req.AccessLevel != models.AccessLevelMember {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Access level must be admin or member"})
+		return
+	}
+
+	updated := *member
+	updated.AccessLevel = req.AccessLevel
+	if updated.AccessLevel != member.AccessLevel {
+		err := oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+			if err := oh.membershipRepository.SetMemberAccessLevel(ctx, organizationID, member.UserID, req.AccessLevel); err != nil {
+				return err
+			}
+			return oh.outboxRepository.AddEvent(ctx, organizationID, models.EventMemberUpdated, gin.H{
+				"member":  updated,
+				"changes": auditDiff(member, &updated),
+			})
+		})
+		if err != nil {
+			respondMemberChangeError(c, err, "Failed to update member")
+			return
+		}
+
+		// Record the new access level in the audit log
+		recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+			OrganizationID: organizationID,
+			Action:         models.AuditActionMemberUpdated,
+			TargetType:     models.AuditTargetMember,
+			TargetID:       member.Email,
+			Changes:        auditDiff(member, &updated),
+		})
+	}
+
+	c.JSON(http.StatusOK, memberResponse(&updated))
+}
+
+// RemoveMember removes a member from the organization. The last active admin and the owner
+// can't be removed.
+func (oh *OrganizationHandler) RemoveMember(c *gin.Context) {
+	organizationID, member, ok := oh.findMember(c)
+	if !ok {
+		return
+	}
+
+	if !oh.removeMember(c, organizationID, member, models.AuditActionMemberRemoved) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// LeaveOrganization removes the signed in user from the organization. The last active admin
+// must appoint another admin first, and the owner must transfer the ownership.
+func (oh *OrganizationHandler) LeaveOrganization(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Tokens issued to the organization aren't one of its members
+	if c.GetString("token_organization_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Organization tokens can't leave the organization"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	member, err := oh.membershipRepository.GetMember(context.Background(), organizationID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if !oh.removeMember(c, organizationID, member, models.AuditActionMemberLeft) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You left the organization"})
+}
+
+// findMember looks up the member identified by the ID of their user in the route. It responds
+// with an error and returns false when there is no such member.
+func (oh *OrganizationHandler) findMember(c *gin.Context) (primitive.ObjectID, *models.OrganizationMember, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return primitive.NilObjectID, nil, false
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return primitive.NilObjectID, nil, false
+	}
+
+	member, err := oh.membershipRepository.GetMember(context.Background(), organizationID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return primitive.NilObjectID, nil, false
+	}
+
+	return organizationID, member, true
+}
+
+// removeMember removes a member along with a member.removed event, and records the removal
+// in the audit log under the given action. It responds with an error and returns false when
+// the member couldn't be removed.
+func (oh *OrganizationHandler) removeMember(c *gin.Context, organizationID primitive.ObjectID, member *models.OrganizationMember, action string) bool {
+	err := oh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := oh.membershipRepository.RemoveMemberKeepingAdmin(ctx, organizationID, member.UserID); err != nil {
+			return err
+		}
+		return oh.outboxRepository.AddEvent(ctx, organizationID, models.EventMemberRemoved, gin.H{"member": member})
+	})
+	if err != nil {
+		respondMemberChangeError(c, err, "Failed to remove member")
+		return false
+	}
+
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         action,
+		TargetType:     models.AuditTargetMember,
+		TargetID:       member.Email,
+		Changes:        auditDiff(member, nil),
+	})
+	return true
+}
+
+// respondMemberChangeError responds to a change of a member which failed.
+func respondMemberChangeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	case errors.Is(err, repository.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "The organization must keep at least one active admin"})
+	case errors.Is(err, repository.ErrOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": "The owner of the organization must transfer its ownership first"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// memberResponse returns the attributes of a member returned by the member endpoints.
+func memberResponse(member *models.OrganizationMember) gin.H {
+	response := gin.H{
+		"user_id":      member.UserID.Hex(),
+		"name":         member.Name,
+		"email":        member.Email,
+		"access_level": member.AccessLevel,
+		"suspended":    member.Suspended,
+		"joined_at":    member.CreatedAt,
+	}
+	if member.ExternalID != "" {
+		response["external_id"] = member.ExternalID
+	}
+	return response
+}
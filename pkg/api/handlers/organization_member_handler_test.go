@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+func TestGetMembers(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organizationID := primitive.NewObjectID()
+	first := &models.OrganizationMember{ID: primitive.NewObjectID(), OrganizationID: organizationID, UserID: primitive.NewObjectID(), Email: "jane@example.com"}
+	second := &models.OrganizationMember{ID: primitive.NewObjectID(), OrganizationID: organizationID, UserID: primitive.NewObjectID(), Email: "jane@example.com"}
+
+	getMembers := func(mt *mtest.T, query string) (int, string) {
+		router := gin.New()
+		router.GET("/organizations/:id/members", newTestOrganizationHandler(mt).GetMembers)
+
+		recorder := serve(router, http.MethodGet, "/organizations/"+organizationID.Hex()+"/members?"+query)
+		var response struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				mt.Fatal(err)
+			}
+		}
+		return recorder.Code, response.NextCursor
+	}
+
+	mt.Run("pages through members sharing an email", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, first)))
+		code, cursor := getMembers(mt, "limit=1")
+		if code != http.StatusOK || cursor == "" {
+			mt.Fatalf("first page returned %d with cursor %q, want a cursor", code, cursor)
+		}
+
+		mt.ClearEvents()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, second)))
+		if code, _ := getMembers(mt, url.Values{"limit": {"1"}, "q": {"jane"}, "cursor": {cursor}}.Encode()); code != http.StatusOK {
+			mt.Fatalf("second page returned %d", code)
+		}
+
+		find := mt.GetStartedEvent().Command
+		conditions, err := find.Lookup("filter", "$and").Array().Values()
+		if err != nil || len(conditions) != 2 {
+			mt.Fatalf("filter %v, want the search and the cursor", find.Lookup("filter"))
+		}
+		after := conditions[1].Document().Lookup("$or", "1")
+		if after.Document().Lookup("email").StringValue() != first.Email || after.Document().Lookup("_id").Document().Lookup("$gt").ObjectID() != first.ID {
+			mt.Errorf("cursor condition %v, want the members after the first one with its email", after)
+		}
+		sort, err := find.Lookup("sort").Document().Elements()
+		if err != nil || len(sort) != 2 || sort[0].Key() != "email" || sort[1].Key() != "_id" {
+			mt.Errorf("sort %v, want by email then ID", find.Lookup("sort"))
+		}
+	})
+
+	mt.Run("invalid cursor", func(mt *mtest.T) {
+		if code, _ := getMembers(mt, "cursor=jane@example.com"); code != http.StatusBadRequest {
+			mt.Errorf("request returned %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// ownershipTransferLifetime is how long a transfer of ownership can be accepted.
+const ownershipTransferLifetime = 7 * 24 * time.Hour
+
+// GetOwnership returns the owner of the organization and the transfer of its ownership
+// pending, if any.
+func (oh *OrganizationHandler) GetOwnership(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ownershipResponse(organization))
+}
+
+// RequestOwnershipTransfer offers the ownership of the organization to one of its members, who
+// becomes the owner once they accept it with AcceptOwnershipTransfer. Only the owner may
+// transfer the ownership, or any admin when the organization has no active owner, because it
+// was created before owners were introduced or its owner is no longer an active admin.
+func (oh *OrganizationHandler) RequestOwnershipTransfer(c *gin.Context) {
+	organization, userID, ok := oh.ownershipOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	toUserID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Only the owner may give the ownership away, unless there is no active owner
+	if organization.OwnerID != userID {
+		active, err := oh.hasActiveOwner(context.Background(), organization)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+			return
+		}
+		if active {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can transfer the ownership of the organization"})
+			return
+		}
+	}
+	if toUserID == organization.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The user already owns the organization"})
+		return
+	}
+
+	// The ownership can only go to an active member
+	member, err := oh.membershipRepository.GetMember(context.Background(), organization.ID, toUserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotMember) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	if member.Suspended {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The ownership can't be transferred to a suspended member"})
+		return
+	}
+
+	now := time.Now()
+	transfer := &models.OwnershipTransfer{
+		ToUserID:    toUserID,
+		RequestedBy: userID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(ownershipTransferLifetime),
+	}
+	if err := oh.organizationRepository.RequestOwnershipTransfer(context.Background(), organization.ID, transfer); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+
+	// Record the request in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organization.ID,
+		Action:         models.AuditActionOwnershipRequested,
+		TargetType:     models.AuditTargetMember,
+		TargetID:       member.Email,
+		Changes:        auditDiff(nil, transfer),
+	})
+
+	organization.OwnershipTransfer = transfer
+	c.JSON(http.StatusOK, ownershipResponse(organization))
+}
+
+// CancelOwnershipTransfer drops the pending transfer of the ownership of the organization. It
+// is canceled by the owner or the member who requested it, or declined by the member it was
+// offered to.
+func (oh *OrganizationHandler) CancelOwnershipTransfer(c *gin.Context) {
+	organization, userID, ok := oh.ownershipOrganization(c)
+	if !ok {
+		return
+	}
+
+	transfer := organization.OwnershipTransfer
+	if transfer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No ownership transfer pending"})
+		return
+	}
+	if userID != organization.OwnerID && userID != transfer.RequestedBy && userID != transfer.ToUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner and the members involved can cancel the ownership transfer"})
+		return
+	}
+
+	if err := oh.organizationRepository.CancelOwnershipTransfer(context.Background(), organization.ID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No ownership transfer pending"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ownership transfer"})
+		return
+	}
+
+	// Record the cancellation in the audit log
+	recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organization.ID,
+		Action:         models.AuditActionOwnershipCanceled,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       organization.ID.Hex(),
+		Changes:        auditDiff(transfer, nil),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transfer canceled"})
+}
+
+// AcceptOwnershipTransfer makes the signed in member the owner of the organization, when its
+// ownership was offered to them. They become an admin if they weren't one, and the previous
+// owner remains an admin.
+func (oh *OrganizationHandler) AcceptOwnershipTransfer(c *gin.Context) {
+	organization, userID, ok := oh.ownershipOrganization(c)
+	if !ok {
+		return
+	}
+
+	if !organization.OwnershipTransfer.Pending() || organization.OwnershipTransfer.ToUserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No ownership transfer pending for you"})
+		return
+	}
+
+	// The new owner becomes an admin along with the transfer
+	var member, promoted *models.OrganizationMember
+	after, err := oh.changeOrganization(organization, func(ctx context.Context) error {
+		var err error
+		member, err = oh.membershipRepository.GetMember(ctx, organization.ID, userID)
+		if err != nil {
+			return err
+		}
+		if member.Suspended {
+			return errMemberSuspended
+		}
+
+		if member.AccessLevel != models.AccessLevelAdmin {
+			updated := *member
+			updated.AccessLevel = models.AccessLevelAdmin
+			if err := oh.membershipRepository.SetMemberAccessLevel(ctx, organization.ID, userID, updated.AccessLevel); err != nil {
+				return err
+			}
+			if err := oh.outboxRepository.AddEvent(ctx, organization.ID, models.EventMemberUpdated, gin.H{
+				"member":  updated,
+				"changes": auditDiff(member, &updated),
+			}); err != nil {
+				return err
+			}
+			promoted = &updated
+		}
+
+		return oh.organizationRepository.AcceptOwnershipTransfer(ctx, organization.ID, userID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			c.JSON(http.StatusNotFound, gin.H{"error": "No ownership transfer pending for you"})
+		case errors.Is(err, repository.ErrNotMember), errors.Is(err, errMemberSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only active members can accept the ownership of the organization"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept ownership transfer"})
+		}
+		return
+	}
+
+	// Record the new owner, and their new access level, in the audit log
+	if promoted != nil {
+		recordAuditEvent(c, oh.auditEventRepository, &models.AuditEvent{
+			OrganizationID: organization.ID,
+			Action:         models.AuditActionMemberUpdated,
+			TargetType:     models.AuditTargetMember,
+			TargetID:       member.Email,
+			Changes:        auditDiff(member, promoted),
+		})
+	}
+	oh.recordOrganizationChange(c, models.AuditActionOwnershipTransferred, organization, after)
+
+	c.JSON(http.StatusOK, ownershipResponse(after))
+}
+
+// ownershipOrganization loads the organization identified by the route, and the signed in user
+// acting on its ownership. It responds with an error and returns false when either is missing.
+func (oh *OrganizationHandler) ownershipOrganization(c *gin.Context) (*models.Organization, primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, primitive.NilObjectID, false
+	}
+
+	// Tokens issued to the organization aren't one of its members
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil || c.GetString("token_organization_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only users can manage the ownership of the organization"})
+		return nil, primitive.NilObjectID, false
+	}
+
+	organization, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, primitive.NilObjectID, false
+	}
+
+	return organization, userID, true
+}
+
+// hasActiveOwner reports whether the owner of an organization is one of its active admins.
+func (oh *OrganizationHandler) hasActiveOwner(ctx context.Context, organization *models.Organization) (bool, error) {
+	if organization.OwnerID.IsZero() {
+		return false, nil
+	}
+
+	owner, err := oh.membershipRepository.GetMember(ctx, organization.ID, organization.OwnerID)
+	if errors.Is(err, repository.ErrNotMember) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner.AccessLevel == models.AccessLevelAdmin && !owner.Suspended, nil
+}
+
+// ownershipResponse returns the owner of an organization and the transfer of its ownership
+// pending, if any.
+func ownershipResponse(organization *models.Organization) gin.H {
+	response := gin.H{
+		"organization_id":  organization.ID.Hex(),
+		"owner_id":         nil,
+		"pending_transfer": nil,
+	}
+	if !organization.OwnerID.IsZero() {
+		response["owner_id"] = organization.OwnerID.Hex()
+	}
+	if organization.OwnershipTransfer.Pending() {
+		response["pending_transfer"] = organization.OwnershipTransfer
+	}
+	return response
+}
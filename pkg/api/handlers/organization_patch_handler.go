@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// Media types of the patches accepted by PatchOrganization
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
+// maxPatchAttempts is how many times a patch is applied to the latest version of an
+// organization changing concurrently, when the client didn't send If-Match.
+const maxPatchAttempts = 3
+
+// patchableOrganizationFields lists the attributes patches can change, with the access level
+// required to change them. Other attributes are managed through their own endpoints.
+var patchableOrganizationFields = map[string]string{
+	"name":        models.AccessLevelMember,
+	"description": models.AccessLevelMember,
+	"require_mfa": models.AccessLevelAdmin,
+}
+
+// patchableOrganization is the document patches are applied to.
+type patchableOrganization struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	RequireMFA  bool   `json:"require_mfa"`
+}
+
+// patchError is a patch which can't be applied, with the status to respond with.
+type patchError struct {
+	status  int
+	message string
+}
+
+func (e *patchError) Error() string {
+	return e.message
+}
+
+// PatchOrganization changes some attributes of an organization, with a JSON Merge Patch
+// (RFC 7396) or a JSON Patch (RFC 6902) depending on the content type. Unlike
+// UpdateOrganization, attributes can be cleared.
+func (oh *OrganizationHandler) PatchOrganization(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Plain JSON is taken as a merge patch
+	mediaType := c.ContentType()
+	if mediaType == "application/json" {
+		mediaType = mergePatchMediaType
+	}
+	if mediaType != mergePatchMediaType && mediaType != jsonPatchMediaType {
+		c.Header("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch format"})
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ifMatch := c.GetHeader("If-Match")
+	for attempt := 1; ; attempt++ {
+		// Keep the current state for the audit log
+		before, err := oh.organizationRepository.GetOrganizationByID(context.Background(), objectID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		if ifMatch != "" && !matchesETag(ifMatch, before) {
+			respondVersionMismatch(c, before)
+			return
+		}
+
+		changes, err := applyOrganizationPatch(before, mediaType, patch, c.GetString("access_level"))
+		if err != nil {
+			var pe *patchError
+			if errors.As(err, &pe) {
+				c.JSON(pe.status, gin.H{"error": pe.message})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to patch organization"})
+			return
+		}
+
+		// The patch only applies to the version it was computed from
+		after := before
+		if len(changes) > 0 {
+			after, err = oh.changeOrganization(before, func(ctx context.Context) error {
+				return oh.organizationRepository.PatchOrganization(ctx, objectID, changes, before.Version)
+			})
+			if errors.Is(err, repository.ErrVersionMismatch) {
+				if ifMatch == "" && attempt < maxPatchAttempts {
+					continue
+				}
+				respondVersionMismatch(c, nil)
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to patch organization"})
+				return
+			}
+
+			// Record the changes in the audit log
+			oh.recordOrganizationChange(c, models.AuditActionOrganizationUpdated, before, after)
+		}
+
+		c.Header("ETag", organizationETag(after))
+		c.JSON(http.StatusOK, gin.H{
+			"organization_id": after.ID.Hex(),
+			"name":            after.Name,
+			"description":     after.Description,
+			"require_mfa":     after.RequireMFA,
+			"version":         after.Version,
+		})
+		return
+	}
+}
+
+// applyOrganizationPatch applies a patch to the patchable attributes of an organization, and
+// returns the attributes which changed, nil for those which were removed. It fails with a
+// patchError when the patch is invalid or changes attributes the access level doesn't allow.
+func applyOrganizationPatch(organization *models.Organization, mediaType string, patch []byte, accessLevel string) (map[string]interface{}, error) {
+	before := patchableOrganization{
+		Name:        organization.Name,
+		Description: organization.Description,
+		RequireMFA:  organization.RequireMFA,
+	}
+	document, err := json.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	if mediaType == jsonPatchMediaType {
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, &patchError{http.StatusBadRequest, "Invalid JSON Patch"}
+		}
+		patched, err = operations.Apply(document)
+		if err != nil {
+			return nil, &patchError{http.StatusConflict, "The patch can't be applied: " + err.Error()}
+		}
+	} else {
+		patched, err = jsonpatch.MergePatch(document, patch)
+		if err != nil {
+			return nil, &patchError{http.StatusBadRequest, "Invalid merge patch"}
+		}
+	}
+
+	// Only patchable attributes may be present in the result
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil || fields == nil {
+		return nil, &patchError{http.StatusUnprocessableEntity, "The patched organization must be an object"}
+	}
+	for field := range fields {
+		if _, ok := patchableOrganizationFields[field]; !ok {
+			return nil, &patchError{http.StatusUnprocessableEntity, "Field " + field + " can't be patched"}
+		}
+	}
+
+	var after patchableOrganization
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, &patchError{http.StatusUnprocessableEntity, "Invalid value in the patched organization"}
+	}
+	if strings.TrimSpace(after.Name) == "" {
+		return nil, &patchError{http.StatusUnprocessableEntity, "Name is required"}
+	}
+
+	changes := map[string]interface{}{}
+	if after.Name != before.Name {
+		changes["name"] = after.Name
+	}
+	if after.Description != before.Description {
+		changes["description"] = after.Description
+		if after.Description == "" {
+			changes["description"] = nil
+		}
+	}
+	if after.RequireMFA != before.RequireMFA {
+		changes["require_mfa"] = after.RequireMFA
+	}
+
+	// Check the access level required by each changed attribute
+	for field := range changes {
+		if patchableOrganizationFields[field] == models.AccessLevelAdmin && accessLevel != models.AccessLevelAdmin {
+			return nil, &patchError{http.StatusForbidden, "Only admins can change " + field}
+		}
+	}
+
+	return changes, nil
+}
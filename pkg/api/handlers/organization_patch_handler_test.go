@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+func TestApplyOrganizationPatch(t *testing.T) {
+	organization := &models.Organization{Name: "Example", Description: "Before"}
+
+	tests := []struct {
+		name        string
+		mediaType   string
+		patch       string
+		accessLevel string
+		changes     map[string]interface{}
+		status      int
+	}{
+		{
+			name:      "merge patch sets attributes",
+			mediaType: mergePatchMediaType,
+			patch:     `{"name": "Renamed"}`,
+			changes:   map[string]interface{}{"name": "Renamed"},
+		},
+		{
+			name:      "merge patch clears attributes set to null",
+			mediaType: mergePatchMediaType,
+			patch:     `{"description": null}`,
+			changes:   map[string]interface{}{"description": nil},
+		},
+		{
+			name:      "merge patch adding an unknown attribute",
+			mediaType: mergePatchMediaType,
+			patch:     `{"owner_id": "someone"}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "merge patch removing the name",
+			mediaType: mergePatchMediaType,
+			patch:     `{"name": null}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "JSON Patch replaces and removes attributes",
+			mediaType: jsonPatchMediaType,
+			patch:     `[{"op": "replace", "path": "/name", "value": "Renamed"}, {"op": "remove", "path": "/description"}]`,
+			changes:   map[string]interface{}{"name": "Renamed", "description": nil},
+		},
+		{
+			name:      "JSON Patch whose test fails",
+			mediaType: jsonPatchMediaType,
+			patch:     `[{"op": "test", "path": "/name", "value": "Other"}, {"op": "replace", "path": "/name", "value": "Renamed"}]`,
+			status:    http.StatusConflict,
+		},
+		{
+			name:      "merge patch sent as JSON Patch",
+			mediaType: jsonPatchMediaType,
+			patch:     `{"name": "Renamed"}`,
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:        "member requiring MFA",
+			mediaType:   mergePatchMediaType,
+			patch:       `{"require_mfa": true}`,
+			accessLevel: models.AccessLevelMember,
+			status:      http.StatusForbidden,
+		},
+		{
+			name:        "admin requiring MFA",
+			mediaType:   jsonPatchMediaType,
+			patch:       `[{"op": "replace", "path": "/require_mfa", "value": true}]`,
+			accessLevel: models.AccessLevelAdmin,
+			changes:     map[string]interface{}{"require_mfa": true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessLevel := test.accessLevel
+			if accessLevel == "" {
+				accessLevel = models.AccessLevelMember
+			}
+
+			changes, err := applyOrganizationPatch(organization, test.mediaType, []byte(test.patch), accessLevel)
+			if test.status != 0 {
+				var pe *patchError
+				if !errors.As(err, &pe) || pe.status != test.status {
+					t.Fatalf("error = %v, want a patch error with status %d", err, test.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(changes, test.changes) {
+				t.Errorf("changes = %v, want %v", changes, test.changes)
+			}
+		})
+	}
+}
+
+func TestPatchOrganization(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", Description: "Before", Version: 3}
+	organizations := "test.organizations"
+
+	patchOrganization := func(mt *mtest.T, contentType, body string) (int, http.Header) {
+		router := gin.New()
+		router.PATCH("/organizations/:id", newTestOrganizationHandler(mt).PatchOrganization)
+		recorder := serveJSON(router, http.MethodPatch, "/organizations/"+organization.ID.Hex(), body, map[string]string{"Content-Type": contentType})
+		return recorder.Code, recorder.Header()
+	}
+
+	mt.Run("merge patch removes attributes", func(mt *mtest.T) {
+		patched := *organization
+		patched.Description = ""
+		patched.Version = 4
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, organization)),
+			updateSucceeds,
+			mtest.CreateCursorResponse(0, organizations, mtest.FirstBatch, mockDocument(mt, &patched)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		if code, _ := patchOrganization(mt, mergePatchMediaType, `{"description": null}`); code != http.StatusOK {
+			mt.Fatalf("patch returned %d", code)
+		}
+		update := mt.GetAllStartedEvents()[1].Command.Lookup("updates", "0")
+		if _, err := update.Document().LookupErr("u", "$unset", "description"); err != nil {
+			mt.Errorf("update %v, want the description removed", update)
+		}
+		if version := update.Document().Lookup("q", "version"); version.Int64() != organization.Version {
+			mt.Errorf("update filters on version %v, want %d", version, organization.Version)
+		}
+	})
+
+	mt.Run("unsupported patch format", func(mt *mtest.T) {
+		code, header := patchOrganization(mt, "text/plain", `name=Renamed`)
+		if code != http.StatusUnsupportedMediaType {
+			mt.Fatalf("patch returned %d, want %d", code, http.StatusUnsupportedMediaType)
+		}
+		if accept := header.Get("Accept-Patch"); accept != mergePatchMediaType+", "+jsonPatchMediaType {
+			mt.Errorf("Accept-Patch = %q, want both patch formats", accept)
+		}
+	})
+}
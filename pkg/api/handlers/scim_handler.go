@@ -0,0 +1,774 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scim"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scimMaxResults caps the number of resources returned in one SCIM list response.
+const scimMaxResults = 200
+
+// SCIMHandler serves the SCIM 2.0 provisioning API. Each token acts on one organization:
+// SCIM users are the members of that organization and its only group is the organization.
+type SCIMHandler struct {
+	organizationRepository *repository.OrganizationRepository
+	membershipRepository   *repository.MembershipRepository
+	userRepository         *repository.UserRepository
+	auditEventRepository   *repository.AuditEventRepository
+	outboxRepository       *repository.OutboxRepository
+	transactions           *repository.Transactions
+}
+
+func NewSCIMHandler(organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, userRepository *repository.UserRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions) *SCIMHandler {
+	return &SCIMHandler{
+		organizationRepository: organizationRepository,
+		membershipRepository:   membershipRepository,
+		userRepository:         userRepository,
+		auditEventRepository:   auditEventRepository,
+		outboxRepository:       outboxRepository,
+		transactions:           transactions,
+	}
+}
+
+// CreateToken issues a new SCIM token for the organization, replacing the previous one. The
+// token is only returned once.
+func (sh *SCIMHandler) CreateToken(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	token, err := utils.GenerateOpaqueToken(utils.SCIMTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate SCIM token"})
+		return
+	}
+
+	if err := sh.organizationRepository.SetSCIMTokenHash(context.Background(), organizationID, utils.HashOpaqueToken(token)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save SCIM token"})
+		return
+	}
+
+	// Only the prefix of the token is recorded, to recognize it
+	recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionSCIMTokenCreated,
+		TargetType:     models.AuditTargetSCIMToken,
+		TargetID:       organizationID.Hex(),
+		Changes: map[string]models.AuditChange{
+			"prefix": {After: token[:displayedPrefixLength]},
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "SCIM token created successfully",
+		"token":    token,
+		"base_url": "/scim/v2",
+	})
+}
+
+// DeleteToken revokes the organization's SCIM token.
+func (sh *SCIMHandler) DeleteToken(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	if err := sh.organizationRepository.SetSCIMTokenHash(context.Background(), organizationID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke SCIM token"})
+		return
+	}
+
+	recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionSCIMTokenRevoked,
+		TargetType:     models.AuditTargetSCIMToken,
+		TargetID:       organizationID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "SCIM token revoked successfully"})
+}
+
+func (sh *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	respondSCIM(c, http.StatusOK, gin.H{
+		"schemas":        []string{scim.ServiceProviderConfigSchema},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the organization's SCIM token",
+			"primary":     true,
+		}},
+	})
+}
+
+func (sh *SCIMHandler) ListUsers(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	users, err := sh.memberUsers(context.Background(), organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to list users")
+		return
+	}
+
+	resources := make([]scim.Resource, len(users))
+	for i, user := range users {
+		resources[i] = user
+	}
+	listSCIMResources(c, resources)
+}
+
+func (sh *SCIMHandler) GetUser(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	user, member, ok := sh.scimMember(c, organization)
+	if !ok {
+		return
+	}
+
+	respondSCIM(c, http.StatusOK, scimUser(user, member))
+}
+
+// CreateUser makes a user a member of the organization, creating the user first when no
+// account exists for their email. Users created this way have no password and sign in
+// through single sign-on. Existing accounts are only added when the organization may
+// provision them, see scimProvisionable.
+func (sh *SCIMHandler) CreateUser(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req scim.User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email())
+	if req.UserName == "" || !strings.Contains(email, "@") {
+		respondSCIMError(c, http.StatusBadRequest, "invalidValue", "userName and a valid email are required")
+		return
+	}
+
+	// Reuse the account of users who already signed up
+	user, err := sh.userRepository.GetUserByEmail(context.Background(), email)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		user = &models.User{
+			ID:            primitive.NewObjectID(),
+			Name:          req.FullName(),
+			Email:         email,
+			ProvisionedBy: organization.ID,
+		}
+		if err := sh.userRepository.CreateUser(context.Background(), user); err != nil {
+			respondSCIMError(c, http.StatusInternalServerError, "", "Failed to create user")
+			return
+		}
+	case err != nil:
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	case !scimProvisionable(organization, user):
+		respondSCIMError(c, http.StatusConflict, "uniqueness", "userName belongs to an account outside the organization's verified domains")
+		return
+	}
+
+	name := req.FullName()
+	if name == "" {
+		name = user.Name
+	}
+	member := models.OrganizationMember{
+		UserID:      user.ID,
+		Name:        name,
+		Email:       user.Email,
+		AccessLevel: models.AccessLevelMember,
+		ExternalID:  req.ExternalID,
+		Suspended:   req.Active != nil && !*req.Active,
+	}
+	if err := sh.addMember(context.Background(), organization.ID, member); err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			respondSCIMError(c, http.StatusConflict, "uniqueness", "User is already a member of the organization")
+			return
+		}
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to add user to the organization")
+		return
+	}
+
+	respondSCIM(c, http.StatusCreated, scimUser(user, &member))
+}
+
+// ReplaceUser replaces the attributes of a member. The user name can't be changed.
+func (sh *SCIMHandler) ReplaceUser(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	user, member, ok := sh.scimMember(c, organization)
+	if !ok {
+		return
+	}
+
+	var req scim.User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	if !strings.EqualFold(req.UserName, user.Email) {
+		respondSCIMError(c, http.StatusBadRequest, "mutability", "userName can't be changed")
+		return
+	}
+
+	sh.saveUser(c, organization, user, member, &req)
+}
+
+func (sh *SCIMHandler) PatchUser(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	user, member, ok := sh.scimMember(c, organization)
+	if !ok {
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	// Apply the operations to the current representation of the user
+	resource := scimUser(user, member)
+	if err := scim.ApplyUserPatch(resource, req.Operations); err != nil {
+		respondSCIMPatchError(c, err)
+		return
+	}
+
+	sh.saveUser(c, organization, user, member, resource)
+}
+
+// DeleteUser removes a member from the organization. The user account is kept, since it may
+// belong to other organizations.
+func (sh *SCIMHandler) DeleteUser(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	_, member, ok := sh.scimMember(c, organization)
+	if !ok {
+		return
+	}
+
+	if err := sh.removeMember(context.Background(), organization.ID, member); err != nil {
+		respondSCIMMemberError(c, err, "Failed to remove user from the organization")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (sh *SCIMHandler) ListGroups(c *gin.Context) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return
+	}
+
+	group, err := sh.scimGroup(context.Background(), organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to list groups")
+		return
+	}
+
+	listSCIMResources(c, []scim.Resource{group})
+}
+
+func (sh *SCIMHandler) GetGroup(c *gin.Context) {
+	organization, ok := sh.scimGroupOrganization(c)
+	if !ok {
+		return
+	}
+
+	group, err := sh.scimGroup(context.Background(), organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to get group")
+		return
+	}
+
+	respondSCIM(c, http.StatusOK, group)
+}
+
+// ReplaceGroup replaces the name and members of the organization.
+func (sh *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	organization, ok := sh.scimGroupOrganization(c)
+	if !ok {
+		return
+	}
+
+	current, err := sh.scimGroup(context.Background(), organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to get group")
+		return
+	}
+
+	var req scim.Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	sh.saveGroup(c, organization, current, &req)
+}
+
+func (sh *SCIMHandler) PatchGroup(c *gin.Context) {
+	organization, ok := sh.scimGroupOrganization(c)
+	if !ok {
+		return
+	}
+
+	current, err := sh.scimGroup(context.Background(), organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to get group")
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	// Apply the operations to a copy, so the changes can be compared with the current members
+	updated := *current
+	updated.Members = append([]scim.Member(nil), current.Members...)
+	if err := scim.ApplyGroupPatch(&updated, req.Operations); err != nil {
+		respondSCIMPatchError(c, err)
+		return
+	}
+
+	sh.saveGroup(c, organization, current, &updated)
+}
+
+// UnsupportedGroupOperation rejects creating and deleting groups, which map to organizations
+// managed through the API.
+func (sh *SCIMHandler) UnsupportedGroupOperation(c *gin.Context) {
+	respondSCIMError(c, http.StatusForbidden, "", "Groups map to organizations and can't be created or deleted through SCIM")
+}
+
+// saveUser stores the attributes of a SCIM user on its membership. The user account may belong
+// to other organizations, so it is left as is.
+func (sh *SCIMHandler) saveUser(c *gin.Context, organization *models.Organization, user *models.User, member *models.OrganizationMember, resource *scim.User) {
+	updated := *member
+	if name := resource.FullName(); name != "" {
+		updated.Name = name
+	}
+	updated.ExternalID = resource.ExternalID
+	updated.Suspended = resource.Active != nil && !*resource.Active
+
+	// Like changes made through the API, changes are recorded in the outbox and the audit log
+	changes := auditDiff(member, &updated)
+	if len(changes) > 0 {
+		err := sh.transactions.Run(context.Background(), func(ctx context.Context) error {
+			if err := sh.membershipRepository.UpdateMember(ctx, organization.ID, updated); err != nil {
+				return err
+			}
+			return sh.outboxRepository.AddEvent(ctx, organization.ID, models.EventMemberUpdated, gin.H{
+				"member":  updated,
+				"changes": changes,
+			})
+		})
+		if err != nil {
+			respondSCIMMemberError(c, err, "Failed to update user")
+			return
+		}
+
+		recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+			OrganizationID: organization.ID,
+			Action:         models.AuditActionMemberUpdated,
+			TargetType:     models.AuditTargetMember,
+			TargetID:       updated.Email,
+			Changes:        changes,
+		})
+	}
+
+	respondSCIM(c, http.StatusOK, scimUser(user, &updated))
+}
+
+// saveGroup renames the organization and adds or removes members to match the updated group.
+func (sh *SCIMHandler) saveGroup(c *gin.Context, organization *models.Organization, current, updated *scim.Group) {
+	ctx := context.Background()
+
+	// Renaming the group renames the organization, as long as it wasn't changed since it was read
+	if updated.DisplayName != "" && updated.DisplayName != organization.Name {
+		renamed, err := changeOrganization(sh.transactions, sh.organizationRepository, sh.outboxRepository, organization, func(ctx context.Context) error {
+			_, err := sh.organizationRepository.UpdateOrganization(ctx, organization.ID, updated.DisplayName, "", &organization.Version)
+			return err
+		})
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			respondSCIMError(c, http.StatusPreconditionFailed, "", "The group was modified, fetch it again before updating")
+			return
+		}
+		if err != nil {
+			respondSCIMError(c, http.StatusInternalServerError, "", "Failed to update group")
+			return
+		}
+
+		recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+			OrganizationID: organization.ID,
+			Action:         models.AuditActionOrganizationUpdated,
+			TargetType:     models.AuditTargetOrganization,
+			TargetID:       organization.ID.Hex(),
+			Changes:        auditDiff(organization, renamed),
+		})
+	}
+
+	currentMembers := map[string]string{}
+	for _, member := range current.Members {
+		currentMembers[member.Value] = member.Display
+	}
+
+	// Add the users that weren't members yet
+	updatedMembers := map[string]bool{}
+	for _, member := range updated.Members {
+		updatedMembers[member.Value] = true
+		if _, ok := currentMembers[member.Value]; ok {
+			continue
+		}
+
+		// Only users the organization may provision can be added, others are unknown to it
+		userID, err := primitive.ObjectIDFromHex(member.Value)
+		if err != nil {
+			respondSCIMError(c, http.StatusBadRequest, "invalidValue", "Unknown user "+member.Value)
+			return
+		}
+		user, err := sh.userRepository.GetUserProfile(ctx, userID)
+		if err != nil || !scimProvisionable(organization, user) {
+			respondSCIMError(c, http.StatusBadRequest, "invalidValue", "Unknown user "+member.Value)
+			return
+		}
+
+		member := models.OrganizationMember{
+			UserID:      user.ID,
+			Name:        user.Name,
+			Email:       user.Email,
+			AccessLevel: models.AccessLevelMember,
+		}
+		err = sh.addMember(ctx, organization.ID, member)
+		if errors.Is(err, repository.ErrMemberExists) {
+			continue
+		}
+		if err != nil {
+			respondSCIMError(c, http.StatusInternalServerError, "", "Failed to add member")
+			return
+		}
+	}
+
+	// Remove the members that are no longer part of the group
+	for value := range currentMembers {
+		if updatedMembers[value] {
+			continue
+		}
+		userID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			continue
+		}
+		member, err := sh.membershipRepository.GetMember(ctx, organization.ID, userID)
+		if err == nil {
+			err = sh.removeMember(ctx, organization.ID, member)
+		}
+		if errors.Is(err, repository.ErrNotMember) {
+			continue
+		}
+		if err != nil {
+			respondSCIMMemberError(c, err, "Failed to remove member")
+			return
+		}
+	}
+
+	organization, err := sh.organizationRepository.GetOrganizationByID(ctx, organization.ID)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to get group")
+		return
+	}
+	group, err := sh.scimGroup(ctx, organization)
+	if err != nil {
+		respondSCIMError(c, http.StatusInternalServerError, "", "Failed to get group")
+		return
+	}
+
+	respondSCIM(c, http.StatusOK, group)
+}
+
+// addMember adds a member to the organization and records the member.added event in the same
+// transaction.
+func (sh *SCIMHandler) addMember(ctx context.Context, organizationID primitive.ObjectID, member models.OrganizationMember) error {
+	return sh.transactions.Run(ctx, func(ctx context.Context) error {
+		if err := sh.membershipRepository.AddMember(ctx, organizationID, member); err != nil {
+			return err
+		}
+		return sh.outboxRepository.AddEvent(ctx, organizationID, models.EventMemberAdded, gin.H{"member": member})
+	})
+}
+
+// removeMember removes a member from the organization and records the member.removed event in
+// the same transaction. Like members removed through the API, the owner and the last active
+// admin can't be removed.
+func (sh *SCIMHandler) removeMember(ctx context.Context, organizationID primitive.ObjectID, member *models.OrganizationMember) error {
+	return sh.transactions.Run(ctx, func(ctx context.Context) error {
+		if err := sh.membershipRepository.RemoveMemberKeepingAdmin(ctx, organizationID, member.UserID); err != nil {
+			return err
+		}
+		return sh.outboxRepository.AddEvent(ctx, organizationID, models.EventMemberRemoved, gin.H{"member": member})
+	})
+}
+
+// scimOrganization loads the organization the SCIM token was issued for.
+func (sh *SCIMHandler) scimOrganization(c *gin.Context) (*models.Organization, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.GetString("scim_organization_id"))
+	if err != nil {
+		respondSCIMError(c, http.StatusUnauthorized, "", "Invalid SCIM token")
+		return nil, false
+	}
+
+	organization, err := sh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil {
+		respondSCIMError(c, http.StatusUnauthorized, "", "Invalid SCIM token")
+		return nil, false
+	}
+
+	return organization, true
+}
+
+// scimGroupOrganization loads the organization of the token, responding with an error when
+// the requested group is another organization.
+func (sh *SCIMHandler) scimGroupOrganization(c *gin.Context) (*models.Organization, bool) {
+	organization, ok := sh.scimOrganization(c)
+	if !ok {
+		return nil, false
+	}
+
+	if c.Param("id") != organization.ID.Hex() {
+		respondSCIMError(c, http.StatusNotFound, "", "Group not found")
+		return nil, false
+	}
+
+	return organization, true
+}
+
+// scimMember loads the user of the request and its membership of the organization.
+func (sh *SCIMHandler) scimMember(c *gin.Context, organization *models.Organization) (*models.User, *models.OrganizationMember, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		respondSCIMError(c, http.StatusNotFound, "", "User not found")
+		return nil, nil, false
+	}
+
+	user, err := sh.userRepository.GetUserByID(context.Background(), userID)
+	if err != nil {
+		respondSCIMError(c, http.StatusNotFound, "", "User not found")
+		return nil, nil, false
+	}
+
+	member, err := sh.membershipRepository.GetMember(context.Background(), organization.ID, user.ID)
+	if err != nil {
+		respondSCIMError(c, http.StatusNotFound, "", "User not found")
+		return nil, nil, false
+	}
+
+	return user, member, true
+}
+
+// memberUsers returns the SCIM users of the organization's members.
+func (sh *SCIMHandler) memberUsers(ctx context.Context, organization *models.Organization) ([]*scim.User, error) {
+	members, err := sh.membershipRepository.GetMembers(ctx, repository.MemberFilter{OrganizationID: organization.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, len(members))
+	for i, member := range members {
+		userIDs[i] = member.UserID
+	}
+
+	users, err := sh.userRepository.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	usersByID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	resources := []*scim.User{}
+	for _, member := range members {
+		if user, ok := usersByID[member.UserID]; ok {
+			resources = append(resources, scimUser(user, member))
+		}
+	}
+
+	return resources, nil
+}
+
+// scimGroup returns the SCIM group of an organization.
+func (sh *SCIMHandler) scimGroup(ctx context.Context, organization *models.Organization) (*scim.Group, error) {
+	users, err := sh.memberUsers(ctx, organization)
+	if err != nil {
+		log.Println("Error getting organization members:", err)
+		return nil, err
+	}
+
+	members := []scim.Member{}
+	for _, user := range users {
+		members = append(members, scim.Member{
+			Value:   user.ID,
+			Display: user.UserName,
+			Ref:     "/scim/v2/Users/" + user.ID,
+		})
+	}
+
+	return &scim.Group{
+		Schemas:     []string{scim.GroupSchema},
+		ID:          organization.ID.Hex(),
+		DisplayName: organization.Name,
+		Members:     members,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      &organization.CreatedAt,
+			LastModified: &organization.UpdatedAt,
+			Location:     "/scim/v2/Groups/" + organization.ID.Hex(),
+		},
+	}, nil
+}
+
+// scimProvisionable reports whether the SCIM token of an organization may add a user who isn't
+// a member yet: users whose account it created, and users with an email at one of its verified
+// domains. Other accounts belong to people the organization can't vouch for.
+func scimProvisionable(organization *models.Organization, user *models.User) bool {
+	return user.ProvisionedBy == organization.ID || hasVerifiedDomain(organization, dnsverify.EmailDomain(user.Email))
+}
+
+// scimUser returns the SCIM user of a member. The name is the one provisioned for the
+// membership, or else the name of the user.
+func scimUser(user *models.User, member *models.OrganizationMember) *scim.User {
+	active := !member.Suspended
+	name := member.Name
+	if name == "" {
+		name = user.Name
+	}
+
+	resource := &scim.User{
+		Schemas:     []string{scim.UserSchema},
+		ID:          user.ID.Hex(),
+		ExternalID:  member.ExternalID,
+		UserName:    user.Email,
+		DisplayName: name,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+			Location:     "/scim/v2/Users/" + user.ID.Hex(),
+		},
+	}
+	if name != "" {
+		resource.Name = &scim.Name{Formatted: name}
+	}
+
+	return resource
+}
+
+// listSCIMResources responds with the page of resources matching the filter of the request.
+func listSCIMResources(c *gin.Context, resources []scim.Resource) {
+	var filter scim.Filter
+	if expression := c.Query("filter"); expression != "" {
+		var err error
+		if filter, err = scim.ParseFilter(expression); err != nil {
+			respondSCIMError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+	}
+
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil {
+		respondSCIMError(c, http.StatusBadRequest, "invalidValue", "Invalid startIndex")
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(scimMaxResults)))
+	if err != nil || count < 0 {
+		respondSCIMError(c, http.StatusBadRequest, "invalidValue", "Invalid count")
+		return
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+
+	matching := []interface{}{}
+	for _, resource := range resources {
+		if filter == nil || filter.Match(resource) {
+			matching = append(matching, resource)
+		}
+	}
+
+	respondSCIM(c, http.StatusOK, scim.NewListResponse(matching, startIndex, count))
+}
+
+func respondSCIM(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scim.ContentType)
+	c.JSON(status, body)
+}
+
+func respondSCIMError(c *gin.Context, status int, scimType, detail string) {
+	respondSCIM(c, status, scim.NewError(status, scimType, detail))
+}
+
+// respondSCIMMemberError responds with the error of a change to a member, which may have been
+// refused because it would suspend or remove the owner or the last active admin.
+func respondSCIMMemberError(c *gin.Context, err error, detail string) {
+	switch {
+	case errors.Is(err, repository.ErrOwner):
+		respondSCIMError(c, http.StatusConflict, "", "The owner of the organization can't be suspended or removed, its ownership must be transferred first")
+	case errors.Is(err, repository.ErrLastAdmin):
+		respondSCIMError(c, http.StatusBadRequest, "invalidValue", "The organization must keep an active admin")
+	default:
+		respondSCIMError(c, http.StatusInternalServerError, "", detail)
+	}
+}
+
+func respondSCIMPatchError(c *gin.Context, err error) {
+	var patchErr *scim.PatchError
+	if errors.As(err, &patchErr) {
+		respondSCIMError(c, http.StatusBadRequest, patchErr.ScimType, patchErr.Detail)
+		return
+	}
+	respondSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+func TestSCIMProvisionable(t *testing.T) {
+	organization := &models.Organization{
+		ID: primitive.NewObjectID(),
+		Domains: []models.OrganizationDomain{
+			{Domain: "example.com", Verified: true},
+			{Domain: "example.org"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		user     models.User
+		expected bool
+	}{
+		{"email at a verified domain", models.User{Email: "Jane@Example.com"}, true},
+		{"email at an unverified domain", models.User{Email: "jane@example.org"}, false},
+		{"email at another domain", models.User{Email: "jane@gmail.com"}, false},
+		{"account created by the organization", models.User{Email: "jane@gmail.com", ProvisionedBy: organization.ID}, true},
+		{"account created by another organization", models.User{Email: "jane@gmail.com", ProvisionedBy: primitive.NewObjectID()}, false},
+	}
+	for _, test := range tests {
+		if provisionable := scimProvisionable(organization, &test.user); provisionable != test.expected {
+			t.Errorf("%s: provisionable = %v, want %v", test.name, provisionable, test.expected)
+		}
+	}
+}
+
+func TestSCIMKeepsOwnerAndAdmin(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{ID: primitive.NewObjectID(), Name: "Jane", Email: "jane@example.com"}
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", OwnerID: user.ID}
+	admin := &models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         user.ID,
+		Email:          user.Email,
+		AccessLevel:    models.AccessLevelAdmin,
+	}
+	count := func(n int) bson.D {
+		if n == 0 {
+			return mtest.CreateCursorResponse(0, "test.collection", mtest.FirstBatch)
+		}
+		return mtest.CreateCursorResponse(0, "test.collection", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+	}
+
+	scimRequest := func(mt *mtest.T, method, body string) *httptest.ResponseRecorder {
+		sh := &SCIMHandler{
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			membershipRepository:   repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+			userRepository:         repository.NewUserRepository(mt.DB, cache.NewLRU(16)),
+			outboxRepository:       repository.NewOutboxRepository(mt.DB),
+			transactions:           repository.NewTransactions(mt.DB),
+		}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("scim_organization_id", organization.ID.Hex())
+		})
+		router.PUT("/scim/v2/Users/:id", sh.ReplaceUser)
+		router.DELETE("/scim/v2/Users/:id", sh.DeleteUser)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, "/scim/v2/Users/"+user.ID.Hex(), strings.NewReader(body)))
+		return recorder
+	}
+	loadMember := func(mt *mtest.T, member *models.OrganizationMember) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)),
+		)
+	}
+
+	mt.Run("owner can't be removed", func(mt *mtest.T) {
+		loadMember(mt, admin)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			count(1),
+			mtest.CreateSuccessResponse(),
+		)
+
+		if recorder := scimRequest(mt, http.MethodDelete, ""); recorder.Code != http.StatusConflict {
+			mt.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusConflict)
+		}
+		for _, name := range commandNames(mt) {
+			if name == "delete" {
+				mt.Fatalf("commands %v, want the owner kept", commandNames(mt))
+			}
+		}
+	})
+
+	mt.Run("owner can't be suspended", func(mt *mtest.T) {
+		loadMember(mt, admin)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			count(1),
+			mtest.CreateSuccessResponse(),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, `{"userName": "jane@example.com", "active": false}`)
+		if recorder.Code != http.StatusConflict {
+			mt.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusConflict)
+		}
+	})
+
+	mt.Run("last admin can't be suspended", func(mt *mtest.T) {
+		loadMember(mt, admin)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			count(0),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, admin)),
+			count(0),
+			mtest.CreateSuccessResponse(),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, `{"userName": "jane@example.com", "active": false}`)
+		if recorder.Code != http.StatusBadRequest {
+			mt.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusBadRequest)
+		}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" && started.Command.Lookup("update").StringValue() == "memberships" {
+				mt.Fatalf("commands %v, want the admin left active", commandNames(mt))
+			}
+		}
+	})
+
+	mt.Run("members are removed", func(mt *mtest.T) {
+		member := *admin
+		member.AccessLevel = models.AccessLevelMember
+		loadMember(mt, &member)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			count(0),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, &member)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		if recorder := scimRequest(mt, http.MethodDelete, ""); recorder.Code != http.StatusNoContent {
+			mt.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusNoContent)
+		}
+	})
+}
+
+func TestSCIMRecordsChanges(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{ID: primitive.NewObjectID(), Name: "Jane", Email: "jane@example.com"}
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", Version: 7}
+	member := &models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         user.ID,
+		Name:           "Jane",
+		Email:          user.Email,
+		AccessLevel:    models.AccessLevelMember,
+	}
+
+	scimRequest := func(mt *mtest.T, method, target, body string) *httptest.ResponseRecorder {
+		sh := &SCIMHandler{
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			membershipRepository:   repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+			userRepository:         repository.NewUserRepository(mt.DB, cache.NewLRU(16)),
+			auditEventRepository:   repository.NewAuditEventRepository(mt.DB),
+			outboxRepository:       repository.NewOutboxRepository(mt.DB),
+			transactions:           repository.NewTransactions(mt.DB),
+		}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("scim_organization_id", organization.ID.Hex())
+		})
+		router.PUT("/scim/v2/Users/:id", sh.ReplaceUser)
+		router.PUT("/scim/v2/Groups/:id", sh.ReplaceGroup)
+
+		return serveJSON(router, method, target, body, nil)
+	}
+	// insertedEvents returns the types of the outbox events and the actions of the audit events
+	// inserted, in order.
+	insertedEvents := func(mt *mtest.T) []string {
+		inserted := []string{}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName != "insert" {
+				continue
+			}
+			document := started.Command.Lookup("documents", "0")
+			if eventType, ok := document.Document().Lookup("type").StringValueOK(); ok {
+				inserted = append(inserted, eventType)
+			}
+			if action, ok := document.Document().Lookup("action").StringValueOK(); ok {
+				inserted = append(inserted, action)
+			}
+		}
+		return inserted
+	}
+
+	mt.Run("user update", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)),
+			updateSucceeds,
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, "/scim/v2/Users/"+user.ID.Hex(), `{"userName": "jane@example.com", "displayName": "Jane Doe"}`)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusOK)
+		}
+		expected := []string{models.EventMemberUpdated, models.AuditActionMemberUpdated}
+		if names, inserted := commandNames(mt), insertedEvents(mt); strings.Join(inserted, ",") != strings.Join(expected, ",") || names[len(names)-2] != "commitTransaction" {
+			mt.Errorf("commands %v inserted %v, want %v with the event committed with the change", names, inserted, expected)
+		}
+	})
+
+	mt.Run("unchanged user", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, "/scim/v2/Users/"+user.ID.Hex(), `{"userName": "jane@example.com", "displayName": "Jane"}`)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusOK)
+		}
+		if names := commandNames(mt); len(names) != 3 {
+			mt.Errorf("commands %v, want nothing written", names)
+		}
+	})
+
+	mt.Run("group rename", func(mt *mtest.T) {
+		renamed := *organization
+		renamed.Name = "Renamed"
+		renamed.Version = 8
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch),
+			updateSucceeds,
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, &renamed)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, &renamed)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, "/scim/v2/Groups/"+organization.ID.Hex(), `{"displayName": "Renamed", "members": []}`)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusOK)
+		}
+		expected := []string{models.EventOrganizationUpdated, models.AuditActionOrganizationUpdated}
+		if inserted := insertedEvents(mt); strings.Join(inserted, ",") != strings.Join(expected, ",") {
+			mt.Errorf("inserted %v, want %v", inserted, expected)
+		}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				if version := started.Command.Lookup("updates", "0", "q", "version"); version.Int64() != 7 {
+					mt.Errorf("rename filters on version %v, want the version read", version)
+				}
+			}
+		}
+	})
+
+	mt.Run("group renamed concurrently", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		recorder := scimRequest(mt, http.MethodPut, "/scim/v2/Groups/"+organization.ID.Hex(), `{"displayName": "Renamed", "members": []}`)
+		if recorder.Code != http.StatusPreconditionFailed {
+			mt.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, http.StatusPreconditionFailed)
+		}
+	})
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/sso"
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/oauth2"
+)
+
+// samlRequestTTL is how long a user has to complete signin at the identity provider.
+const samlRequestTTL = 10 * time.Minute
+
+type SSOHandler struct {
+	organizationRepository *repository.OrganizationRepository
+	membershipRepository   *repository.MembershipRepository
+	userRepository         *repository.UserRepository
+	auditEventRepository   *repository.AuditEventRepository
+	outboxRepository       *repository.OutboxRepository
+	transactions           *repository.Transactions
+	serviceProviders       *sso.ServiceProviders
+	requestStates          cache.Cache
+}
+
+func NewSSOHandler(organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, userRepository *repository.UserRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions, serviceProviders *sso.ServiceProviders, requestStates cache.Cache) *SSOHandler {
+	return &SSOHandler{
+		organizationRepository: organizationRepository,
+		membershipRepository:   membershipRepository,
+		userRepository:         userRepository,
+		auditEventRepository:   auditEventRepository,
+		outboxRepository:       outboxRepository,
+		transactions:           transactions,
+		serviceProviders:       serviceProviders,
+		requestStates:          requestStates,
+	}
+}
+
+type samlConfigRequest struct {
+	IDPMetadataXML     string `json:"idp_metadata_xml"`
+	IDPMetadataURL     string `json:"idp_metadata_url"`
+	DefaultAccessLevel string `json:"default_access_level"`
+}
+
+// ConfigureSAML sets up the identity provider of an organization from its metadata, given
+// either inline or as a URL.
+func (sh *SSOHandler) ConfigureSAML(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req samlConfigRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Members provisioned on first signin get this access level
+	if req.DefaultAccessLevel == "" {
+		req.DefaultAccessLevel = models.AccessLevelMember
+	}
+	if req.DefaultAccessLevel != models.AccessLevelMember && req.DefaultAccessLevel != models.AccessLevelAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid default access level"})
+		return
+	}
+
+	// Load the identity provider metadata
+	metadataXML := []byte(req.IDPMetadataXML)
+	if len(metadataXML) == 0 {
+		if req.IDPMetadataURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "idp_metadata_xml or idp_metadata_url is required"})
+			return
+		}
+		metadataXML, err = sso.FetchIDPMetadata(context.Background(), req.IDPMetadataURL)
+		if errors.Is(err, sso.ErrInvalidMetadataURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching identity provider metadata:", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to fetch identity provider metadata"})
+			return
+		}
+	}
+
+	idpMetadata, err := sso.ParseIDPMetadata(metadataXML)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config := models.SAMLConfig{
+		IDPEntityID:        idpMetadata.EntityID,
+		IDPMetadataXML:     string(metadataXML),
+		DefaultAccessLevel: req.DefaultAccessLevel,
+		UpdatedAt:          time.Now(),
+	}
+	if err := sh.organizationRepository.SetSAMLConfig(context.Background(), organizationID, &config); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save SAML configuration"})
+		return
+	}
+
+	recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionSAMLConfigured,
+		TargetType:     models.AuditTargetSAMLConfig,
+		TargetID:       organizationID.Hex(),
+		Changes:        auditDiff(nil, &config),
+	})
+
+	c.JSON(http.StatusOK, sh.samlConfigResponse(organizationID, &config))
+}
+
+// GetSAMLConfig returns the SAML configuration of an organization along with the service
+// provider details to register with the identity provider.
+func (sh *SSOHandler) GetSAMLConfig(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organization, err := sh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sh.samlConfigResponse(organizationID, organization.SAML))
+}
+
+// DeleteSAMLConfig turns SAML signin off for an organization.
+func (sh *SSOHandler) DeleteSAMLConfig(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	if err := sh.organizationRepository.SetSAMLConfig(context.Background(), organizationID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SAML configuration"})
+		return
+	}
+
+	recordAuditEvent(c, sh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionSAMLDeleted,
+		TargetType:     models.AuditTargetSAMLConfig,
+		TargetID:       organizationID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "SAML configuration deleted successfully"})
+}
+
+// Metadata serves the service provider metadata of an organization.
+func (sh *SSOHandler) Metadata(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	metadata, err := xml.MarshalIndent(sh.serviceProviders.For(organizationID, nil).Metadata(), "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render metadata"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// Login redirects the user to the identity provider of an organization.
+func (sh *SSOHandler) Login(c *gin.Context) {
+	organization, serviceProvider, ok := sh.loadServiceProvider(c)
+	if !ok {
+		return
+	}
+
+	// Create the authentication request
+	authnRequest, err := serviceProvider.MakeAuthenticationRequest(
+		serviceProvider.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create SAML request"})
+		return
+	}
+
+	// The identity provider returns the relay state to the ACS, which uses it to find the request ID
+	relayState := oauth2.GenerateVerifier()
+	if err := sh.requestStates.Set(context.Background(), samlRequestKey(organization.ID, relayState), authnRequest.ID, samlRequestTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start signin"})
+		return
+	}
+
+	redirectURL, err := authnRequest.Redirect(relayState, serviceProvider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create SAML request"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL.String())
+}
+
+// AssertionConsumerService signs in a user from a SAML response, provisioning the user and
+// their membership of the organization on first signin.
+func (sh *SSOHandler) AssertionConsumerService(c *gin.Context) {
+	organization, serviceProvider, ok := sh.loadServiceProvider(c)
+	if !ok {
+		return
+	}
+
+	// Match the response with the request made by Login, unless it was initiated by the identity provider
+	var possibleRequestIDs []string
+	if relayState := c.PostForm("RelayState"); relayState != "" {
+		var requestID string
+		found, err := sh.requestStates.Get(context.Background(), samlRequestKey(organization.ID, relayState), &requestID)
+		if err != nil {
+			log.Println("Error loading SAML request:", err)
+		}
+		if found {
+			possibleRequestIDs = append(possibleRequestIDs, requestID)
+			if err := sh.requestStates.Delete(context.Background(), samlRequestKey(organization.ID, relayState)); err != nil {
+				log.Println("Error deleting SAML request:", err)
+			}
+		}
+	}
+
+	assertion, err := serviceProvider.ParseResponse(c.Request, possibleRequestIDs)
+	if err != nil {
+		var invalidResponse *saml.InvalidResponseError
+		if errors.As(err, &invalidResponse) {
+			log.Println("Invalid SAML response:", invalidResponse.PrivateErr)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid SAML response"})
+		return
+	}
+
+	// Only trust the identity provider for the domains the organization owns
+	email := sso.AssertionEmail(assertion)
+	if email == "" || !hasVerifiedDomain(organization, dnsverify.EmailDomain(email)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The email address is not in a verified domain of the organization"})
+		return
+	}
+
+	user, err := sh.provisionUser(context.Background(), organization, assertion, email)
+	if errors.Is(err, errMemberSuspended) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your membership of the organization is suspended"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	finishSignin(c, sh.userRepository, sh.auditEventRepository, user)
+}
+
+// errMemberSuspended is returned when the identity provider suspended the member signing in.
+var errMemberSuspended = errors.New("organization member is suspended")
+
+// provisionUser finds or creates the user signing in, and makes them a member of the organization.
+func (sh *SSOHandler) provisionUser(ctx context.Context, organization *models.Organization, assertion *saml.Assertion, email string) (*models.User, error) {
+	identity := models.UserIdentity{
+		Provider: "saml:" + organization.ID.Hex(),
+		Subject:  sso.AssertionSubject(assertion),
+		Email:    email,
+		LinkedAt: time.Now(),
+	}
+
+	user, err := sh.userRepository.GetUserByEmail(ctx, email)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		// Create a user without a password, who signs in through the identity provider
+		user = &models.User{
+			ID:         primitive.NewObjectID(),
+			Name:       sso.AssertionName(assertion),
+			Email:      email,
+			Identities: []models.UserIdentity{identity},
+		}
+		if err := sh.userRepository.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !hasIdentity(user, identity.Provider):
+		if err := sh.userRepository.AddIdentity(ctx, user.ID, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the user to the organization on first signin
+	member, err := sh.membershipRepository.GetMember(ctx, organization.ID, user.ID)
+	if err == nil && member.Suspended {
+		return nil, errMemberSuspended
+	}
+	if errors.Is(err, repository.ErrNotMember) {
+		newMember := models.OrganizationMember{
+			UserID:      user.ID,
+			Name:        user.Name,
+			Email:       email,
+			AccessLevel: organization.SAML.DefaultAccessLevel,
+		}
+		err = sh.transactions.Run(ctx, func(ctx context.Context) error {
+			if err := sh.membershipRepository.AddMember(ctx, organization.ID, newMember); err != nil {
+				return err
+			}
+			return sh.outboxRepository.AddEvent(ctx, organization.ID, models.EventMemberAdded, gin.H{"member": newMember})
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// loadServiceProvider loads the organization of the request and its service provider,
+// responding with an error when SAML isn't configured for it.
+func (sh *SSOHandler) loadServiceProvider(c *gin.Context) (*models.Organization, *saml.ServiceProvider, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, nil, false
+	}
+
+	organization, err := sh.organizationRepository.GetOrganizationByID(context.Background(), organizationID)
+	if err != nil || organization.SAML == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML is not configured for this organization"})
+		return nil, nil, false
+	}
+
+	idpMetadata, err := sso.ParseIDPMetadata([]byte(organization.SAML.IDPMetadataXML))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid identity provider metadata"})
+		return nil, nil, false
+	}
+
+	return organization, sh.serviceProviders.For(organizationID, idpMetadata), true
+}
+
+func (sh *SSOHandler) samlConfigResponse(organizationID primitive.ObjectID, config *models.SAMLConfig) gin.H {
+	serviceProvider := sh.serviceProviders.For(organizationID, nil)
+	return gin.H{
+		"organization_id": organizationID.Hex(),
+		"saml":            config,
+		"service_provider": gin.H{
+			"entity_id":    serviceProvider.EntityID,
+			"metadata_url": serviceProvider.MetadataURL.String(),
+			"acs_url":      serviceProvider.AcsURL.String(),
+		},
+	}
+}
+
+// ssoOrganizationFor returns the organization whose identity provider users with the given
+// email address must sign in with, or nil when they may use any signin method.
+func ssoOrganizationFor(ctx context.Context, organizationRepository *repository.OrganizationRepository, email string) *models.Organization {
+	domain := dnsverify.EmailDomain(email)
+	if domain == "" {
+		return nil
+	}
+
+	organization, err := organizationRepository.GetOrganizationByVerifiedDomain(ctx, domain)
+	if err != nil || organization.SAML == nil {
+		return nil
+	}
+
+	return organization
+}
+
+// respondSSORequired rejects a signin that has to go through an organization's identity provider.
+func respondSSORequired(c *gin.Context, organization *models.Organization) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":     "Your organization requires signing in with single sign-on",
+		"code":      "sso_required",
+		"login_url": "/sso/saml/" + organization.ID.Hex() + "/login",
+	})
+}
+
+func hasVerifiedDomain(organization *models.Organization, domain string) bool {
+	for _, organizationDomain := range organization.Domains {
+		if organizationDomain.Domain == domain && organizationDomain.Verified {
+			return true
+		}
+	}
+	return false
+}
+
+func hasIdentity(user *models.User, provider string) bool {
+	for _, identity := range user.Identities {
+		if identity.Provider == provider {
+			return true
+		}
+	}
+	return false
+}
+
+func samlRequestKey(organizationID primitive.ObjectID, relayState string) string {
+	return "saml-request:" + organizationID.Hex() + ":" + relayState
+}
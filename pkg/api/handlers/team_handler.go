@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// TeamHandler manages the teams of organizations. Admins manage every team, maintainers the
+// members of their teams and of the teams nested in them.
+type TeamHandler struct {
+	teamRepository       *repository.TeamRepository
+	membershipRepository *repository.MembershipRepository
+	auditEventRepository *repository.AuditEventRepository
+	outboxRepository     *repository.OutboxRepository
+	transactions         *repository.Transactions
+}
+
+func NewTeamHandler(teamRepository *repository.TeamRepository, membershipRepository *repository.MembershipRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions) *TeamHandler {
+	return &TeamHandler{
+		teamRepository:       teamRepository,
+		membershipRepository: membershipRepository,
+		auditEventRepository: auditEventRepository,
+		outboxRepository:     outboxRepository,
+		transactions:         transactions,
+	}
+}
+
+// GetTeams lists the teams of the organization ordered by name.
+func (th *TeamHandler) GetTeams(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	teams, err := th.teamRepository.GetTeams(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// CreateTeam creates a team, optionally nested in a parent team and granting an access level
+// in the organization to its members.
+func (th *TeamHandler) CreateTeam(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ParentID    string `json:"parent_id"`
+		AccessLevel string `json:"access_level"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	createdBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	team := &models.Team{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		AccessLevel:    req.AccessLevel,
+		CreatedBy:      createdBy,
+	}
+	if team.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Team name is required"})
+		return
+	}
+	if !validTeamAccessLevel(team.AccessLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+		return
+	}
+	if req.ParentID != "" {
+		if team.ParentID, err = primitive.ObjectIDFromHex(req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent team ID"})
+			return
+		}
+		if _, err := th.teamRepository.GetTeam(context.Background(), organizationID, team.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent team not found"})
+			return
+		}
+	}
+
+	err = th.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := th.teamRepository.CreateTeam(ctx, team); err != nil {
+			return err
+		}
+		return th.outboxRepository.AddEvent(ctx, organizationID, models.EventTeamCreated, gin.H{"team": team})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTeamExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A team with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	th.recordTeamEvent(c, team, models.AuditActionTeamCreated, auditDiff(nil, team))
+	c.JSON(http.StatusCreated, team)
+}
+
+// GetTeam returns a team with its direct members.
+func (th *TeamHandler) GetTeam(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+
+	teamMembers, err := th.teamRepository.GetTeamMembers(context.Background(), []primitive.ObjectID{team.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+	members, err := th.teamMemberResponses(team, teamMembers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team, "members": members})
+}
+
+// UpdateTeam changes the name, description, parent or access level of a team. Maintainers may
+// change the name and description, only admins the parent and access level. A team can't be
+// nested in itself or in one of its child teams.
+func (th *TeamHandler) UpdateTeam(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		ParentID    *string `json:"parent_id"`
+		AccessLevel *string `json:"access_level"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	teams, err := th.teamRepository.GetTeams(context.Background(), team.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+	if (req.ParentID != nil || req.AccessLevel != nil) && !isOrganizationAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change the parent or access level of a team"})
+		return
+	}
+	if !isOrganizationAdmin(c) {
+		maintainer, err := th.maintains(c, teams, team)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+			return
+		}
+		if !maintainer {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and team maintainers can update the team"})
+			return
+		}
+	}
+
+	before := *team
+	if req.Name != nil {
+		if team.Name = strings.TrimSpace(*req.Name); team.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Team name is required"})
+			return
+		}
+	}
+	if req.Description != nil {
+		team.Description = *req.Description
+	}
+	if req.AccessLevel != nil {
+		if team.AccessLevel = *req.AccessLevel; !validTeamAccessLevel(team.AccessLevel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access level"})
+			return
+		}
+	}
+	if req.ParentID != nil {
+		team.ParentID = primitive.NilObjectID
+		if *req.ParentID != "" {
+			if team.ParentID, err = primitive.ObjectIDFromHex(*req.ParentID); err != nil || !containsTeam(teams, team.ParentID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parent team not found"})
+				return
+			}
+			for _, descendantID := range models.TeamDescendants(teams, team.ID) {
+				if descendantID == team.ParentID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "A team can't be nested in itself or in one of its child teams"})
+					return
+				}
+			}
+		}
+	}
+
+	changes := auditDiff(&before, team)
+	err = th.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := th.teamRepository.UpdateTeam(ctx, team); err != nil {
+			return err
+		}
+		return th.outboxRepository.AddEvent(ctx, team.OrganizationID, models.EventTeamUpdated, gin.H{"team": team, "changes": changes})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTeamExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A team with this name already exists"})
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+
+	th.recordTeamEvent(c, team, models.AuditActionTeamUpdated, changes)
+	c.JSON(http.StatusOK, team)
+}
+
+// DeleteTeam deletes a team without child teams, and its memberships.
+func (th *TeamHandler) DeleteTeam(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+
+	err := th.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := th.teamRepository.DeleteTeam(ctx, team.OrganizationID, team.ID); err != nil {
+			return err
+		}
+		return th.outboxRepository.AddEvent(ctx, team.OrganizationID, models.EventTeamDeleted, gin.H{"team": team})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTeamHasChildren) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Teams nested in the team must be moved or deleted first"})
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+
+	th.recordTeamEvent(c, team, models.AuditActionTeamDeleted, auditDiff(team, nil))
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// GetTeamMembers lists the direct members of a team, with their role in it.
+func (th *TeamHandler) GetTeamMembers(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+
+	teamMembers, err := th.teamRepository.GetTeamMembers(context.Background(), []primitive.ObjectID{team.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+	members, err := th.teamMemberResponses(team, teamMembers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// GetEffectiveTeamMembers lists the members of a team and of every team nested in it, each
+// once, with the teams they belong to.
+func (th *TeamHandler) GetEffectiveTeamMembers(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+
+	teams, err := th.teamRepository.GetTeams(context.Background(), team.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+	teamMembers, err := th.teamRepository.GetTeamMembers(context.Background(), models.TeamDescendants(teams, team.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+
+	// Group the teams of each user
+	teamNames := make(map[primitive.ObjectID]string, len(teams))
+	for _, t := range teams {
+		teamNames[t.ID] = t.Name
+	}
+	userTeams := map[primitive.ObjectID][]gin.H{}
+	userIDs := []primitive.ObjectID{}
+	for _, teamMember := range teamMembers {
+		if _, ok := userTeams[teamMember.UserID]; !ok {
+			userIDs = append(userIDs, teamMember.UserID)
+		}
+		userTeams[teamMember.UserID] = append(userTeams[teamMember.UserID], gin.H{
+			"team_id": teamMember.TeamID.Hex(),
+			"name":    teamNames[teamMember.TeamID],
+			"role":    teamMember.Role,
+		})
+	}
+
+	members, err := th.membershipRepository.GetMembersByUserIDs(context.Background(), team.OrganizationID, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+		return
+	}
+
+	response := make([]gin.H, len(members))
+	for i, member := range members {
+		response[i] = memberResponse(member)
+		response[i]["teams"] = userTeams[member.UserID]
+	}
+	c.JSON(http.StatusOK, gin.H{"members": response})
+}
+
+// SetTeamMember adds a member of the organization to a team, or changes their role in it.
+// Only admins can add members to a team granting admin access.
+func (th *TeamHandler) SetTeamMember(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.TeamRoleMember
+	}
+	if req.Role != models.TeamRoleMember && req.Role != models.TeamRoleMaintainer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be member or maintainer"})
+		return
+	}
+
+	teams, ok := th.requireMaintainer(c, team)
+	if !ok {
+		return
+	}
+	if !isOrganizationAdmin(c) && grantsAdmin(teams, team.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage the members of teams granting admin access"})
+		return
+	}
+
+	// Only members of the organization can join its teams
+	member, err := th.membershipRepository.GetMember(context.Background(), team.OrganizationID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotMember) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team member"})
+		return
+	}
+
+	before, err := th.teamRepository.GetTeamMember(context.Background(), team.ID, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotTeamMember) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team member"})
+		return
+	}
+
+	teamMember := &models.TeamMember{
+		TeamID:         team.ID,
+		OrganizationID: team.OrganizationID,
+		UserID:         userID,
+		Role:           req.Role,
+	}
+	action, eventType, status := models.AuditActionTeamMemberAdded, models.EventTeamMemberAdded, http.StatusCreated
+	if before != nil {
+		action, eventType, status = models.AuditActionTeamMemberUpdated, models.EventTeamMemberUpdated, http.StatusOK
+	}
+	err = th.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if before != nil {
+			if err := th.teamRepository.SetTeamMemberRole(ctx, team.ID, userID, req.Role); err != nil {
+				return err
+			}
+		} else if err := th.teamRepository.AddTeamMember(ctx, teamMember); err != nil {
+			return err
+		}
+		return th.outboxRepository.AddEvent(ctx, team.OrganizationID, eventType, gin.H{"team": team, "member": teamMember})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTeamMemberExists) || errors.Is(err, repository.ErrNotTeamMember) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The team member was changed concurrently, try again"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team member"})
+		return
+	}
+
+	var beforeRole interface{}
+	if before != nil {
+		beforeRole = before.Role
+	}
+	recordAuditEvent(c, th.auditEventRepository, &models.AuditEvent{
+		OrganizationID: team.OrganizationID,
+		Action:         action,
+		TargetType:     models.AuditTargetTeamMember,
+		TargetID:       team.ID.Hex() + ":" + userID.Hex(),
+		Changes: map[string]models.AuditChange{
+			"team":  {After: team.Name},
+			"email": {After: member.Email},
+			"role":  {Before: beforeRole, After: req.Role},
+		},
+	})
+
+	response := memberResponse(member)
+	response["role"] = req.Role
+	c.JSON(status, response)
+}
+
+// RemoveTeamMember removes a member from a team. Members may remove themselves.
+func (th *TeamHandler) RemoveTeamMember(c *gin.Context) {
+	team, ok := th.findTeam(c)
+	if !ok {
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if userID.Hex() != c.GetString("user_id") {
+		teams, ok := th.requireMaintainer(c, team)
+		if !ok {
+			return
+		}
+		if !isOrganizationAdmin(c) && grantsAdmin(teams, team.ID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage the members of teams granting admin access"})
+			return
+		}
+	}
+
+	err = th.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := th.teamRepository.RemoveTeamMember(ctx, team.ID, userID); err != nil {
+			return err
+		}
+		return th.outboxRepository.AddEvent(ctx, team.OrganizationID, models.EventTeamMemberRemoved, gin.H{
+			"team":    team,
+			"user_id": userID.Hex(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotTeamMember) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove team member"})
+		return
+	}
+
+	recordAuditEvent(c, th.auditEventRepository, &models.AuditEvent{
+		OrganizationID: team.OrganizationID,
+		Action:         models.AuditActionTeamMemberRemoved,
+		TargetType:     models.AuditTargetTeamMember,
+		TargetID:       team.ID.Hex() + ":" + userID.Hex(),
+		Changes: map[string]models.AuditChange{
+			"team": {Before: team.Name},
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
+
+// findTeam retrieves the team of the request, responding with an error when the organization
+// has no such team.
+func (th *TeamHandler) findTeam(c *gin.Context) (*models.Team, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, false
+	}
+	teamID, err := primitive.ObjectIDFromHex(c.Param("teamId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return nil, false
+	}
+
+	team, err := th.teamRepository.GetTeam(context.Background(), organizationID, teamID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team"})
+		return nil, false
+	}
+	return team, true
+}
+
+// requireMaintainer returns every team of the organization when the user is an admin or a
+// maintainer of the team or of a team it is nested in, and responds with an error otherwise.
+func (th *TeamHandler) requireMaintainer(c *gin.Context, team *models.Team) ([]*models.Team, bool) {
+	teams, err := th.teamRepository.GetTeams(context.Background(), team.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+		return nil, false
+	}
+	if isOrganizationAdmin(c) {
+		return teams, true
+	}
+
+	maintainer, err := th.maintains(c, teams, team)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+		return nil, false
+	}
+	if !maintainer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and team maintainers can manage team members"})
+		return nil, false
+	}
+	return teams, true
+}
+
+// maintains reports whether the user is a maintainer of the team or of a team it is nested in.
+func (th *TeamHandler) maintains(c *gin.Context, teams []*models.Team, team *models.Team) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		return false, nil
+	}
+	memberships, err := th.teamRepository.GetUserTeamMemberships(context.Background(), team.OrganizationID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	maintained := map[primitive.ObjectID]bool{}
+	for _, membership := range memberships {
+		if membership.Role == models.TeamRoleMaintainer {
+			maintained[membership.TeamID] = true
+		}
+	}
+	for _, ancestorID := range models.TeamAncestors(teams, team.ID) {
+		if maintained[ancestorID] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// teamMemberResponses returns the direct members of a team with their role in it.
+func (th *TeamHandler) teamMemberResponses(team *models.Team, teamMembers []*models.TeamMember) ([]gin.H, error) {
+	roles := make(map[primitive.ObjectID]string, len(teamMembers))
+	userIDs := make([]primitive.ObjectID, len(teamMembers))
+	for i, teamMember := range teamMembers {
+		roles[teamMember.UserID] = teamMember.Role
+		userIDs[i] = teamMember.UserID
+	}
+
+	members, err := th.membershipRepository.GetMembersByUserIDs(context.Background(), team.OrganizationID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]gin.H, len(members))
+	for i, member := range members {
+		response[i] = memberResponse(member)
+		response[i]["role"] = roles[member.UserID]
+	}
+	return response, nil
+}
+
+func (th *TeamHandler) recordTeamEvent(c *gin.Context, team *models.Team, action string, changes map[string]models.AuditChange) {
+	recordAuditEvent(c, th.auditEventRepository, &models.AuditEvent{
+		OrganizationID: team.OrganizationID,
+		Action:         action,
+		TargetType:     models.AuditTargetTeam,
+		TargetID:       team.ID.Hex(),
+		Changes:        changes,
+	})
+}
+
+// isOrganizationAdmin reports whether the user acts as an admin of the organization, on their
+// own or through a team. It must run after OrganizationAccess.
+func isOrganizationAdmin(c *gin.Context) bool {
+	return c.GetString("access_level") == models.AccessLevelAdmin
+}
+
+// grantsAdmin reports whether the team or a team it is nested in grants admin access.
+func grantsAdmin(teams []*models.Team, teamID primitive.ObjectID) bool {
+	ancestors := map[primitive.ObjectID]bool{}
+	for _, ancestorID := range models.TeamAncestors(teams, teamID) {
+		ancestors[ancestorID] = true
+	}
+	for _, team := range teams {
+		if ancestors[team.ID] && team.AccessLevel == models.AccessLevelAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTeam(teams []*models.Team, id primitive.ObjectID) bool {
+	for _, team := range teams {
+		if team.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func validTeamAccessLevel(accessLevel string) bool {
+	return accessLevel == "" || accessLevel == models.AccessLevelMember || accessLevel == models.AccessLevelAdmin
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+func TestTeamGrantedAccess(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example"}
+	userID := primitive.NewObjectID()
+	member := &models.OrganizationMember{OrganizationID: organization.ID, UserID: userID, AccessLevel: models.AccessLevelMember}
+	parent := &models.Team{ID: primitive.NewObjectID(), OrganizationID: organization.ID, Name: "Admins", AccessLevel: models.AccessLevelAdmin}
+	team := &models.Team{ID: primitive.NewObjectID(), OrganizationID: organization.ID, ParentID: parent.ID, Name: "Platform"}
+	teamMember := &models.TeamMember{ID: primitive.NewObjectID(), TeamID: team.ID, OrganizationID: organization.ID, UserID: userID, Role: models.TeamRoleMember}
+
+	teamsGranted := func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.team_members", mtest.FirstBatch, mockDocument(mt, teamMember)),
+			mtest.CreateCursorResponse(0, "test.teams", mtest.FirstBatch, mockDocument(mt, parent), mockDocument(mt, team)),
+		)
+	}
+	accessLevel := func(mt *mtest.T, membershipRepository *repository.MembershipRepository, expected string) {
+		mt.Helper()
+
+		mt.ClearEvents()
+		accessLevel, err := membershipRepository.GetAccessLevel(context.Background(), organization, userID)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if accessLevel != expected {
+			mt.Errorf("access level = %q, want %q", accessLevel, expected)
+		}
+	}
+
+	mt.Run("cached until a team changes", func(mt *mtest.T) {
+		shared := cache.NewLRU(16)
+		membershipRepository := repository.NewMembershipRepository(mt.DB, shared)
+		teamRepository := repository.NewTeamRepository(mt.DB, shared)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)))
+		teamsGranted(mt)
+		accessLevel(mt, membershipRepository, models.AccessLevelAdmin)
+
+		accessLevel(mt, membershipRepository, models.AccessLevelAdmin)
+		if names := commandNames(mt); len(names) != 0 {
+			mt.Errorf("commands %v, want the team access level cached", names)
+		}
+
+		mt.AddMockResponses(
+			updateSucceeds,
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{userID}}),
+		)
+		downgraded := *parent
+		downgraded.AccessLevel = models.AccessLevelMember
+		if err := teamRepository.UpdateTeam(context.Background(), &downgraded); err != nil {
+			mt.Fatal(err)
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.team_members", mtest.FirstBatch, mockDocument(mt, teamMember)),
+			mtest.CreateCursorResponse(0, "test.teams", mtest.FirstBatch, mockDocument(mt, &downgraded), mockDocument(mt, team)),
+		)
+		accessLevel(mt, membershipRepository, models.AccessLevelMember)
+	})
+
+	mt.Run("cached until the member leaves the team", func(mt *mtest.T) {
+		shared := cache.NewLRU(16)
+		membershipRepository := repository.NewMembershipRepository(mt.DB, shared)
+		th := &TeamHandler{
+			teamRepository:       repository.NewTeamRepository(mt.DB, shared),
+			membershipRepository: membershipRepository,
+			auditEventRepository: repository.NewAuditEventRepository(mt.DB),
+			outboxRepository:     repository.NewOutboxRepository(mt.DB),
+			transactions:         repository.NewTransactions(mt.DB),
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)))
+		teamsGranted(mt)
+		accessLevel(mt, membershipRepository, models.AccessLevelAdmin)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.teams", mtest.FirstBatch, mockDocument(mt, team)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocument(mt, teamMember)}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", userID.Hex()) })
+		router.DELETE("/organizations/:id/teams/:teamId/members/:userId", th.RemoveTeamMember)
+		recorder := serve(router, http.MethodDelete, "/organizations/"+organization.ID.Hex()+"/teams/"+team.ID.Hex()+"/members/"+userID.Hex())
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("removal returned %d %s", recorder.Code, recorder.Body.String())
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.team_members", mtest.FirstBatch))
+		accessLevel(mt, membershipRepository, models.AccessLevelMember)
+	})
+}
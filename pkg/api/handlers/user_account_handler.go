@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// DeleteAccount deletes the signed in user, after removing them from their organizations.
+// Owners of organizations must transfer their ownership first, and the last active admin of an
+// organization must appoint another admin, so that no organization is left without either.
+func (uh *UserHandler) DeleteAccount(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	owned, err := uh.organizationRepository.GetOwnedOrganizations(context.Background(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	if len(owned) > 0 {
+		organizationIDs := make([]string, len(owned))
+		for i, organization := range owned {
+			organizationIDs[i] = organization.ID.Hex()
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "Transfer the ownership of your organizations before deleting your account",
+			"organization_ids": organizationIDs,
+		})
+		return
+	}
+
+	memberships, err := uh.membershipRepository.GetUserMemberships(context.Background(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	// Leave every organization along with the deletion
+	var left []*models.OrganizationMember
+	err = uh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		left = nil
+		for _, member := range memberships {
+			err := uh.membershipRepository.RemoveMemberKeepingAdmin(ctx, member.OrganizationID, user.ID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				// Deleted organizations only need the membership gone
+				if err := uh.membershipRepository.RemoveMember(ctx, member.OrganizationID, user.ID); err != nil && !errors.Is(err, repository.ErrNotMember) {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := uh.outboxRepository.AddEvent(ctx, member.OrganizationID, models.EventMemberRemoved, gin.H{"member": member}); err != nil {
+				return err
+			}
+			left = append(left, member)
+		}
+		return uh.userRepository.DeleteUser(ctx, user.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrOwner):
+			c.JSON(http.StatusConflict, gin.H{"error": "Transfer the ownership of your organizations before deleting your account"})
+		case errors.Is(err, repository.ErrLastAdmin):
+			c.JSON(http.StatusConflict, gin.H{"error": "Appoint another admin of the organizations you are the last active admin of before deleting your account"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		}
+		return
+	}
+
+	// Record the departures and the deletion in the audit log
+	for _, member := range left {
+		recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+			OrganizationID: member.OrganizationID,
+			Action:         models.AuditActionMemberLeft,
+			TargetType:     models.AuditTargetMember,
+			TargetID:       member.Email,
+			Changes:        auditDiff(member, nil),
+		})
+	}
+	recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+		Action:     models.AuditActionUserDeleted,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.Hex(),
+		Changes: map[string]models.AuditChange{
+			"email": {Before: user.Email},
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
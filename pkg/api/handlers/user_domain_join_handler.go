@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+)
+
+// GetDomainOrganizations lists the organizations the signed in user may join because they
+// verified the domain of the user's email, and the access level the user would join with.
+func (uh *UserHandler) GetDomainOrganizations(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	response := []gin.H{}
+	organization, domain, err := uh.domainOrganization(context.Background(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+	if organization != nil {
+		response = append(response, gin.H{
+			"organization_id": organization.ID.Hex(),
+			"name":            organization.Name,
+			"description":     organization.Description,
+			"domain":          domain.Domain,
+			"access_level":    domain.JoinAccessLevel,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": response})
+}
+
+// JoinDomainOrganization makes the signed in user a member of an organization which verified
+// the domain of the user's email and lets its users join.
+func (uh *UserHandler) JoinDomainOrganization(c *gin.Context) {
+	user, ok := uh.currentUser(c)
+	if !ok {
+		return
+	}
+
+	organization, domain, err := uh.domainOrganization(context.Background(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+	if organization == nil || organization.ID.Hex() != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	member, err := uh.joinByDomain(c, organization, domain, user)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of the organization"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Joined organization successfully",
+		"organization_id": organization.ID.Hex(),
+		"access_level":    member.AccessLevel,
+	})
+}
+
+// autoJoinDomainOrganization adds a user whose email was just verified to the organization
+// that verified its domain, when the organization adds such users automatically. Failures are
+// logged, the user can still join later.
+func (uh *UserHandler) autoJoinDomainOrganization(c *gin.Context, user *models.User) {
+	organization, domain, err := uh.domainOrganization(context.Background(), user)
+	if err != nil || organization == nil || domain.JoinPolicy != models.DomainJoinAuto {
+		return
+	}
+
+	if _, err := uh.joinByDomain(c, organization, domain, user); err != nil && !errors.Is(err, repository.ErrMemberExists) {
+		log.Println("Error adding user to organization "+organization.ID.Hex()+" by domain:", err)
+	}
+}
+
+// domainOrganization returns the organization that verified the domain of the user's email,
+// and the domain, when it lets users at the domain join and the user isn't a member yet. It
+// returns a nil organization otherwise, including when the email of the user isn't verified.
+func (uh *UserHandler) domainOrganization(ctx context.Context, user *models.User) (*models.Organization, models.OrganizationDomain, error) {
+	if !emailVerified(user) {
+		return nil, models.OrganizationDomain{}, nil
+	}
+
+	domainName := dnsverify.EmailDomain(user.Email)
+	if domainName == "" {
+		return nil, models.OrganizationDomain{}, nil
+	}
+	organization, err := uh.organizationRepository.GetOrganizationByVerifiedDomain(ctx, domainName)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.OrganizationDomain{}, nil
+	}
+	if err != nil {
+		return nil, models.OrganizationDomain{}, err
+	}
+	domain, ok := findDomain(organization, domainName)
+	if !ok || !domain.Joinable() {
+		return nil, models.OrganizationDomain{}, nil
+	}
+
+	_, err = uh.membershipRepository.GetMember(ctx, organization.ID, user.ID)
+	if err == nil {
+		return nil, models.OrganizationDomain{}, nil
+	}
+	if !errors.Is(err, repository.ErrNotMember) {
+		return nil, models.OrganizationDomain{}, err
+	}
+
+	return organization, domain, nil
+}
+
+// joinByDomain adds a user to an organization with the access level of the domain of the
+// user's email, and records it.
+func (uh *UserHandler) joinByDomain(c *gin.Context, organization *models.Organization, domain models.OrganizationDomain, user *models.User) (*models.OrganizationMember, error) {
+	member := models.OrganizationMember{
+		UserID:      user.ID,
+		Name:        user.Name,
+		Email:       strings.ToLower(user.Email),
+		AccessLevel: domain.JoinAccessLevel,
+	}
+	if member.AccessLevel == "" {
+		member.AccessLevel = models.AccessLevelMember
+	}
+
+	err := uh.transactions.Run(context.Background(), func(ctx context.Context) error {
+		if err := uh.membershipRepository.AddMember(ctx, organization.ID, member); err != nil {
+			return err
+		}
+		return uh.outboxRepository.AddEvent(ctx, organization.ID, models.EventMemberAdded, gin.H{"member": member})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organization.ID,
+		ActorID:        user.ID,
+		ActorEmail:     user.Email,
+		Action:         models.AuditActionMemberJoinedByDomain,
+		TargetType:     models.AuditTargetMember,
+		TargetID:       member.Email,
+		Changes:        auditDiff(nil, &member),
+	})
+
+	return &member, nil
+}
+
+// emailVerified reports whether the email of a user was verified, by an identity provider
+// vouching for it. Emails given at signup with a password aren't verified.
+func emailVerified(user *models.User) bool {
+	for _, identity := range user.Identities {
+		if strings.EqualFold(identity.Email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
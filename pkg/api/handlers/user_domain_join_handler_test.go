@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+func TestAutoJoinDomainOrganization(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	verifiedUser := &models.User{
+		ID:         primitive.NewObjectID(),
+		Name:       "Jane",
+		Email:      "Jane@example.com",
+		Identities: []models.UserIdentity{{Provider: "corp", Subject: "subject-1", Email: "jane@example.com"}},
+	}
+	organizationWithPolicy := func(policy string) *models.Organization {
+		return &models.Organization{
+			ID: primitive.NewObjectID(),
+			Domains: []models.OrganizationDomain{
+				{Domain: "example.com", Verified: true, JoinPolicy: policy},
+			},
+		}
+	}
+
+	autoJoin := func(mt *mtest.T, user *models.User) {
+		uh := &UserHandler{
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			membershipRepository:   repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+			auditEventRepository:   repository.NewAuditEventRepository(mt.DB),
+			outboxRepository:       repository.NewOutboxRepository(mt.DB),
+			transactions:           repository.NewTransactions(mt.DB),
+		}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/users/oauth/corp/callback", nil)
+
+		uh.autoJoinDomainOrganization(c, user)
+	}
+
+	mt.Run("auto policy adds the user", func(mt *mtest.T) {
+		organization := organizationWithPolicy(models.DomainJoinAuto)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		autoJoin(mt, verifiedUser)
+
+		started := mt.GetAllStartedEvents()
+		if len(started) < 4 || started[3].CommandName != "insert" || started[3].Command.Lookup("insert").StringValue() != "memberships" {
+			mt.Fatalf("commands %v, want the user added to the organization", commandNames(mt))
+		}
+		member := started[3].Command.Lookup("documents", "0").Document()
+		if member.Lookup("organization_id").ObjectID() != organization.ID || member.Lookup("user_id").ObjectID() != verifiedUser.ID {
+			mt.Errorf("added %v, want the user in the organization", member)
+		}
+		if member.Lookup("email").StringValue() != "jane@example.com" || member.Lookup("access_level").StringValue() != models.AccessLevelMember {
+			mt.Errorf("added %v, want the lowercased email with the member access level", member)
+		}
+	})
+
+	mt.Run("offer policy leaves the user to join", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organizationWithPolicy(models.DomainJoinOffer))),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch),
+		)
+
+		autoJoin(mt, verifiedUser)
+
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Errorf("commands %v, want the organization and membership lookups only", names)
+		}
+	})
+
+	mt.Run("auto policy skips members", func(mt *mtest.T) {
+		organization := organizationWithPolicy(models.DomainJoinAuto)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization)),
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, &models.OrganizationMember{
+				OrganizationID: organization.ID,
+				UserID:         verifiedUser.ID,
+				AccessLevel:    models.AccessLevelAdmin,
+			})),
+		)
+
+		autoJoin(mt, verifiedUser)
+
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Errorf("commands %v, want the organization and membership lookups only", names)
+		}
+	})
+
+	mt.Run("no policy leaves the user out", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organizationWithPolicy(models.DomainJoinNone))),
+		)
+
+		autoJoin(mt, verifiedUser)
+
+		if names := commandNames(mt); len(names) != 1 {
+			mt.Errorf("commands %v, want the organization lookup only", names)
+		}
+	})
+
+	mt.Run("unverified email is ignored", func(mt *mtest.T) {
+		user := *verifiedUser
+		user.Identities = nil
+
+		autoJoin(mt, &user)
+
+		if names := commandNames(mt); len(names) != 0 {
+			mt.Errorf("commands %v, want none", names)
+		}
+	})
+}
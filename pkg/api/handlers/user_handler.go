@@ -2,23 +2,53 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/oauth"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserHandler struct {
     userRepository *repository.UserRepository
+    organizationRepository *repository.OrganizationRepository
+    membershipRepository *repository.MembershipRepository
+    auditEventRepository *repository.AuditEventRepository
+    outboxRepository *repository.OutboxRepository
+    transactions *repository.Transactions
+    signinLimiter  *ratelimit.Limiter
+    signupLimiter  *ratelimit.Limiter
+    refreshLimiter *ratelimit.Limiter
+    signinLockout  *ratelimit.Lockout
+    oauthProviders map[string]oauth.Provider
+    loginStates    cache.Cache
 }
 
-func NewUserHandler(userRepository *repository.UserRepository) *UserHandler {
+func NewUserHandler(userRepository *repository.UserRepository, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions, signinLimiter *ratelimit.Limiter, signupLimiter *ratelimit.Limiter, refreshLimiter *ratelimit.Limiter, signinLockout *ratelimit.Lockout, oauthProviders map[string]oauth.Provider, loginStates cache.Cache) *UserHandler {
     return &UserHandler{
         userRepository: userRepository,
+        organizationRepository: organizationRepository,
+        membershipRepository: membershipRepository,
+        auditEventRepository: auditEventRepository,
+        outboxRepository: outboxRepository,
+        transactions: transactions,
+        signinLimiter:  signinLimiter,
+        signupLimiter:  signupLimiter,
+        refreshLimiter: refreshLimiter,
+        signinLockout:  signinLockout,
+        oauthProviders: oauthProviders,
+        loginStates:    loginStates,
     }
 }
 
@@ -29,6 +59,11 @@ func (uh *UserHandler) Signup(c *gin.Context) {
         return
     }
 
+    // Signups are limited per email, whether or not the account exists
+    if !allowAccount(c, uh.signupLimiter, strings.ToLower(strings.TrimSpace(user.Email))) {
+        return
+    }
+
     // Hash the user's password
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
     if err != nil {
@@ -37,12 +72,13 @@ func (uh *UserHandler) Signup(c *gin.Context) {
     }
     user.Password = string(hashedPassword)
 
-    // Set up user creation timestamp
+    // Set up user ID and creation timestamp
+    user.ID = primitive.NewObjectID()
     user.CreatedAt = time.Now()
     user.UpdatedAt = time.Now()
 
     // Generate access token
-    accessToken, err := utils.GenerateAccessToken(&user)
+    accessToken, err := utils.GenerateAccessToken(&user, scopes.All)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
         return
@@ -61,6 +97,19 @@ func (uh *UserHandler) Signup(c *gin.Context) {
         return
     }
 
+    // Record the new account in the audit log
+    recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+        ActorID:    user.ID,
+        ActorEmail: user.Email,
+        Action:     models.AuditActionUserCreated,
+        TargetType: models.AuditTargetUser,
+        TargetID:   user.ID.Hex(),
+        Changes: map[string]models.AuditChange{
+            "name":  {After: user.Name},
+            "email": {After: user.Email},
+        },
+    })
+
     // Respond with access token and refresh token
     c.JSON(http.StatusOK, gin.H{
         "message":        "User signed up successfully",
@@ -75,21 +124,68 @@ func (uh *UserHandler) Signin(c *gin.Context) {
         return
     }
 
+    // Attempts are limited per account, whether or not the account exists
+    accountKey := strings.ToLower(strings.TrimSpace(user.Email))
+    if !uh.allowSignin(c, accountKey) {
+        return
+    }
+
+    // Members of organizations enforcing single sign-on can't use their password. This is
+    // checked after the limits, so that probing which emails use single sign-on is limited too
+    if organization := ssoOrganizationFor(context.Background(), uh.organizationRepository, user.Email); organization != nil {
+        respondSSORequired(c, organization)
+        return
+    }
+
     // Find the user by email in the database
     foundUser, err := uh.userRepository.GetUserByEmail(context.Background(), user.Email)
     if err != nil {
+        uh.recordSigninFailure(accountKey)
         c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
         return
     }
 
     // Verify the password
     if !utils.VerifyPassword(user.Password, foundUser.Password) {
+        uh.recordSigninFailure(accountKey)
         c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
         return
     }
 
+    // Forget previous failures once the password is correct
+    if err := uh.signinLockout.Reset(context.Background(), accountKey); err != nil {
+        log.Println("Error resetting signin lockout:", err)
+    }
+
+    finishSignin(c, uh.userRepository, uh.auditEventRepository, foundUser)
+}
+
+// finishSignin issues tokens to a user who proved their identity, or asks for a second
+// factor first when the user enabled two-factor authentication.
+func finishSignin(c *gin.Context, userRepository *repository.UserRepository, auditEventRepository *repository.AuditEventRepository, foundUser *models.User) {
+    // Users with two-factor authentication must provide a code before receiving tokens
+    if foundUser.MFAEnabled {
+        mfaToken, err := utils.GenerateMFAToken(foundUser)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate MFA token"})
+            return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+            "message":      "Two-factor authentication required",
+            "mfa_required": true,
+            "mfa_token":    mfaToken,
+        })
+        return
+    }
+
+    completeSignin(c, userRepository, auditEventRepository, foundUser)
+}
+
+// completeSignin issues new tokens to a user who passed every signin step.
+func completeSignin(c *gin.Context, userRepository *repository.UserRepository, auditEventRepository *repository.AuditEventRepository, foundUser *models.User) {
     // Generate access token
-    accessToken, err := utils.GenerateAccessToken(foundUser)
+    accessToken, err := utils.GenerateAccessToken(foundUser, scopes.All)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
         return
@@ -102,12 +198,21 @@ func (uh *UserHandler) Signin(c *gin.Context) {
     foundUser.AccessToken = accessToken
     foundUser.RefreshToken = refreshToken
 
-    // Save the updated user in the database
-    if err := uh.userRepository.UpdateUser(context.Background(), foundUser.ID, foundUser); err != nil {
+    // Save only the tokens, so that second factors used meanwhile aren't written back
+    if err := userRepository.SaveTokens(context.Background(), foundUser.ID, accessToken, refreshToken); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
         return
     }
 
+    // Record the signin in the audit log
+    recordAuditEvent(c, auditEventRepository, &models.AuditEvent{
+        ActorID:    foundUser.ID,
+        ActorEmail: foundUser.Email,
+        Action:     models.AuditActionUserSignedIn,
+        TargetType: models.AuditTargetUser,
+        TargetID:   foundUser.ID.Hex(),
+    })
+
     // Respond with access token and refresh token
     c.JSON(http.StatusOK, gin.H{
         "message":        "User signed in successfully",
@@ -131,9 +236,14 @@ func (uh *UserHandler) RefreshToken(c *gin.Context) {
         return
     }
 
+    // Refreshes are limited per account
+    if !allowAccount(c, uh.refreshLimiter, "user:"+userID.Hex()) {
+        return
+    }
+
     // Generate new access token
     user := models.User{ID: userID}
-    accessToken, err := utils.GenerateAccessToken(&user)
+    accessToken, err := utils.GenerateAccessToken(&user, scopes.All)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
         return
@@ -148,6 +258,14 @@ func (uh *UserHandler) RefreshToken(c *gin.Context) {
         return
     }
 
+    // Record the refresh in the audit log
+    recordAuditEvent(c, uh.auditEventRepository, &models.AuditEvent{
+        ActorID:    userID,
+        Action:     models.AuditActionUserTokensRefreshed,
+        TargetType: models.AuditTargetUser,
+        TargetID:   userID.Hex(),
+    })
+
     // Respond with new access token and refresh token
     c.JSON(http.StatusOK, gin.H{
         "message":        "Tokens refreshed successfully",
@@ -155,3 +273,65 @@ func (uh *UserHandler) RefreshToken(c *gin.Context) {
         "refresh_token":  refreshToken,
     })
 }
+
+// allowAccount applies a per-account rate limit, reporting the quota through RateLimit-*
+// headers. It responds with 429 Too Many Requests and returns false once the quota of the
+// account is exhausted.
+func allowAccount(c *gin.Context, limiter *ratelimit.Limiter, accountKey string) bool {
+    result, err := limiter.Allow(context.Background(), accountKey)
+    if err != nil {
+        // Don't lock the account out because the counters are unavailable
+        log.Println("Error checking account rate limit:", err)
+        return true
+    }
+
+    for name, value := range result.Headers() {
+        c.Header(name, value)
+    }
+    if !result.Allowed {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+        return false
+    }
+    return true
+}
+
+// allowSignin rejects password attempts while the account is locked after repeated failures,
+// and applies the per-account rate limit.
+func (uh *UserHandler) allowSignin(c *gin.Context, accountKey string) bool {
+    lockedFor, err := uh.signinLockout.Locked(context.Background(), accountKey)
+    if err != nil {
+        log.Println("Error checking signin lockout:", err)
+    }
+    if lockedFor > 0 {
+        c.Header("Retry-After", strconv.FormatInt(int64(lockedFor.Seconds())+1, 10))
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed signin attempts, try again later"})
+        return false
+    }
+
+    return allowAccount(c, uh.signinLimiter, accountKey)
+}
+
+// recordSigninFailure counts a failed signin attempt towards the account's lockout.
+func (uh *UserHandler) recordSigninFailure(accountKey string) {
+    if _, err := uh.signinLockout.RecordFailure(context.Background(), accountKey); err != nil {
+        log.Println("Error recording failed signin:", err)
+    }
+}
+
+// currentUser loads the user authenticated by BearerTokenAuth, responding with an error
+// when it can't be found.
+func (uh *UserHandler) currentUser(c *gin.Context) (*models.User, bool) {
+    userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+        return nil, false
+    }
+
+    user, err := uh.userRepository.GetUserByID(context.Background(), userID)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+        return nil, false
+    }
+
+    return user, true
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// GetOrganizations lists the organizations the signed in user is an active member of, with
+// their access level, ordered by name. Tokens issued to an organization only see that one.
+func (uh *UserHandler) GetOrganizations(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	memberships, err := uh.membershipRepository.GetUserMemberships(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+
+	tokenOrganizationID := c.GetString("token_organization_id")
+	membershipsByOrganization := make(map[primitive.ObjectID]*models.OrganizationMember, len(memberships))
+	organizationIDs := []primitive.ObjectID{}
+	for _, membership := range memberships {
+		if membership.Suspended {
+			continue
+		}
+		if tokenOrganizationID != "" && membership.OrganizationID.Hex() != tokenOrganizationID {
+			continue
+		}
+		membershipsByOrganization[membership.OrganizationID] = membership
+		organizationIDs = append(organizationIDs, membership.OrganizationID)
+	}
+
+	// Deleted organizations are left out
+	organizations, err := uh.organizationRepository.GetOrganizationsByIDs(context.Background(), organizationIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+	sort.Slice(organizations, func(i, j int) bool {
+		return organizations[i].Name < organizations[j].Name
+	})
+
+	response := make([]gin.H, len(organizations))
+	for i, organization := range organizations {
+		membership := membershipsByOrganization[organization.ID]
+		response[i] = gin.H{
+			"organization_id": organization.ID.Hex(),
+			"name":            organization.Name,
+			"description":     organization.Description,
+			"access_level":    membership.AccessLevel,
+			"joined_at":       membership.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": response})
+}
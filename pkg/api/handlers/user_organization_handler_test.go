@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+func TestGetOrganizations(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	zeta := &models.Organization{ID: primitive.NewObjectID(), Name: "Zeta"}
+	alpha := &models.Organization{ID: primitive.NewObjectID(), Name: "Alpha"}
+	suspendedIn := primitive.NewObjectID()
+	memberships := []*models.OrganizationMember{
+		{OrganizationID: zeta.ID, UserID: userID, AccessLevel: models.AccessLevelAdmin},
+		{OrganizationID: alpha.ID, UserID: userID, AccessLevel: models.AccessLevelMember},
+		{OrganizationID: suspendedIn, UserID: userID, AccessLevel: models.AccessLevelAdmin, Suspended: true},
+	}
+
+	// getOrganizations lists the user's organizations, with the given organizations found among
+	// those the user is an active member of
+	getOrganizations := func(mt *mtest.T, tokenOrganizationID string, found ...*models.Organization) []gin.H {
+		documents := make([]bson.D, len(memberships))
+		for i, membership := range memberships {
+			documents[i] = mockDocument(mt, membership)
+		}
+		organizations := make([]bson.D, len(found))
+		for i, organization := range found {
+			organizations[i] = mockDocument(mt, organization)
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, documents...),
+			mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, organizations...),
+		)
+
+		uh := &UserHandler{
+			organizationRepository: repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			membershipRepository:   repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+		}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", userID.Hex())
+			if tokenOrganizationID != "" {
+				c.Set("token_organization_id", tokenOrganizationID)
+			}
+		})
+		router.GET("/users/organizations", uh.GetOrganizations)
+
+		recorder := serve(router, http.MethodGet, "/users/organizations")
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("request returned %d %s", recorder.Code, recorder.Body.String())
+		}
+		var response struct {
+			Organizations []gin.H `json:"organizations"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		return response.Organizations
+	}
+	// requestedIDs returns the organizations the handler looked up
+	requestedIDs := func(mt *mtest.T) []primitive.ObjectID {
+		values, err := mt.GetAllStartedEvents()[1].Command.Lookup("filter", "_id", "$in").Array().Values()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		ids := make([]primitive.ObjectID, len(values))
+		for i, value := range values {
+			ids[i] = value.ObjectID()
+		}
+		return ids
+	}
+
+	mt.Run("active memberships ordered by name", func(mt *mtest.T) {
+		organizations := getOrganizations(mt, "", zeta, alpha)
+
+		for _, id := range requestedIDs(mt) {
+			if id == suspendedIn {
+				mt.Error("organization the user is suspended in looked up")
+			}
+		}
+		if len(organizations) != 2 || organizations[0]["name"] != "Alpha" || organizations[1]["name"] != "Zeta" {
+			mt.Fatalf("organizations %v, want Alpha then Zeta", organizations)
+		}
+		if organizations[0]["access_level"] != models.AccessLevelMember || organizations[1]["access_level"] != models.AccessLevelAdmin {
+			mt.Errorf("organizations %v, want the access level of each membership", organizations)
+		}
+	})
+
+	mt.Run("token issued to an organization", func(mt *mtest.T) {
+		organizations := getOrganizations(mt, zeta.ID.Hex(), zeta)
+
+		if ids := requestedIDs(mt); len(ids) != 1 || ids[0] != zeta.ID {
+			mt.Errorf("looked up %v, want only the organization of the token", ids)
+		}
+		if len(organizations) != 1 || organizations[0]["organization_id"] != zeta.ID.Hex() {
+			mt.Errorf("organizations %v, want only the organization of the token", organizations)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/webhooks"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Number of deliveries returned per page, by default and at most
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+// WebhookHandler manages the webhooks of organizations and their delivery logs.
+type WebhookHandler struct {
+	webhookRepository    *repository.WebhookRepository
+	deliveryRepository   *repository.WebhookDeliveryRepository
+	auditEventRepository *repository.AuditEventRepository
+	dispatcher           *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(webhookRepository *repository.WebhookRepository, deliveryRepository *repository.WebhookDeliveryRepository, auditEventRepository *repository.AuditEventRepository, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepository:    webhookRepository,
+		deliveryRepository:   deliveryRepository,
+		auditEventRepository: auditEventRepository,
+		dispatcher:           dispatcher,
+	}
+}
+
+type createWebhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+}
+
+func (wh *WebhookHandler) GetWebhooks(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	organizationWebhooks, err := wh.webhookRepository.GetOrganizationWebhooks(context.Background(), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organizationWebhooks)
+}
+
+// CreateWebhook registers an endpoint notified of the events it subscribes to. The secret
+// payloads are signed with is only returned once.
+func (wh *WebhookHandler) CreateWebhook(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req createWebhookRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, ok := normalizeWebhookEvents(req.Events)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Events must be among " + strings.Join(models.WebhookEvents, ", ")})
+		return
+	}
+
+	secret, err := utils.GenerateOpaqueToken(utils.WebhookSecretPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+
+	createdBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	webhook := &models.Webhook{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		URL:            req.URL,
+		Description:    strings.TrimSpace(req.Description),
+		Events:         events,
+		Secret:         secret,
+		CreatedBy:      createdBy,
+	}
+	if err := wh.webhookRepository.CreateWebhook(context.Background(), webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save webhook"})
+		return
+	}
+
+	recordAuditEvent(c, wh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditActionWebhookCreated,
+		TargetType:     models.AuditTargetWebhook,
+		TargetID:       webhook.ID.Hex(),
+		Changes:        auditDiff(nil, webhook),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// DeleteWebhook deletes a webhook along with its delivery log.
+func (wh *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhook, ok := wh.organizationWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := wh.webhookRepository.DeleteOrganizationWebhook(context.Background(), webhook.ID, webhook.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if err := wh.deliveryRepository.DeleteWebhookDeliveries(context.Background(), webhook.ID); err != nil {
+		log.Println("Error deleting deliveries of webhook:", err)
+	}
+
+	recordAuditEvent(c, wh.auditEventRepository, &models.AuditEvent{
+		OrganizationID: webhook.OrganizationID,
+		Action:         models.AuditActionWebhookDeleted,
+		TargetType:     models.AuditTargetWebhook,
+		TargetID:       webhook.ID.Hex(),
+		Changes:        auditDiff(webhook, nil),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries lists the deliveries to a webhook with the log of their attempts, most recent
+// first. Further pages are requested with the next_cursor of the previous page.
+func (wh *WebhookHandler) GetDeliveries(c *gin.Context) {
+	webhook, ok := wh.organizationWebhook(c)
+	if !ok {
+		return
+	}
+
+	var before primitive.ObjectID
+	if cursor := c.Query("cursor"); cursor != "" {
+		var err error
+		if before, err = primitive.ObjectIDFromHex(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	limit := int64(defaultDeliveriesLimit)
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit < 1 || limit > maxDeliveriesLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxDeliveriesLimit)})
+			return
+		}
+	}
+
+	deliveries, err := wh.deliveryRepository.GetWebhookDeliveries(context.Background(), webhook.ID, before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+		return
+	}
+
+	response := gin.H{"deliveries": deliveries}
+	if int64(len(deliveries)) == limit {
+		response["next_cursor"] = deliveries[len(deliveries)-1].ID.Hex()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Redeliver queues a new delivery of the payload of a previous delivery, e.g. once the
+// endpoint is fixed after deliveries failed.
+func (wh *WebhookHandler) Redeliver(c *gin.Context) {
+	webhook, ok := wh.organizationWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	previous, err := wh.deliveryRepository.GetWebhookDelivery(context.Background(), deliveryID, webhook.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	delivery, err := wh.dispatcher.Redeliver(context.Background(), previous)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue redelivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// organizationWebhook loads the webhook of the request, responding with an error when it
+// doesn't belong to the organization.
+func (wh *WebhookHandler) organizationWebhook(c *gin.Context) (*models.Webhook, bool) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, false
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	webhook, err := wh.webhookRepository.GetOrganizationWebhook(context.Background(), webhookID, organizationID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook"})
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// normalizeWebhookEvents checks the events subscribed to and removes duplicates.
+func normalizeWebhookEvents(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return nil, false
+	}
+
+	known := map[string]bool{}
+	for _, event := range models.WebhookEvents {
+		known[event] = true
+	}
+
+	events := []string{}
+	seen := map[string]bool{}
+	for _, event := range requested {
+		if !known[event] {
+			return nil, false
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	return events, true
+}
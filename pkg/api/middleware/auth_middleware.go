@@ -2,17 +2,54 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func BearerTokenAuth(userRepository *repository.UserRepository, organizationRepository *repository.OrganizationRepository) gin.HandlerFunc {
+// publicPaths are the routes reachable without an access token.
+var publicPaths = map[string]bool{
+    "/users/signup":        true,
+    "/users/signin":        true,
+    "/users/signin/mfa":    true,
+    "/users/refresh-token": true,
+    "/users/oauth":         true,
+    "/oauth/token":         true,
+    "/oauth/introspect":    true,
+    "/oauth/revoke":        true,
+}
+
+// publicPrefixes are path prefixes of routes reachable without an access token.
+var publicPrefixes = []string{
+    "/users/oauth/",
+    "/sso/",
+    "/scim/",
+    "/.well-known/",
+}
+
+func isPublicPath(path string) bool {
+    if publicPaths[path] {
+        return true
+    }
+    for _, prefix := range publicPrefixes {
+        if strings.HasPrefix(path, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+func BearerTokenAuth(userRepository *repository.UserRepository, apiTokenRepository *repository.APITokenRepository) gin.HandlerFunc {
     return func(c *gin.Context) {
         // Skip authentication for signup, signin, and refresh-token routes
-        if c.Request.URL.Path == "/users/signup" || c.Request.URL.Path == "/users/signin" || c.Request.URL.Path == "/users/refresh-token" {
+        if isPublicPath(c.Request.URL.Path) {
             c.Next()
             return
         }
@@ -31,34 +68,251 @@ func BearerTokenAuth(userRepository *repository.UserRepository, organizationRepo
 
         token := tokenParts[1]
 
+        // Personal access tokens, API keys and tokens of OAuth clients are recognized by their prefix
+        if isOpaqueToken(token) {
+            apiToken, user, err := verifyAPIToken(token, userRepository, apiTokenRepository)
+            if err != nil {
+                c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+                return
+            }
+
+            c.Set("token_type", apiToken.Type)
+            c.Set("token_id", apiToken.ID.Hex())
+            c.Set("token_scopes", apiToken.Scopes)
+            if user != nil {
+                c.Set("user_id", user.ID.Hex())
+                c.Set("user_email", user.Email)
+                c.Set("mfa_enabled", user.MFAEnabled)
+            } else {
+                c.Set("token_organization_id", apiToken.OrganizationID.Hex())
+                c.Set("token_access_level", apiToken.AccessLevel)
+                if apiToken.Type == models.TokenTypeAPIKey && !apiToken.UserID.IsZero() {
+                    c.Set("token_created_by", apiToken.UserID.Hex())
+                }
+            }
+
+            c.Next()
+            return
+        }
+
         // Verify token validity
-        userID, err := verifyToken(token, userRepository)
+        user, tokenScopes, err := verifyToken(token, userRepository)
         if err != nil {
             c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
             return
         }
 
+        // Set user ID and email in context for further use
+        c.Set("token_type", models.TokenTypeSession)
+        c.Set("token_scopes", tokenScopes)
+        c.Set("user_id", user.ID.Hex())
+        c.Set("user_email", user.Email)
+        c.Set("mfa_enabled", user.MFAEnabled)
+
+        c.Next()
+    }
+}
+
+// SessionOnly rejects requests authenticated with a personal access token or an API key, for
+// routes that manage credentials. It must run after BearerTokenAuth.
+func SessionOnly() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.GetString("token_type") != models.TokenTypeSession {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint requires signing in"})
+            return
+        }
+
+        c.Next()
+    }
+}
+
+// OrganizationAccess only lets members of the organization identified by the :id route
+// parameter through, along with admins of its parents it grants access to, and stores their
+// access level in the context. It must run after BearerTokenAuth.
+func OrganizationAccess(organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository) gin.HandlerFunc {
+    return organizationAccess(organizationRepository.GetOrganizationByID, membershipRepository)
+}
+
+// DeletedOrganizationAccess is OrganizationAccess for organizations which were deleted and can
+// still be restored.
+func DeletedOrganizationAccess(organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository) gin.HandlerFunc {
+    return organizationAccess(organizationRepository.GetDeletedOrganizationByID, membershipRepository)
+}
+
+func organizationAccess(getOrganization func(ctx context.Context, id primitive.ObjectID) (*models.Organization, error), membershipRepository *repository.MembershipRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        organizationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+            return
+        }
+
+        // Tokens issued to an organization only act on it, with the access level they were given
+        tokenOrganizationID := c.GetString("token_organization_id")
+        if tokenOrganizationID != "" && tokenOrganizationID != organizationID.Hex() {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token does not have access to the organization"})
+            return
+        }
+
+        organization, err := getOrganization(context.Background(), organizationID)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User does not have access to the organization"})
+            return
+        }
+
+        if tokenOrganizationID != "" {
+            accessLevel, err := tokenAccessLevel(c, organization, membershipRepository)
+            if err != nil {
+                c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization access"})
+                return
+            }
+            if accessLevel == "" {
+                c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token does not have access to the organization"})
+                return
+            }
+
+            c.Set("access_level", accessLevel)
+            c.Next()
+            return
+        }
+
         // Check if the user has access to the organization
-        organizationID := c.Param("organization_id")
-        if !organizationRepository.IsUserMemberOfOrganization(context.Background(), userID, organizationID) {
+        userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+        if err != nil {
             c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User does not have access to the organization"})
             return
         }
 
-        // Set user ID in context for further use
-        c.Set("user_id", userID)
+        // Members, admins of parent organizations included, are given the highest access level
+        // of their own, their teams' and the one inherited from the parent
+        accessLevel, err := membershipRepository.GetAccessLevel(context.Background(), organization, userID)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization access"})
+            return
+        }
+        if accessLevel == "" {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User does not have access to the organization"})
+            return
+        }
+
+        // Organizations may require every member to use two-factor authentication
+        if organization.RequireMFA && !c.GetBool("mfa_enabled") {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                "error": "The organization requires two-factor authentication",
+                "code":  "mfa_required",
+            })
+            return
+        }
+
+        // Set access level in context for further use
+        c.Set("access_level", accessLevel)
 
         c.Next()
     }
 }
 
-func verifyToken(token string, userRepository *repository.UserRepository) (string, error) {
-    // Retrieve the user by token from the repository
-    user, err := userRepository.GetUserByToken(context.Background(), token)
+// tokenAccessLevel returns the access level of a token issued to the organization. API keys
+// are tied to the membership of the user who created them: they never get more access than
+// their creator currently has, and none once the creator has left the organization.
+func tokenAccessLevel(c *gin.Context, organization *models.Organization, membershipRepository *repository.MembershipRepository) (string, error) {
+    accessLevel := c.GetString("token_access_level")
+
+    createdBy, err := primitive.ObjectIDFromHex(c.GetString("token_created_by"))
+    if err != nil {
+        return accessLevel, nil
+    }
+    creatorAccessLevel, err := membershipRepository.GetAccessLevel(context.Background(), organization, createdBy)
     if err != nil {
         return "", err
     }
 
-    // Return the user ID if found
-    return user.ID.Hex(), nil
+    // Keys of creators demoted to members act as members, and those of creators who left the
+    // organization or were suspended have no access
+    if creatorAccessLevel != models.AccessLevelAdmin {
+        return creatorAccessLevel, nil
+    }
+
+    return accessLevel, nil
+}
+
+// RequireAccessLevel only lets members with one of the given access levels through. It must
+// run after OrganizationAccess.
+func RequireAccessLevel(accessLevels ...string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        accessLevel := c.GetString("access_level")
+        for _, allowed := range accessLevels {
+            if accessLevel == allowed {
+                c.Next()
+                return
+            }
+        }
+
+        c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient access level"})
+    }
+}
+
+func verifyToken(token string, userRepository *repository.UserRepository) (*models.User, []string, error) {
+    // Check the token signature and expiry before touching the database
+    userID, tokenScopes, err := utils.ParseAccessToken(token)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    objectID, err := primitive.ObjectIDFromHex(userID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Retrieve the user along with their latest access token, bypassing the cache
+    user, err := userRepository.GetSessionUser(context.Background(), objectID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Only the latest token issued to the user is valid
+    if user.AccessToken != token {
+        return nil, nil, errors.New("token has been replaced")
+    }
+
+    return user, tokenScopes, nil
+}
+
+func isOpaqueToken(token string) bool {
+    for _, prefix := range []string{utils.PersonalAccessTokenPrefix, utils.APIKeyPrefix, utils.OAuthAccessTokenPrefix} {
+        if strings.HasPrefix(token, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// verifyAPIToken looks up an opaque token by its hash, along with the user it acts as, if any.
+func verifyAPIToken(token string, userRepository *repository.UserRepository, apiTokenRepository *repository.APITokenRepository) (*models.APIToken, *models.User, error) {
+    apiToken, err := apiTokenRepository.GetTokenByHash(context.Background(), utils.HashOpaqueToken(token))
+    if err != nil {
+        return nil, nil, err
+    }
+    if apiToken.Expired() {
+        return nil, nil, errors.New("token has expired")
+    }
+
+    // Refresh tokens of OAuth clients can only be exchanged for access tokens
+    if apiToken.Type == models.TokenTypeOAuthRefreshToken {
+        return nil, nil, errors.New("not an access token")
+    }
+
+    // Personal access tokens and tokens a user approved for an OAuth client act as the user
+    var user *models.User
+    if apiToken.Type == models.TokenTypePersonalAccessToken || (apiToken.Type == models.TokenTypeOAuthAccessToken && !apiToken.UserID.IsZero()) {
+        if user, err = userRepository.GetUserProfile(context.Background(), apiToken.UserID); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    // A failure to record the last use shouldn't reject the request
+    if err := apiTokenRepository.TouchLastUsed(context.Background(), apiToken.ID); err != nil {
+        log.Println("Error recording API token use:", err)
+    }
+
+    return apiToken, user, nil
 }
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// mockDocument converts a model to the document the mock deployment replies with.
+func mockDocument(t testing.TB, value interface{}) bson.D {
+	t.Helper()
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestAPIKeyAccessLevel(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	organization := &models.Organization{ID: primitive.NewObjectID(), Name: "Example", RequireMFA: true}
+	creator := primitive.NewObjectID()
+
+	// accessLevel serves a request with an admin API key created by the given member, or by a
+	// member who left the organization when member is nil. The organization requires two-factor
+	// authentication, which keys are exempt from.
+	accessLevel := func(mt *mtest.T, member *models.OrganizationMember) (int, string) {
+		replies := []bson.D{mtest.CreateCursorResponse(0, "test.organizations", mtest.FirstBatch, mockDocument(mt, organization))}
+		if member != nil {
+			replies = append(replies, mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch, mockDocument(mt, member)))
+		} else {
+			replies = append(replies, mtest.CreateCursorResponse(0, "test.memberships", mtest.FirstBatch))
+		}
+		if member != nil && member.AccessLevel == models.AccessLevelMember {
+			replies = append(replies, mtest.CreateCursorResponse(0, "test.team_members", mtest.FirstBatch))
+		}
+		mt.AddMockResponses(replies...)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("token_type", models.TokenTypeAPIKey)
+			c.Set("token_organization_id", organization.ID.Hex())
+			c.Set("token_access_level", models.AccessLevelAdmin)
+			c.Set("token_created_by", creator.Hex())
+		})
+		granted := ""
+		router.GET("/organizations/:id", OrganizationAccess(
+			repository.NewOrganizationRepository(mt.DB, cache.NewLRU(16)),
+			repository.NewMembershipRepository(mt.DB, cache.NewLRU(16)),
+		), func(c *gin.Context) {
+			granted = c.GetString("access_level")
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/organizations/"+organization.ID.Hex(), nil))
+		return recorder.Code, granted
+	}
+
+	tests := []struct {
+		name        string
+		member      *models.OrganizationMember
+		code        int
+		accessLevel string
+	}{
+		{
+			"creator still an admin",
+			&models.OrganizationMember{OrganizationID: organization.ID, UserID: creator, AccessLevel: models.AccessLevelAdmin},
+			http.StatusOK, models.AccessLevelAdmin,
+		},
+		{
+			"creator demoted to member",
+			&models.OrganizationMember{OrganizationID: organization.ID, UserID: creator, AccessLevel: models.AccessLevelMember},
+			http.StatusOK, models.AccessLevelMember,
+		},
+		{
+			"creator suspended",
+			&models.OrganizationMember{OrganizationID: organization.ID, UserID: creator, AccessLevel: models.AccessLevelAdmin, Suspended: true},
+			http.StatusForbidden, "",
+		},
+		{"creator left", nil, http.StatusForbidden, ""},
+	}
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			code, accessLevel := accessLevel(mt, test.member)
+			if code != test.code || accessLevel != test.accessLevel {
+				mt.Errorf("got %d with access level %q, want %d with %q", code, accessLevel, test.code, test.accessLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc extracts the identity a rate limit is counted against from a request. An empty
+// key skips the limit for that request.
+type KeyFunc func(c *gin.Context) string
+
+// ClientIPKey limits requests per client IP address.
+func ClientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// UserKey limits requests per authenticated user. It must run after BearerTokenAuth.
+func UserKey(c *gin.Context) string {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		return ""
+	}
+	return "user:" + userID
+}
+
+// RateLimit rejects requests with 429 Too Many Requests once the limiter's quota for the
+// request's key is exhausted, and reports the quota through RateLimit-* headers.
+func RateLimit(limiter *ratelimit.Limiter, keyFunc KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(context.Background(), key)
+		if err != nil {
+			// Don't lock everybody out because the counters are unavailable
+			log.Println("Error checking rate limit:", err)
+			c.Next()
+			return
+		}
+
+		for name, value := range result.Headers() {
+			c.Header(name, value)
+		}
+
+		if !result.Allowed {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
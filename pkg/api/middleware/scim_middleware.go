@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scim"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuth authenticates SCIM requests with the bearer token issued to an organization's
+// identity provider, and sets the ID of that organization for the handlers.
+func SCIMAuth(organizationRepository *repository.OrganizationRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || tokenParts[1] == "" {
+			abortSCIMUnauthorized(c)
+			return
+		}
+
+		// Tokens are stored hashed, so look the organization up by the hash
+		organization, err := organizationRepository.GetOrganizationBySCIMTokenHash(context.Background(), utils.HashOpaqueToken(tokenParts[1]))
+		if err != nil {
+			abortSCIMUnauthorized(c)
+			return
+		}
+
+		c.Set("scim_organization_id", organization.ID.Hex())
+		c.Next()
+	}
+}
+
+func abortSCIMUnauthorized(c *gin.Context) {
+	c.Header("Content-Type", scim.ContentType)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, scim.NewError(http.StatusUnauthorized, "", "Invalid SCIM token"))
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScopes only lets requests through when their token was granted every given scope.
+// It must run after BearerTokenAuth.
+func RequireScopes(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		missing := scopes.Missing(c.GetStringSlice("token_scopes"), required)
+		if len(missing) > 0 {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(required, " ")))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":          "Insufficient scope",
+				"code":           "insufficient_scope",
+				"missing_scopes": missing,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+)
+
+func TestRequireScopes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{ID: primitive.NewObjectID(), Email: "jane@example.com"}
+	token := utils.PersonalAccessTokenPrefix + "secret"
+
+	// request serves a request to a route requiring orgs:write with a personal access token
+	// granted the given scopes
+	request := func(mt *mtest.T, granted []string) *httptest.ResponseRecorder {
+		apiToken := &models.APIToken{
+			ID:        primitive.NewObjectID(),
+			Type:      models.TokenTypePersonalAccessToken,
+			UserID:    user.ID,
+			TokenHash: utils.HashOpaqueToken(token),
+			Scopes:    granted,
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.api_tokens", mtest.FirstBatch, mockDocument(mt, apiToken)),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, mockDocument(mt, user)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		router := gin.New()
+		router.Use(BearerTokenAuth(
+			repository.NewUserRepository(mt.DB, cache.NewLRU(16)),
+			repository.NewAPITokenRepository(mt.DB, cache.NewLRU(16)),
+		))
+		router.POST("/organizations", RequireScopes(scopes.OrgsWrite), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+
+		request := httptest.NewRequest(http.MethodPost, "/organizations", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	mt.Run("granted scope", func(mt *mtest.T) {
+		recorder := request(mt, []string{scopes.OrgsRead, scopes.OrgsWrite})
+		if recorder.Code != http.StatusCreated {
+			mt.Errorf("request returned %d %s, want %d", recorder.Code, recorder.Body.String(), http.StatusCreated)
+		}
+	})
+
+	mt.Run("missing scope", func(mt *mtest.T) {
+		recorder := request(mt, []string{scopes.OrgsRead})
+		if recorder.Code != http.StatusForbidden {
+			mt.Fatalf("request returned %d, want %d", recorder.Code, http.StatusForbidden)
+		}
+		if challenge := recorder.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, `error="insufficient_scope"`) || !strings.Contains(challenge, scopes.OrgsWrite) {
+			mt.Errorf("WWW-Authenticate = %q, want the insufficient_scope challenge for %s", challenge, scopes.OrgsWrite)
+		}
+
+		var response struct {
+			Code          string   `json:"code"`
+			MissingScopes []string `json:"missing_scopes"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Code != "insufficient_scope" || len(response.MissingScopes) != 1 || response.MissingScopes[0] != scopes.OrgsWrite {
+			mt.Errorf("response %+v, want %s missing", response, scopes.OrgsWrite)
+		}
+	})
+
+	mt.Run("no scopes", func(mt *mtest.T) {
+		if recorder := request(mt, nil); recorder.Code != http.StatusForbidden {
+			mt.Errorf("request returned %d, want %d", recorder.Code, http.StatusForbidden)
+		}
+	})
+}
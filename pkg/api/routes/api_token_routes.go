@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAPITokenRoutes defines the routes managing personal access tokens and API keys. Tokens
+// can only be managed from a signed in session, not with another token.
+func SetupAPITokenRoutes(router *gin.Engine, apiTokenHandler *handlers.APITokenHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository) {
+	sessionOnly := middleware.SessionOnly()
+
+	tokenRoutes := router.Group("/users/tokens")
+	tokenRoutes.Use(sessionOnly)
+	{
+		tokenRoutes.GET("", middleware.RequireScopes(scopes.AccountRead), apiTokenHandler.GetPersonalAccessTokens)
+		tokenRoutes.POST("", middleware.RequireScopes(scopes.AccountWrite), apiTokenHandler.CreatePersonalAccessToken)
+		tokenRoutes.DELETE("/:token_id", middleware.RequireScopes(scopes.AccountWrite), apiTokenHandler.DeletePersonalAccessToken)
+	}
+
+	// API keys of an organization, managed by its admins
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+
+	router.GET("/organizations/:id/api-keys", sessionOnly, canRead, organizationAccess, adminOnly, apiTokenHandler.GetAPIKeys)
+	router.POST("/organizations/:id/api-keys", sessionOnly, canWrite, organizationAccess, adminOnly, apiTokenHandler.CreateAPIKey)
+	router.DELETE("/organizations/:id/api-keys/:key_id", sessionOnly, canWrite, organizationAccess, adminOnly, apiTokenHandler.DeleteAPIKey)
+}
@@ -3,25 +3,37 @@ package routes
 import (
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/dnsverify"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/purge"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAuthRoutes(router *gin.Engine, userRepository *repository.UserRepository, organizationRepository *repository.OrganizationRepository) {
+func SetupAuthRoutes(router *gin.Engine, userRepository *repository.UserRepository, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, apiTokenRepository *repository.APITokenRepository, invitationRepository *repository.InvitationRepository, invitationJobRepository *repository.InvitationJobRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions) {
     // Initialize organization handler
-    organizationHandler := handlers.NewOrganizationHandler(organizationRepository)
+    organizationHandler := handlers.NewOrganizationHandler(organizationRepository, userRepository, membershipRepository, invitationRepository, invitationJobRepository, auditEventRepository, outboxRepository, transactions, dnsverify.DefaultResolver, purge.DefaultRetention)
 
     // Create a new router group for authenticated routes
     authRoutes := router.Group("/auth")
-    authRoutes.Use(middleware.BearerTokenAuth(userRepository, organizationRepository))
+    authRoutes.Use(middleware.BearerTokenAuth(userRepository, apiTokenRepository))
+
+    // Routes on a single organization are restricted to its members
+    organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+
+    // Scopes the token must grant
+    canRead := middleware.RequireScopes(scopes.OrgsRead)
+    canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+    canInvite := middleware.RequireScopes(scopes.InvitationsWrite)
 
     {
         // Organization routes
-        authRoutes.POST("/organization", organizationHandler.CreateOrganization)
-        authRoutes.GET("/organization/:organization_id", organizationHandler.GetOrganizationByID)
-        authRoutes.GET("/organization", organizationHandler.GetAllOrganizations)
-        authRoutes.PUT("/organization/:organization_id", organizationHandler.UpdateOrganization)
-        authRoutes.DELETE("/organization/:organization_id", organizationHandler.DeleteOrganization)
-        authRoutes.POST("/organization/:organization_id/invite", organizationHandler.InviteUserToOrganization)
+        authRoutes.POST("/organization", canWrite, organizationHandler.CreateOrganization)
+        authRoutes.GET("/organization/:id", canRead, organizationAccess, organizationHandler.GetOrganizationByID)
+        authRoutes.GET("/organization", canRead, organizationHandler.GetAllOrganizations)
+        authRoutes.PUT("/organization/:id", canWrite, organizationAccess, organizationHandler.UpdateOrganization)
+        authRoutes.DELETE("/organization/:id", canWrite, organizationAccess, middleware.RequireAccessLevel(models.AccessLevelAdmin), organizationHandler.DeleteOrganization)
+        authRoutes.POST("/organization/:id/invite", canInvite, organizationAccess, organizationHandler.InviteUserToOrganization)
     }
 }
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAuthorizationServerRoutes defines the OAuth 2.0 authorization server routes and the
+// registration of OAuth clients by organizations.
+func SetupAuthorizationServerRoutes(router *gin.Engine, authorizationServerHandler *handlers.AuthorizationServerHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, rateLimitStore ratelimit.Store) {
+	tokenIPLimiter := ratelimit.NewLimiter(rateLimitStore, "oauth-token:ip", 60, time.Minute)
+	sessionOnly := middleware.SessionOnly()
+
+	// Clients of an organization, managed by its admins
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+
+	router.GET("/organizations/:id/oauth-clients", sessionOnly, canRead, organizationAccess, adminOnly, authorizationServerHandler.GetClients)
+	router.POST("/organizations/:id/oauth-clients", sessionOnly, canWrite, organizationAccess, adminOnly, authorizationServerHandler.CreateClient)
+	router.DELETE("/organizations/:id/oauth-clients/:client_id", sessionOnly, canWrite, organizationAccess, adminOnly, authorizationServerHandler.DeleteClient)
+
+	oauthRoutes := router.Group("/oauth")
+	{
+		// Consent is given by the user from a signed in session
+		oauthRoutes.GET("/authorize", sessionOnly, middleware.RequireScopes(scopes.AccountRead), authorizationServerHandler.GetAuthorization)
+		oauthRoutes.POST("/authorize", sessionOnly, middleware.RequireScopes(scopes.AccountWrite), authorizationServerHandler.Authorize)
+
+		// Endpoints called by clients, authenticated with their credentials
+		rateLimited := middleware.RateLimit(tokenIPLimiter, middleware.ClientIPKey)
+		oauthRoutes.POST("/token", rateLimited, authorizationServerHandler.Token)
+		oauthRoutes.POST("/introspect", rateLimited, authorizationServerHandler.Introspect)
+		oauthRoutes.POST("/revoke", rateLimited, authorizationServerHandler.Revoke)
+	}
+}
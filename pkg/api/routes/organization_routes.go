@@ -1,24 +1,104 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
 )
 
 // SetupOrganizationRoutes defines organization-related routes.
-func SetupOrganizationRoutes(router *gin.Engine, organizationHandler *handlers.OrganizationHandler) {
+func SetupOrganizationRoutes(router *gin.Engine, organizationHandler *handlers.OrganizationHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, rateLimitStore ratelimit.Store) {
+	// Initialize rate limits for invitations
+	inviteIPLimiter := ratelimit.NewLimiter(rateLimitStore, "invite:ip", 60, time.Hour)
+	inviteUserLimiter := ratelimit.NewLimiter(rateLimitStore, "invite:user", 30, time.Hour)
+
+	// Routes on a single organization are restricted to its members, some to its admins
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+
+	// Scopes the token must grant
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+	canInvite := middleware.RequireScopes(scopes.InvitationsWrite)
+	canManageMembers := middleware.RequireScopes(scopes.MembersManage)
+
 	// Define a group for organization routes
 	organizationRoutes := router.Group("/organizations")
 
 	// Define routes for creating, reading, updating, and deleting organizations
-	organizationRoutes.POST("/", organizationHandler.CreateOrganization)
-	organizationRoutes.GET("/:id", organizationHandler.GetOrganizationByID)
-	organizationRoutes.PUT("/:id", organizationHandler.UpdateOrganization)
-	organizationRoutes.DELETE("/:id", organizationHandler.DeleteOrganization)
+	organizationRoutes.POST("/", canWrite, organizationHandler.CreateOrganization)
+	organizationRoutes.GET("/:id", canRead, organizationAccess, organizationHandler.GetOrganizationByID)
+	organizationRoutes.PUT("/:id", canWrite, organizationAccess, organizationHandler.UpdateOrganization)
+	organizationRoutes.PATCH("/:id", canWrite, organizationAccess, organizationHandler.PatchOrganization)
+	organizationRoutes.DELETE("/:id", canWrite, organizationAccess, adminOnly, organizationHandler.DeleteOrganization)
+
+	// Define route for restoring a deleted organization, until it is purged
+	organizationRoutes.POST("/:id/restore", canWrite, middleware.DeletedOrganizationAccess(organizationRepository, membershipRepository), adminOnly, organizationHandler.RestoreOrganization)
+
+	// Define routes for listing and managing members, and for leaving an organization
+	organizationRoutes.GET("/:id/members", canRead, organizationAccess, organizationHandler.GetMembers)
+	organizationRoutes.PATCH("/:id/members/:userId", canManageMembers, organizationAccess, adminOnly, organizationHandler.UpdateMember)
+	organizationRoutes.DELETE("/:id/members/:userId", canManageMembers, organizationAccess, adminOnly, organizationHandler.RemoveMember)
+	organizationRoutes.POST("/:id/leave", canWrite, organizationAccess, organizationHandler.LeaveOrganization)
+
+	// Define routes for listing the organizations nested in an organization, and for moving it
+	organizationRoutes.GET("/:id/tree", canRead, organizationAccess, organizationHandler.GetOrganizationTree)
+	organizationRoutes.PUT("/:id/parent", canWrite, organizationAccess, adminOnly, organizationHandler.SetOrganizationParent)
+
+	// Define routes for transferring the ownership of an organization to another member
+	organizationRoutes.GET("/:id/ownership", canRead, organizationAccess, organizationHandler.GetOwnership)
+	organizationRoutes.POST("/:id/ownership/transfer", canManageMembers, organizationAccess, adminOnly, organizationHandler.RequestOwnershipTransfer)
+	organizationRoutes.DELETE("/:id/ownership/transfer", canWrite, organizationAccess, organizationHandler.CancelOwnershipTransfer)
+	organizationRoutes.POST("/:id/ownership/transfer/accept", canWrite, organizationAccess, organizationHandler.AcceptOwnershipTransfer)
+
+	// Define route for requiring two-factor authentication from all members
+	organizationRoutes.PUT("/:id/mfa-policy", canWrite, organizationAccess, adminOnly, organizationHandler.SetMFAPolicy)
+
+	// Define routes for claiming and verifying email domains
+	organizationRoutes.GET("/:id/domains", canRead, organizationAccess, organizationHandler.GetDomains)
+	organizationRoutes.POST("/:id/domains", canWrite, organizationAccess, adminOnly, organizationHandler.AddDomain)
+	organizationRoutes.POST("/:id/domains/:domain/verify", canWrite, organizationAccess, adminOnly, organizationHandler.VerifyDomain)
+	organizationRoutes.DELETE("/:id/domains/:domain", canWrite, organizationAccess, adminOnly, organizationHandler.RemoveDomain)
+	organizationRoutes.PUT("/:id/domains/:domain/join-policy", canWrite, organizationAccess, adminOnly, organizationHandler.SetDomainJoinPolicy)
+
+	// Define route for reading the audit log of an organization
+	organizationRoutes.GET("/:id/audit-log", canRead, organizationAccess, adminOnly, organizationHandler.GetAuditLog)
 
 	// Define route for getting all organizations
-	organizationRoutes.GET("/", organizationHandler.GetAllOrganizations)
+	organizationRoutes.GET("/", canRead, organizationHandler.GetAllOrganizations)
 
 	// Define route for inviting users to organizations
-	organizationRoutes.POST("/:id/invite", organizationHandler.InviteUserToOrganization)
+	organizationRoutes.POST("/:id/invite",
+		canInvite,
+		organizationAccess,
+		middleware.RateLimit(inviteIPLimiter, middleware.ClientIPKey),
+		middleware.RateLimit(inviteUserLimiter, middleware.UserKey),
+		organizationHandler.InviteUserToOrganization,
+	)
+
+	// Define routes for inviting many users at once in the background, and for following the job
+	organizationRoutes.POST("/:id/invitations/bulk",
+		canInvite,
+		organizationAccess,
+		middleware.RateLimit(inviteIPLimiter, middleware.ClientIPKey),
+		middleware.RateLimit(inviteUserLimiter, middleware.UserKey),
+		organizationHandler.BulkInviteUsers,
+	)
+	organizationRoutes.GET("/:id/invitations/bulk/:jobId", canInvite, organizationAccess, organizationHandler.GetInvitationJob)
+	organizationRoutes.GET("/:id/invitations/bulk/:jobId/report", canInvite, organizationAccess, organizationHandler.GetInvitationJobReport)
+
+	// Define routes for managing the invitation links anyone can join an organization with
+	organizationRoutes.GET("/:id/invite-links", canInvite, organizationAccess, adminOnly, organizationHandler.GetInviteLinks)
+	organizationRoutes.POST("/:id/invite-links", canInvite, organizationAccess, adminOnly, organizationHandler.CreateInviteLink)
+	organizationRoutes.GET("/:id/invite-links/:linkId/uses", canInvite, organizationAccess, adminOnly, organizationHandler.GetInviteLinkUses)
+	organizationRoutes.DELETE("/:id/invite-links/:linkId", canInvite, organizationAccess, adminOnly, organizationHandler.RevokeInviteLink)
+
+	// Define route for accepting an invitation or an invitation link, as the invited user
+	router.POST("/invitations/accept", middleware.RequireScopes(scopes.AccountWrite), organizationHandler.AcceptInvitation)
 }
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSCIMRoutes defines the SCIM 2.0 provisioning routes.
+func SetupSCIMRoutes(router *gin.Engine, scimHandler *handlers.SCIMHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, rateLimitStore ratelimit.Store) {
+	scimIPLimiter := ratelimit.NewLimiter(rateLimitStore, "scim:ip", 300, time.Minute)
+
+	// Tokens of an organization's identity provider, managed by its admins
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canManageMembers := middleware.RequireScopes(scopes.MembersManage)
+
+	router.POST("/organizations/:id/scim/token", canManageMembers, organizationAccess, adminOnly, scimHandler.CreateToken)
+	router.DELETE("/organizations/:id/scim/token", canManageMembers, organizationAccess, adminOnly, scimHandler.DeleteToken)
+
+	// Provisioning endpoints, authenticated with the organization's SCIM token
+	scimRoutes := router.Group("/scim/v2")
+	scimRoutes.Use(middleware.RateLimit(scimIPLimiter, middleware.ClientIPKey))
+	scimRoutes.Use(middleware.SCIMAuth(organizationRepository))
+	{
+		scimRoutes.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+
+		scimRoutes.GET("/Users", scimHandler.ListUsers)
+		scimRoutes.POST("/Users", scimHandler.CreateUser)
+		scimRoutes.GET("/Users/:id", scimHandler.GetUser)
+		scimRoutes.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scimRoutes.PATCH("/Users/:id", scimHandler.PatchUser)
+		scimRoutes.DELETE("/Users/:id", scimHandler.DeleteUser)
+
+		scimRoutes.GET("/Groups", scimHandler.ListGroups)
+		scimRoutes.POST("/Groups", scimHandler.UnsupportedGroupOperation)
+		scimRoutes.GET("/Groups/:id", scimHandler.GetGroup)
+		scimRoutes.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scimRoutes.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scimRoutes.DELETE("/Groups/:id", scimHandler.UnsupportedGroupOperation)
+	}
+}
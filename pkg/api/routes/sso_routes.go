@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSSORoutes defines single sign-on routes.
+func SetupSSORoutes(router *gin.Engine, ssoHandler *handlers.SSOHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, rateLimitStore ratelimit.Store) {
+	ssoIPLimiter := ratelimit.NewLimiter(rateLimitStore, "sso:ip", 30, time.Minute)
+
+	// Configuration of an organization's identity provider, restricted to its admins
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+
+	router.GET("/organizations/:id/sso/saml", canRead, organizationAccess, adminOnly, ssoHandler.GetSAMLConfig)
+	router.PUT("/organizations/:id/sso/saml", canWrite, organizationAccess, adminOnly, ssoHandler.ConfigureSAML)
+	router.DELETE("/organizations/:id/sso/saml", canWrite, organizationAccess, adminOnly, ssoHandler.DeleteSAMLConfig)
+
+	// Service provider endpoints, reached by browsers and identity providers without a token
+	samlRoutes := router.Group("/sso/saml/:id")
+	samlRoutes.Use(middleware.RateLimit(ssoIPLimiter, middleware.ClientIPKey))
+	{
+		samlRoutes.GET("/metadata", ssoHandler.Metadata)
+		samlRoutes.GET("/login", ssoHandler.Login)
+		samlRoutes.POST("/acs", ssoHandler.AssertionConsumerService)
+	}
+}
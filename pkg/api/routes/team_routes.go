@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTeamRoutes defines the routes managing the teams of an organization. Every member can
+// see them, admins create and delete them, and maintainers manage their members.
+func SetupTeamRoutes(router *gin.Engine, teamHandler *handlers.TeamHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository) {
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+	canManageMembers := middleware.RequireScopes(scopes.MembersManage)
+
+	teamRoutes := router.Group("/organizations/:id/teams")
+	{
+		teamRoutes.GET("", canRead, organizationAccess, teamHandler.GetTeams)
+		teamRoutes.POST("", canWrite, organizationAccess, adminOnly, teamHandler.CreateTeam)
+		teamRoutes.GET("/:teamId", canRead, organizationAccess, teamHandler.GetTeam)
+		teamRoutes.PATCH("/:teamId", canWrite, organizationAccess, teamHandler.UpdateTeam)
+		teamRoutes.DELETE("/:teamId", canWrite, organizationAccess, adminOnly, teamHandler.DeleteTeam)
+		teamRoutes.GET("/:teamId/members", canRead, organizationAccess, teamHandler.GetTeamMembers)
+		teamRoutes.GET("/:teamId/effective-members", canRead, organizationAccess, teamHandler.GetEffectiveTeamMembers)
+		teamRoutes.PUT("/:teamId/members/:userId", canManageMembers, organizationAccess, teamHandler.SetTeamMember)
+		teamRoutes.DELETE("/:teamId/members/:userId", canManageMembers, organizationAccess, teamHandler.RemoveTeamMember)
+	}
+}
@@ -1,20 +1,61 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/oauth"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/ratelimit"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupUserRoutes(router *gin.Engine, userRepository *repository.UserRepository) {
+func SetupUserRoutes(router *gin.Engine, userRepository *repository.UserRepository, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, auditEventRepository *repository.AuditEventRepository, outboxRepository *repository.OutboxRepository, transactions *repository.Transactions, rateLimitStore ratelimit.Store, oauthProviders map[string]oauth.Provider, loginStates cache.Cache) {
+    // Initialize rate limits and the signin lockout
+    signupIPLimiter := ratelimit.NewLimiter(rateLimitStore, "signup:ip", 10, time.Hour)
+    signupAccountLimiter := ratelimit.NewLimiter(rateLimitStore, "signup:account", 5, time.Hour)
+    signinIPLimiter := ratelimit.NewLimiter(rateLimitStore, "signin:ip", 20, time.Minute)
+    signinAccountLimiter := ratelimit.NewLimiter(rateLimitStore, "signin:account", 10, time.Minute)
+    signinLockout := ratelimit.NewLockout(rateLimitStore, "signin", 5, time.Minute, time.Hour)
+    signinMFAIPLimiter := ratelimit.NewLimiter(rateLimitStore, "signin-mfa:ip", 20, time.Minute)
+    refreshTokenIPLimiter := ratelimit.NewLimiter(rateLimitStore, "refresh-token:ip", 30, time.Minute)
+    refreshTokenAccountLimiter := ratelimit.NewLimiter(rateLimitStore, "refresh-token:account", 10, time.Minute)
+    oauthIPLimiter := ratelimit.NewLimiter(rateLimitStore, "oauth:ip", 30, time.Minute)
+
     // Initialize user handler
-    userHandler := handlers.NewUserHandler(userRepository)
+    userHandler := handlers.NewUserHandler(userRepository, organizationRepository, membershipRepository, auditEventRepository, outboxRepository, transactions, signinAccountLimiter, signupAccountLimiter, refreshTokenAccountLimiter, signinLockout, oauthProviders, loginStates)
 
     // Define user-related routes
     userRoutes := router.Group("/users")
     {
-        userRoutes.POST("/signup", userHandler.Signup)
-        userRoutes.POST("/signin", userHandler.Signin)
-        userRoutes.POST("/refresh-token", userHandler.RefreshToken)
+        userRoutes.POST("/signup", middleware.RateLimit(signupIPLimiter, middleware.ClientIPKey), userHandler.Signup)
+        userRoutes.POST("/signin", middleware.RateLimit(signinIPLimiter, middleware.ClientIPKey), userHandler.Signin)
+        userRoutes.POST("/signin/mfa", middleware.RateLimit(signinMFAIPLimiter, middleware.ClientIPKey), userHandler.SigninMFA)
+        userRoutes.POST("/refresh-token", middleware.RateLimit(refreshTokenIPLimiter, middleware.ClientIPKey), userHandler.RefreshToken)
+
+        // Signin with external identity providers
+        userRoutes.GET("/oauth", userHandler.GetOAuthProviders)
+        userRoutes.GET("/oauth/:provider/login", middleware.RateLimit(oauthIPLimiter, middleware.ClientIPKey), userHandler.OAuthLogin)
+        userRoutes.GET("/oauth/:provider/callback", middleware.RateLimit(oauthIPLimiter, middleware.ClientIPKey), userHandler.OAuthCallback)
+        userRoutes.POST("/oauth/:provider/link", middleware.RateLimit(signinIPLimiter, middleware.ClientIPKey), userHandler.LinkOAuthIdentity)
+
+        // Two-factor authentication enrollment
+        userRoutes.POST("/mfa/totp/enroll", middleware.RequireScopes(scopes.AccountWrite), userHandler.EnrollTOTP)
+        userRoutes.GET("/mfa/totp/qr-code", middleware.RequireScopes(scopes.AccountRead), userHandler.GetTOTPQRCode)
+        userRoutes.POST("/mfa/totp/confirm", middleware.RequireScopes(scopes.AccountWrite), userHandler.ConfirmTOTP)
+        userRoutes.POST("/mfa/totp/disable", middleware.RequireScopes(scopes.AccountWrite), userHandler.DisableTOTP)
+
+        // Organizations the user is a member of
+        userRoutes.GET("/me/organizations", middleware.RequireScopes(scopes.OrgsRead), userHandler.GetOrganizations)
+
+        // Organizations the user may join with the domain of their email
+        userRoutes.GET("/me/domain-organizations", middleware.RequireScopes(scopes.OrgsRead), userHandler.GetDomainOrganizations)
+        userRoutes.POST("/me/domain-organizations/:id/join", middleware.RequireScopes(scopes.AccountWrite), userHandler.JoinDomainOrganization)
+
+        // Deleting the account, only from a signed in session
+        userRoutes.DELETE("/me", middleware.SessionOnly(), middleware.RequireScopes(scopes.AccountWrite), userHandler.DeleteAccount)
     }
 }
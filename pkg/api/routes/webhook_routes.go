@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/middleware"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWebhookRoutes defines the routes managing the webhooks of an organization, restricted to
+// its admins.
+func SetupWebhookRoutes(router *gin.Engine, webhookHandler *handlers.WebhookHandler, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository) {
+	organizationAccess := middleware.OrganizationAccess(organizationRepository, membershipRepository)
+	adminOnly := middleware.RequireAccessLevel(models.AccessLevelAdmin)
+	canRead := middleware.RequireScopes(scopes.OrgsRead)
+	canWrite := middleware.RequireScopes(scopes.OrgsWrite)
+
+	webhookRoutes := router.Group("/organizations/:id/webhooks")
+	{
+		webhookRoutes.GET("", canRead, organizationAccess, adminOnly, webhookHandler.GetWebhooks)
+		webhookRoutes.POST("", canWrite, organizationAccess, adminOnly, webhookHandler.CreateWebhook)
+		webhookRoutes.DELETE("/:webhook_id", canWrite, organizationAccess, adminOnly, webhookHandler.DeleteWebhook)
+		webhookRoutes.GET("/:webhook_id/deliveries", canRead, organizationAccess, adminOnly, webhookHandler.GetDeliveries)
+		webhookRoutes.POST("/:webhook_id/deliveries/:delivery_id/redeliver", canWrite, organizationAccess, adminOnly, webhookHandler.Redeliver)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/api/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWellKnownRoutes defines the public discovery routes under /.well-known.
+func SetupWellKnownRoutes(router *gin.Engine, jwksHandler *handlers.JWKSHandler, authorizationServerHandler *handlers.AuthorizationServerHandler) {
+	wellKnownRoutes := router.Group("/.well-known")
+	{
+		wellKnownRoutes.GET("/jwks.json", jwksHandler.GetJWKS)
+		wellKnownRoutes.GET("/oauth-authorization-server", authorizationServerHandler.Metadata)
+	}
+}
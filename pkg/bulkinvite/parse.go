@@ -0,0 +1,129 @@
+// Package bulkinvite invites many users to an organization at once, from a CSV file or a JSON
+// array, in the background.
+package bulkinvite
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// MaxRows caps the number of invitations of one job, whose report is stored in one document.
+const MaxRows = 1000
+
+// ErrNoRows is returned when a file lists no invitation.
+var ErrNoRows = errors.New("no invitations were given")
+
+// ErrTooManyRows is returned when a file lists more than MaxRows invitations.
+var ErrTooManyRows = fmt.Errorf("at most %d invitations can be sent at once", MaxRows)
+
+// ParseCSV reads invitations from a CSV file with an email and an optional access level per
+// line. A first line naming the columns, such as "email,access_level", sets their order. Rows
+// are numbered after the lines of the file.
+func ParseCSV(r io.Reader) ([]models.InvitationJobRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	emailColumn, accessLevelColumn := 0, 1
+	rows := []models.InvitationJobRow{}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		// The header names the columns
+		if first && isCSVHeader(record) {
+			emailColumn, accessLevelColumn = -1, -1
+			for i, name := range record {
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "email":
+					emailColumn = i
+				case "access_level", "role":
+					accessLevelColumn = i
+				}
+			}
+			if emailColumn < 0 {
+				return nil, errors.New("invalid CSV: the header has no email column")
+			}
+			continue
+		}
+
+		row := models.InvitationJobRow{Row: line}
+		if emailColumn < len(record) {
+			row.Email = strings.TrimSpace(record[emailColumn])
+		}
+		if accessLevelColumn >= 0 && accessLevelColumn < len(record) {
+			row.AccessLevel = strings.ToLower(strings.TrimSpace(record[accessLevelColumn]))
+		}
+		rows = append(rows, row)
+	}
+
+	return checkRows(rows)
+}
+
+// ParseJSON reads invitations from a JSON array of emails, or of objects with an email and an
+// optional access_level. Rows are numbered after their position in the array, from 1.
+func ParseJSON(data []byte) ([]models.InvitationJobRow, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, errors.New("invalid JSON: expected an array of invitations")
+	}
+
+	rows := make([]models.InvitationJobRow, len(items))
+	for i, item := range items {
+		rows[i].Row = i + 1
+
+		var email string
+		if err := json.Unmarshal(item, &email); err == nil {
+			rows[i].Email = strings.TrimSpace(email)
+			continue
+		}
+
+		var invitation struct {
+			Email       string `json:"email"`
+			AccessLevel string `json:"access_level"`
+			Role        string `json:"role"`
+		}
+		if err := json.Unmarshal(item, &invitation); err != nil {
+			return nil, fmt.Errorf("invalid JSON: invitation %d must be an email or an object", i+1)
+		}
+		rows[i].Email = strings.TrimSpace(invitation.Email)
+		rows[i].AccessLevel = strings.ToLower(strings.TrimSpace(invitation.AccessLevel))
+		if rows[i].AccessLevel == "" {
+			rows[i].AccessLevel = strings.ToLower(strings.TrimSpace(invitation.Role))
+		}
+	}
+
+	return checkRows(rows)
+}
+
+// isCSVHeader reports whether a CSV record names columns rather than giving an invitation.
+func isCSVHeader(record []string) bool {
+	for _, name := range record {
+		if strings.EqualFold(strings.TrimSpace(name), "email") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRows(rows []models.InvitationJobRow) ([]models.InvitationJobRow, error) {
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(rows) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+	return rows, nil
+}
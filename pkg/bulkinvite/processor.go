@@ -0,0 +1,227 @@
+package bulkinvite
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/utils"
+)
+
+const (
+	// pollInterval is how often the processor looks for jobs.
+	pollInterval = 2 * time.Second
+
+	// jobLease is how long a claimed job is hidden from other processors. It is extended after
+	// each row, so a job is only claimed again if its processor died.
+	jobLease = time.Minute
+)
+
+// Processor sends the invitations of bulk invitation jobs, the same way single invitations
+// are: members and users with a pending invitation are skipped, and each invitation is
+// recorded in the outbox and the audit log.
+type Processor struct {
+	invitationJobRepository *repository.InvitationJobRepository
+	organizationRepository  *repository.OrganizationRepository
+	membershipRepository    *repository.MembershipRepository
+	invitationRepository    *repository.InvitationRepository
+	outboxRepository        *repository.OutboxRepository
+	auditEventRepository    *repository.AuditEventRepository
+	transactions            *repository.Transactions
+}
+
+func NewProcessor(invitationJobRepository *repository.InvitationJobRepository, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, invitationRepository *repository.InvitationRepository, outboxRepository *repository.OutboxRepository, auditEventRepository *repository.AuditEventRepository, transactions *repository.Transactions) *Processor {
+	return &Processor{
+		invitationJobRepository: invitationJobRepository,
+		organizationRepository:  organizationRepository,
+		membershipRepository:    membershipRepository,
+		invitationRepository:    invitationRepository,
+		outboxRepository:        outboxRepository,
+		auditEventRepository:    auditEventRepository,
+		transactions:            transactions,
+	}
+}
+
+// Start processes jobs in the background until ctx is done. Several instances can run
+// processors, each job is claimed by one of them at a time.
+func (p *Processor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			p.processDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// processDue processes every job waiting to be processed.
+func (p *Processor) processDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := p.invitationJobRepository.ClaimNext(ctx, time.Now(), jobLease)
+		if err != nil {
+			return
+		}
+
+		if err := p.process(ctx, job); err != nil {
+			log.Println("Error processing invitation job "+job.ID.Hex()+":", err)
+		}
+	}
+}
+
+// process invites the rows of a job which weren't processed yet, saving the result of each.
+func (p *Processor) process(ctx context.Context, job *models.InvitationJob) error {
+	if _, err := p.organizationRepository.GetOrganizationByID(ctx, job.OrganizationID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return p.invitationJobRepository.Finish(ctx, job.ID, "The organization was deleted", time.Now())
+		}
+		return err
+	}
+
+	// Only the first row with an email is invited, including rows processed before a restart
+	firstRows := map[string]int{}
+	for i, row := range job.Rows {
+		email := normalizeEmail(row.Email)
+		if _, ok := firstRows[email]; !ok {
+			firstRows[email] = i
+		}
+
+		if row.Status != "" {
+			continue
+		}
+
+		var result models.InvitationJobRow
+		if first := firstRows[email]; first != i && email != "" {
+			result = skipRow(row, "Duplicate of row "+strconv.Itoa(job.Rows[first].Row))
+		} else {
+			result = p.invite(ctx, job, row)
+		}
+
+		if err := p.invitationJobRepository.SaveRow(ctx, job.ID, i, result, time.Now().Add(jobLease)); err != nil {
+			return err
+		}
+	}
+
+	return p.invitationJobRepository.Finish(ctx, job.ID, "", time.Now())
+}
+
+// invite sends the invitation of a row, and returns the row with its result.
+func (p *Processor) invite(ctx context.Context, job *models.InvitationJob, row models.InvitationJobRow) models.InvitationJobRow {
+	row.Email = normalizeEmail(row.Email)
+	if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email {
+		return invalidRow(row, "Invalid email address")
+	}
+
+	// Invitees join as members unless an admin invites them as admin
+	if row.AccessLevel == "" {
+		row.AccessLevel = models.AccessLevelMember
+	}
+	if row.AccessLevel != models.AccessLevelMember && row.AccessLevel != models.AccessLevelAdmin {
+		return invalidRow(row, "Invalid access level")
+	}
+	if row.AccessLevel == models.AccessLevelAdmin && job.InviterAccessLevel != models.AccessLevelAdmin {
+		return invalidRow(row, "Only admins can invite admins")
+	}
+
+	// Don't invite members or users who were already invited
+	_, err := p.membershipRepository.GetMemberByEmail(ctx, job.OrganizationID, row.Email)
+	if err == nil {
+		return skipRow(row, "Already a member of the organization")
+	}
+	if !errors.Is(err, repository.ErrNotMember) {
+		return failRow(row)
+	}
+	_, err = p.invitationRepository.GetPendingInvitation(ctx, job.OrganizationID, row.Email)
+	if err == nil {
+		return skipRow(row, "Already has a pending invitation")
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return failRow(row)
+	}
+
+	// Generate the token the invitee accepts the invitation with. The invitation only stores
+	// its hash, the token is kept in the job until the report is first downloaded.
+	token, err := utils.GenerateOpaqueToken(utils.InvitationTokenPrefix)
+	if err != nil {
+		return failRow(row)
+	}
+
+	invitation := &models.Invitation{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: job.OrganizationID,
+		InvitedEmail:   row.Email,
+		AccessLevel:    row.AccessLevel,
+		TokenHash:      utils.HashOpaqueToken(token),
+		InvitedBy:      job.CreatedBy,
+		ExpiresAt:      time.Now().Add(models.InvitationLifetime),
+	}
+	err = p.transactions.Run(ctx, func(ctx context.Context) error {
+		if err := p.invitationRepository.CreateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		return p.outboxRepository.AddEvent(ctx, job.OrganizationID, models.EventInvitationCreated, bson.M{"invitation": invitation})
+	})
+	if err != nil {
+		return failRow(row)
+	}
+
+	// Record the invitation in the audit log on behalf of the user who created the job.
+	// Failures are logged, the invitation was already sent.
+	p.auditEventRepository.CreateEvent(ctx, &models.AuditEvent{
+		OrganizationID: job.OrganizationID,
+		ActorID:        job.CreatedBy,
+		ActorEmail:     job.CreatedByEmail,
+		Action:         models.AuditActionInvitationCreated,
+		TargetType:     models.AuditTargetInvitation,
+		TargetID:       invitation.ID.Hex(),
+		Changes: map[string]models.AuditChange{
+			"user_email":   {After: invitation.InvitedEmail},
+			"access_level": {After: invitation.AccessLevel},
+			"expires_at":   {After: invitation.ExpiresAt},
+		},
+		CreatedAt: time.Now(),
+	})
+
+	row.Status = models.InvitationRowInvited
+	row.InvitationID = invitation.ID
+	row.InvitationToken = token
+	return row
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func invalidRow(row models.InvitationJobRow, reason string) models.InvitationJobRow {
+	row.Status = models.InvitationRowInvalid
+	row.Reason = reason
+	return row
+}
+
+func skipRow(row models.InvitationJobRow, reason string) models.InvitationJobRow {
+	row.Status = models.InvitationRowSkipped
+	row.Reason = reason
+	return row
+}
+
+func failRow(row models.InvitationJobRow) models.InvitationJobRow {
+	row.Status = models.InvitationRowFailed
+	row.Reason = "The invitation couldn't be sent, try again later"
+	return row
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultSize is the number of entries kept by the in-process cache.
+const DefaultSize = 10000
+
+// Cache stores values under string keys for a limited time. Values are encoded with BSON,
+// so any model that can be stored in MongoDB can be cached as is.
+type Cache interface {
+	// Get decodes the value stored under key into dest and reports whether it was found.
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes the given keys.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// New returns a cache backed by Redis, or by an in-process LRU when no Redis client is
+// available. Hits and misses are reported through expvar.
+func New(client *goredis.Client) Cache {
+	if client == nil {
+		return NewMetered(NewLRU(DefaultSize))
+	}
+	return NewMetered(NewRedis(client))
+}
+
+// entry wraps cached values so that scalars can be encoded as BSON documents too.
+type entry struct {
+	Value interface{} `bson:"v"`
+}
+
+func encode(value interface{}) ([]byte, error) {
+	return bson.Marshal(entry{Value: value})
+}
+
+func decode(data []byte, dest interface{}) error {
+	var raw struct {
+		Value bson.RawValue `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return raw.Value.Unmarshal(dest)
+}
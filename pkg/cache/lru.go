@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruItem struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// LRU is an in-process cache evicting the least recently used entry once it holds size
+// entries. It is not shared between instances of the API, so entries invalidated on one
+// instance may be served by another until they expire.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	l.mu.Lock()
+	element, ok := l.items[key]
+	if !ok {
+		l.mu.Unlock()
+		return false, nil
+	}
+
+	item := element.Value.(*lruItem)
+	if !time.Now().Before(item.expiresAt) {
+		l.remove(element)
+		l.mu.Unlock()
+		return false, nil
+	}
+	l.order.MoveToFront(element)
+	data := item.data
+	l.mu.Unlock()
+
+	if err := decode(data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *LRU) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		item := element.Value.(*lruItem)
+		item.data = data
+		item.expiresAt = time.Now().Add(ttl)
+		l.order.MoveToFront(element)
+		return nil
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{
+		key:       key,
+		data:      data,
+		expiresAt: time.Now().Add(ttl),
+	})
+
+	// Evict the least recently used entries
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+
+	return nil
+}
+
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.items[key]; ok {
+			l.remove(element)
+		}
+	}
+
+	return nil
+}
+
+// remove drops an element from the cache. The caller must hold the lock.
+func (l *LRU) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.items, element.Value.(*lruItem).key)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"strings"
+	"time"
+)
+
+// stats exposes hit, miss and error counters per kind of entry through expvar, e.g.
+// "organization.hits" for keys starting with "organization:".
+var stats = expvar.NewMap("cache")
+
+// Metered counts the hits, misses and errors of the cache it wraps.
+type Metered struct {
+	cache Cache
+}
+
+func NewMetered(cache Cache) *Metered {
+	return &Metered{cache: cache}
+}
+
+func (m *Metered) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	found, err := m.cache.Get(ctx, key, dest)
+	switch {
+	case err != nil:
+		stats.Add(kind(key)+".errors", 1)
+	case found:
+		stats.Add(kind(key)+".hits", 1)
+	default:
+		stats.Add(kind(key)+".misses", 1)
+	}
+	return found, err
+}
+
+func (m *Metered) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	err := m.cache.Set(ctx, key, value, ttl)
+	if err != nil {
+		stats.Add(kind(key)+".errors", 1)
+	}
+	return err
+}
+
+func (m *Metered) Delete(ctx context.Context, keys ...string) error {
+	err := m.cache.Delete(ctx, keys...)
+	if err != nil {
+		stats.Add("delete.errors", 1)
+	}
+	return err
+}
+
+// kind returns the part of a key before its first colon.
+func kind(key string) string {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Redis is a cache shared by every instance of the API.
+type Redis struct {
+	client *goredis.Client
+	prefix string
+}
+
+func NewRedis(client *goredis.Client) *Redis {
+	return &Redis{
+		client: client,
+		prefix: "cache:",
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := decode(data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+key, data, ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
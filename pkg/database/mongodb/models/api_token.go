@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of credentials accepted as bearer tokens
+const (
+	TokenTypeSession             = "session"
+	TokenTypePersonalAccessToken = "personal_access_token"
+	TokenTypeAPIKey              = "api_key"
+	TokenTypeOAuthAccessToken    = "oauth_access_token"
+	TokenTypeOAuthRefreshToken   = "oauth_refresh_token"
+)
+
+// APIToken is an opaque credential stored by its hash. Personal access tokens act as the user
+// who created them and API keys belong to an organization and act on it alone, with no more
+// access than the user who created them has in it. Tokens issued to OAuth clients act as the
+// user who approved the client, or on the client's organization for the client credentials
+// grant.
+type APIToken struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Type           string             `json:"type,omitempty" bson:"type,omitempty"`
+	Name           string             `json:"name,omitempty" bson:"name,omitempty"`
+	Prefix         string             `json:"prefix,omitempty" bson:"prefix,omitempty"`
+	TokenHash      string             `json:"-" bson:"token_hash,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	ClientID       string             `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	Scopes         []string           `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	ExpiresAt      time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt     time.Time          `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// Expired reports whether the token has an expiry date in the past.
+func (t *APIToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionOrganizationCreated  = "organization.created"
+	AuditActionOrganizationUpdated  = "organization.updated"
+	AuditActionOrganizationDeleted  = "organization.deleted"
+	AuditActionOrganizationRestored = "organization.restored"
+	AuditActionOrganizationPurged   = "organization.purged"
+	AuditActionOrganizationMoved    = "organization.moved"
+	AuditActionOwnershipRequested   = "organization.ownership_transfer_requested"
+	AuditActionOwnershipCanceled    = "organization.ownership_transfer_canceled"
+	AuditActionOwnershipTransferred = "organization.ownership_transferred"
+	AuditActionMFAPolicyUpdated     = "organization.mfa_policy_updated"
+	AuditActionInvitationCreated    = "organization.invitation_created"
+	AuditActionInvitationAccepted   = "organization.invitation_accepted"
+	AuditActionInviteLinkCreated    = "organization.invite_link_created"
+	AuditActionInviteLinkRevoked    = "organization.invite_link_revoked"
+	AuditActionMemberUpdated        = "organization.member_updated"
+	AuditActionMemberRemoved        = "organization.member_removed"
+	AuditActionMemberLeft           = "organization.member_left"
+	AuditActionTeamCreated          = "organization.team_created"
+	AuditActionTeamUpdated          = "organization.team_updated"
+	AuditActionTeamDeleted          = "organization.team_deleted"
+	AuditActionTeamMemberAdded      = "organization.team_member_added"
+	AuditActionTeamMemberUpdated    = "organization.team_member_updated"
+	AuditActionTeamMemberRemoved    = "organization.team_member_removed"
+	AuditActionWebhookCreated       = "organization.webhook_created"
+	AuditActionWebhookDeleted       = "organization.webhook_deleted"
+	AuditActionDomainAdded          = "organization.domain_added"
+	AuditActionDomainVerified       = "organization.domain_verified"
+	AuditActionDomainRemoved        = "organization.domain_removed"
+	AuditActionDomainJoinPolicy     = "organization.domain_join_policy_updated"
+	AuditActionMemberJoinedByDomain = "organization.member_joined_by_domain"
+	AuditActionSAMLConfigured       = "organization.saml_configured"
+	AuditActionSAMLDeleted          = "organization.saml_deleted"
+	AuditActionSCIMTokenCreated     = "organization.scim_token_created"
+	AuditActionSCIMTokenRevoked     = "organization.scim_token_revoked"
+	AuditActionAPIKeyCreated        = "organization.api_key_created"
+	AuditActionAPIKeyRevoked        = "organization.api_key_revoked"
+	AuditActionOAuthClientCreated   = "organization.oauth_client_created"
+	AuditActionOAuthClientDeleted   = "organization.oauth_client_deleted"
+	AuditActionUserCreated          = "user.created"
+	AuditActionUserSignedIn         = "user.signed_in"
+	AuditActionUserTokensRefreshed  = "user.tokens_refreshed"
+	AuditActionUserMFAEnrollment    = "user.mfa_enrollment_started"
+	AuditActionUserMFAEnabled       = "user.mfa_enabled"
+	AuditActionUserMFADisabled      = "user.mfa_disabled"
+	AuditActionUserDeleted          = "user.deleted"
+)
+
+// Types of the targets of audit events
+const (
+	AuditTargetOrganization = "organization"
+	AuditTargetMember       = "member"
+	AuditTargetInvitation   = "invitation"
+	AuditTargetTeam         = "team"
+	AuditTargetTeamMember   = "team_member"
+	AuditTargetWebhook      = "webhook"
+	AuditTargetDomain       = "domain"
+	AuditTargetUser         = "user"
+	AuditTargetSAMLConfig   = "saml_config"
+	AuditTargetSCIMToken    = "scim_token"
+	AuditTargetAPIKey       = "api_key"
+	AuditTargetOAuthClient  = "oauth_client"
+)
+
+// AuditChange is the value of an attribute before and after a change.
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditEvent records who changed what and from where. Events are never updated or deleted.
+type AuditEvent struct {
+	ID             primitive.ObjectID     `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID     `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	ActorID        primitive.ObjectID     `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorEmail     string                 `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	ActorTokenType string                 `json:"actor_token_type,omitempty" bson:"actor_token_type,omitempty"`
+	ActorTokenID   primitive.ObjectID     `json:"actor_token_id,omitempty" bson:"actor_token_id,omitempty"`
+	Action         string                 `json:"action,omitempty" bson:"action,omitempty"`
+	TargetType     string                 `json:"target_type,omitempty" bson:"target_type,omitempty"`
+	TargetID       string                 `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	Changes        map[string]AuditChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	IP             string                 `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent      string                 `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	RequestID      string                 `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	CreatedAt      time.Time              `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
@@ -6,10 +6,60 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// InvitationLifetime is how long an invitation can be accepted.
+const InvitationLifetime = 7 * 24 * time.Hour
+
+// Types of invitations
+const (
+	InvitationTypeEmail = "email"
+	InvitationTypeLink  = "link"
+)
+
+// Invitation lets the user with the invited email join an organization with the access level.
+// Invitations of type link have no invited email instead: anyone with the link can join, up to
+// MaxUses times (unlimited when 0), optionally only with an email of the allowed domain.
+// Invitations without a type were sent to an email. Only the hash of the invitation token is
+// stored.
 type Invitation struct {
-	ID              primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	OrganizationID  primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
-	InvitedEmail    string             `json:"user_email,omitempty" bson:"invited_email,omitempty"`
-	InvitationToken string             `json:"invitation_token,omitempty" bson:"invitation_token,omitempty"`
-	CreatedAt       time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
-}
\ No newline at end of file
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	Type           string             `json:"type,omitempty" bson:"type,omitempty"`
+	InvitedEmail   string             `json:"user_email,omitempty" bson:"invited_email,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	AllowedDomain  string             `json:"allowed_domain,omitempty" bson:"allowed_domain,omitempty"`
+	MaxUses        int                `json:"max_uses,omitempty" bson:"max_uses,omitempty"`
+	UseCount       int                `json:"use_count" bson:"use_count,omitempty"`
+	TokenHash      string             `json:"-" bson:"token_hash,omitempty"`
+	InvitedBy      primitive.ObjectID `json:"invited_by,omitempty" bson:"invited_by,omitempty"`
+	ExpiresAt      time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	AcceptedAt     time.Time          `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	RevokedAt      time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// IsLink reports whether the invitation is a link anyone can join with.
+func (i *Invitation) IsLink() bool {
+	return i.Type == InvitationTypeLink
+}
+
+// Pending reports whether the invitation can still be accepted.
+func (i *Invitation) Pending() bool {
+	if !i.RevokedAt.IsZero() || !time.Now().Before(i.ExpiresAt) {
+		return false
+	}
+	if i.IsLink() {
+		return i.MaxUses == 0 || i.UseCount < i.MaxUses
+	}
+	return i.AcceptedAt.IsZero()
+}
+
+// InvitationUse records a user who joined an organization with an invitation link.
+type InvitationUse struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	InvitationID   primitive.ObjectID `json:"invitation_id,omitempty" bson:"invitation_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Email          string             `json:"email,omitempty" bson:"email,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	UsedAt         time.Time          `json:"used_at,omitempty" bson:"used_at,omitempty"`
+}
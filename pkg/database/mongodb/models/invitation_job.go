@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses of bulk invitation jobs
+const (
+	InvitationJobPending   = "pending"
+	InvitationJobRunning   = "running"
+	InvitationJobCompleted = "completed"
+	InvitationJobFailed    = "failed"
+)
+
+// Results of the rows of bulk invitation jobs
+const (
+	InvitationRowInvited = "invited"
+	InvitationRowSkipped = "skipped"
+	InvitationRowInvalid = "invalid"
+	InvitationRowFailed  = "failed"
+)
+
+// InvitationJobRow is an invitation requested in a bulk invitation job, and its result once
+// processed. The invitation token is kept until the report is first downloaded, as it is the
+// only way to deliver the invitation, and is cleared from the job then.
+type InvitationJobRow struct {
+	Row             int                `json:"row" bson:"row"`
+	Email           string             `json:"email" bson:"email"`
+	AccessLevel     string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	Status          string             `json:"status,omitempty" bson:"status,omitempty"`
+	Reason          string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	InvitationID    primitive.ObjectID `json:"invitation_id,omitempty" bson:"invitation_id,omitempty"`
+	InvitationToken string             `json:"invitation_token,omitempty" bson:"invitation_token,omitempty"`
+}
+
+// InvitationJob invites many users to an organization in the background. Rows are processed
+// in order and their result saved one at a time, so a job interrupted midway resumes where it
+// stopped. Jobs are deleted once they expire.
+type InvitationJob struct {
+	ID                 primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID     primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	CreatedBy          primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedByEmail     string             `json:"-" bson:"created_by_email,omitempty"`
+	InviterAccessLevel string             `json:"-" bson:"inviter_access_level,omitempty"`
+	Status             string             `json:"status,omitempty" bson:"status,omitempty"`
+	Rows               []InvitationJobRow `json:"-" bson:"rows,omitempty"`
+	Error              string             `json:"error,omitempty" bson:"error,omitempty"`
+	LeaseUntil         time.Time          `json:"-" bson:"lease_until,omitempty"`
+	CreatedAt          time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	CompletedAt        *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt          time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// Counts returns the number of rows with each result, and of rows not processed yet.
+func (j *InvitationJob) Counts() map[string]int {
+	counts := map[string]int{
+		InvitationRowInvited: 0,
+		InvitationRowSkipped: 0,
+		InvitationRowInvalid: 0,
+		InvitationRowFailed:  0,
+		"pending":            0,
+	}
+	for _, row := range j.Rows {
+		if row.Status == "" {
+			counts["pending"]++
+			continue
+		}
+		counts[row.Status]++
+	}
+	return counts
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuth 2.0 grant types
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// OAuthClient is a third-party application registered by an organization to act on behalf of
+// users, or on the organization itself with the client credentials grant.
+type OAuthClient struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ClientID       string             `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	SecretHash     string             `json:"-" bson:"secret_hash,omitempty"`
+	Name           string             `json:"name,omitempty" bson:"name,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	RedirectURIs   []string           `json:"redirect_uris,omitempty" bson:"redirect_uris,omitempty"`
+	GrantTypes     []string           `json:"grant_types,omitempty" bson:"grant_types,omitempty"`
+	Scopes         []string           `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	Public         bool               `json:"public,omitempty" bson:"public,omitempty"`
+	CreatedBy      primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// AllowsGrant reports whether the client was registered for the grant type.
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRedirectURI reports whether uri exactly matches one of the registered redirect URIs.
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizationCode is issued when a user approves a client, to be exchanged once for
+// tokens. The code is bound to the redirect URI given in the authorization request, if any,
+// which the token request must then repeat.
+type OAuthAuthorizationCode struct {
+	ID            primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	CodeHash      string             `json:"-" bson:"code_hash,omitempty"`
+	ClientID      string             `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	UserID        primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	RedirectURI   string             `json:"redirect_uri,omitempty" bson:"redirect_uri,omitempty"`
+	Scopes        []string           `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	CodeChallenge string             `json:"-" bson:"code_challenge,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
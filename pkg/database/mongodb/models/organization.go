@@ -6,17 +6,100 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Access levels of organization members
+const (
+	AccessLevelAdmin  = "admin"
+	AccessLevelMember = "member"
+)
+
+// OrganizationMember is the membership of a user in an organization, stored in the
+// memberships collection. The name and email of the user are copied for listing and searching.
 type OrganizationMember struct {
-	Name        string `json:"name,omitempty" bson:"name,omitempty"`
-	Email       string `json:"email,omitempty" bson:"email,omitempty"`
-	AccessLevel string `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Name           string             `json:"name,omitempty" bson:"name,omitempty"`
+	Email          string             `json:"email,omitempty" bson:"email,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	ExternalID     string             `json:"external_id,omitempty" bson:"external_id,omitempty"`
+	Suspended      bool               `json:"suspended,omitempty" bson:"suspended,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt      time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Policies for users with an email at a verified domain of an organization
+const (
+	DomainJoinNone  = "none"
+	DomainJoinOffer = "offer"
+	DomainJoinAuto  = "auto"
+)
+
+// OrganizationDomain is an email domain claimed by an organization. Ownership is proven by
+// publishing the verification token in a DNS TXT record. Once verified, users with a verified
+// email at the domain are offered to join the organization, or added to it, depending on the
+// join policy, with the join access level. Domains without a policy have none.
+type OrganizationDomain struct {
+	Domain            string    `json:"domain,omitempty" bson:"domain,omitempty"`
+	VerificationToken string    `json:"verification_token,omitempty" bson:"verification_token,omitempty"`
+	Verified          bool      `json:"verified,omitempty" bson:"verified,omitempty"`
+	VerifiedAt        time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+	JoinPolicy        string    `json:"join_policy,omitempty" bson:"join_policy,omitempty"`
+	JoinAccessLevel   string    `json:"join_access_level,omitempty" bson:"join_access_level,omitempty"`
+}
+
+// Joinable reports whether users with an email at the domain may join the organization.
+func (d *OrganizationDomain) Joinable() bool {
+	return d.Verified && (d.JoinPolicy == DomainJoinOffer || d.JoinPolicy == DomainJoinAuto)
 }
 
+// SAMLConfig describes the SAML 2.0 identity provider members of an organization sign in with.
+type SAMLConfig struct {
+	IDPEntityID        string    `json:"idp_entity_id,omitempty" bson:"idp_entity_id,omitempty"`
+	IDPMetadataXML     string    `json:"-" bson:"idp_metadata_xml,omitempty"`
+	DefaultAccessLevel string    `json:"default_access_level,omitempty" bson:"default_access_level,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// OwnershipTransfer is a pending transfer of the ownership of an organization to one of its
+// members, which takes effect once they accept it before it expires.
+type OwnershipTransfer struct {
+	ToUserID    primitive.ObjectID `json:"to_user_id" bson:"to_user_id"`
+	RequestedBy primitive.ObjectID `json:"requested_by" bson:"requested_by"`
+	RequestedAt time.Time          `json:"requested_at" bson:"requested_at"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// Pending reports whether the transfer can still be accepted.
+func (t *OwnershipTransfer) Pending() bool {
+	return t != nil && time.Now().Before(t.ExpiresAt)
+}
+
+// MaxOrganizationDepth bounds the number of levels of nested organizations, counting the top
+// one.
+const MaxOrganizationDepth = 10
+
+// Organization is a group of users. Version is incremented by every change, to detect
+// concurrent updates. Members are stored in the memberships collection; the embedded list is
+// only read to migrate organizations created before. Organizations may be nested in a parent,
+// whose admins are granted the parent admin access level in the child, or nothing when it has
+// none. The owner is the member who created the organization, or who accepted its ownership
+// since, and can't leave it or be demoted until they transfer the ownership. Organizations
+// created before owners were introduced have none.
 type Organization struct {
-	ID           primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name         string               `json:"name,omitempty" bson:"name,omitempty"`
-	Description  string               `json:"description,omitempty" bson:"description,omitempty"`
-	CreatedAt    time.Time            `json:"created_at,omitempty" bson:"created_at,omitempty"`
-	UpdatedAt    time.Time            `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
-	Members      []OrganizationMember `json:"members,omitempty" bson:"members,omitempty"`
+	ID                primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name              string               `json:"name,omitempty" bson:"name,omitempty"`
+	Description       string               `json:"description,omitempty" bson:"description,omitempty"`
+	CreatedAt         time.Time            `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt         time.Time            `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	Members           []OrganizationMember `json:"-" bson:"members,omitempty"`
+	RequireMFA        bool                 `json:"require_mfa,omitempty" bson:"require_mfa,omitempty"`
+	Domains           []OrganizationDomain `json:"domains,omitempty" bson:"domains,omitempty"`
+	SAML              *SAMLConfig          `json:"saml,omitempty" bson:"saml,omitempty"`
+	SCIMTokenHash     string               `json:"-" bson:"scim_token_hash,omitempty"`
+	DeletedAt         *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Version           int64                `json:"version,omitempty" bson:"version,omitempty"`
+	ParentID          primitive.ObjectID   `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	ParentAdminAccess string               `json:"parent_admin_access,omitempty" bson:"parent_admin_access,omitempty"`
+	OwnerID           primitive.ObjectID   `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	OwnershipTransfer *OwnershipTransfer   `json:"-" bson:"ownership_transfer,omitempty"`
 }
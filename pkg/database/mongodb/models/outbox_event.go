@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Domain events published through the outbox
+const (
+	EventOrganizationUpdated  = "organization.updated"
+	EventOrganizationDeleted  = "organization.deleted"
+	EventOrganizationRestored = "organization.restored"
+	EventMemberAdded          = "member.added"
+	EventMemberUpdated        = "member.updated"
+	EventMemberRemoved        = "member.removed"
+	EventInvitationCreated    = "invitation.created"
+	EventInvitationAccepted   = "invitation.accepted"
+	EventInvitationRevoked    = "invitation.revoked"
+	EventTeamCreated          = "team.created"
+	EventTeamUpdated          = "team.updated"
+	EventTeamDeleted          = "team.deleted"
+	EventTeamMemberAdded      = "team.member_added"
+	EventTeamMemberUpdated    = "team.member_updated"
+	EventTeamMemberRemoved    = "team.member_removed"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes,
+// and published to every sink by the relay afterwards. Payload is the JSON document sinks
+// publish, with the ID, type, organization, time and data of the event.
+type OutboxEvent struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Type           string             `json:"type,omitempty" bson:"type,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	Payload        json.RawMessage    `json:"payload,omitempty" bson:"payload,omitempty"`
+	PublishedTo    []string           `json:"published_to,omitempty" bson:"published_to,omitempty"`
+	Attempts       int                `json:"attempts,omitempty" bson:"attempts,omitempty"`
+	NextAttemptAt  time.Time          `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	LastError      string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	PublishedAt    *time.Time         `json:"published_at,omitempty" bson:"published_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
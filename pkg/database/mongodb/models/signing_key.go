@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKey is a private key access tokens are signed with. Keys are published for
+// verification before they become active and remain published for a while after being
+// replaced by a newer key. The private key is stored encrypted with a key encryption key
+// kept out of the database; keys created before that have a plaintext PrivateKey instead.
+type SigningKey struct {
+	ID                  primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	KeyID               string             `json:"kid,omitempty" bson:"kid,omitempty"`
+	Algorithm           string             `json:"alg,omitempty" bson:"alg,omitempty"`
+	PrivateKey          []byte             `json:"-" bson:"private_key,omitempty"`
+	EncryptedPrivateKey []byte             `json:"-" bson:"encrypted_private_key,omitempty"`
+	ActivatesAt         time.Time          `json:"activates_at,omitempty" bson:"activates_at,omitempty"`
+	CreatedAt           time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
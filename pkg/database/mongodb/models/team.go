@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roles of team members
+const (
+	TeamRoleMaintainer = "maintainer"
+	TeamRoleMember     = "member"
+)
+
+// Team groups members of an organization. Teams may be nested in a parent team, whose
+// effective members include the members of its child teams. The access level of a team is
+// granted in the organization to its effective members, on top of their own. Teams without an
+// access level grant nothing.
+type Team struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	ParentID       primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Name           string             `json:"name,omitempty" bson:"name,omitempty"`
+	Description    string             `json:"description,omitempty" bson:"description,omitempty"`
+	AccessLevel    string             `json:"access_level,omitempty" bson:"access_level,omitempty"`
+	CreatedBy      primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt      time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// TeamMember is the membership of a member of an organization in one of its teams.
+// Maintainers manage the members of the team and of its child teams.
+type TeamMember struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	TeamID         primitive.ObjectID `json:"team_id,omitempty" bson:"team_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Role           string             `json:"role,omitempty" bson:"role,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt      time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// TeamAncestors returns the IDs of a team and of every team it is nested in, from the team
+// up, given all the teams of its organization. It stops at a parent missing from the teams.
+func TeamAncestors(teams []*Team, id primitive.ObjectID) []primitive.ObjectID {
+	parents := make(map[primitive.ObjectID]primitive.ObjectID, len(teams))
+	for _, team := range teams {
+		parents[team.ID] = team.ParentID
+	}
+
+	ancestors := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for current := id; !current.IsZero() && !seen[current]; current = parents[current] {
+		seen[current] = true
+		ancestors = append(ancestors, current)
+		if _, ok := parents[current]; !ok {
+			break
+		}
+	}
+	return ancestors
+}
+
+// TeamDescendants returns the IDs of a team and of every team nested in it, at any depth,
+// given all the teams of its organization.
+func TeamDescendants(teams []*Team, id primitive.ObjectID) []primitive.ObjectID {
+	children := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, team := range teams {
+		if !team.ParentID.IsZero() {
+			children[team.ParentID] = append(children[team.ParentID], team.ID)
+		}
+	}
+
+	descendants := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	queue := []primitive.ObjectID{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		descendants = append(descendants, current)
+		queue = append(queue, children[current]...)
+	}
+	return descendants
+}
@@ -6,6 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+    Provider string    `json:"provider,omitempty" bson:"provider,omitempty"`
+    Subject  string    `json:"subject,omitempty" bson:"subject,omitempty"`
+    Email    string    `json:"email,omitempty" bson:"email,omitempty"`
+    LinkedAt time.Time `json:"linked_at,omitempty" bson:"linked_at,omitempty"`
+}
+
 type User struct {
     ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
     Name      string             `json:"name,omitempty" bson:"name,omitempty"`
@@ -13,6 +21,13 @@ type User struct {
     Password  string             `json:"password,omitempty" bson:"password,omitempty"`
     AccessToken  string             `json:"access_token,omitempty" bson:"access_token,omitempty"`
 	RefreshToken string             `json:"refresh_token,omitempty" bson:"refresh_token,omitempty"`
+    MFAEnabled   bool               `json:"-" bson:"mfa_enabled,omitempty"`
+    TOTPSecret   string             `json:"-" bson:"totp_secret,omitempty"`
+    PendingTOTPSecret string        `json:"-" bson:"pending_totp_secret,omitempty"`
+    RecoveryCodes []string          `json:"-" bson:"recovery_codes,omitempty"`
+    LastTOTPStep int64              `json:"-" bson:"last_totp_step,omitempty"`
+    Identities   []UserIdentity     `json:"-" bson:"identities,omitempty"`
+    ProvisionedBy primitive.ObjectID `json:"-" bson:"provisioned_by,omitempty"`
     CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
     UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
 }
\ No newline at end of file
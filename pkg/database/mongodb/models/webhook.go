@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEvents lists every event webhooks can subscribe to.
+var WebhookEvents = []string{
+	EventOrganizationUpdated,
+	EventOrganizationDeleted,
+	EventOrganizationRestored,
+	EventMemberAdded,
+	EventMemberUpdated,
+	EventMemberRemoved,
+	EventInvitationCreated,
+	EventInvitationAccepted,
+	EventInvitationRevoked,
+	EventTeamCreated,
+	EventTeamUpdated,
+	EventTeamDeleted,
+	EventTeamMemberAdded,
+	EventTeamMemberUpdated,
+	EventTeamMemberRemoved,
+}
+
+// Statuses of webhook deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of an organization notified of the events it subscribed to. Payloads
+// are signed with the secret so the endpoint can check they come from the service.
+type Webhook struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	URL            string             `json:"url,omitempty" bson:"url,omitempty"`
+	Description    string             `json:"description,omitempty" bson:"description,omitempty"`
+	Events         []string           `json:"events,omitempty" bson:"events,omitempty"`
+	Secret         string             `json:"-" bson:"secret,omitempty"`
+	CreatedBy      primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt      time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// WebhookDelivery is an event to deliver to a webhook, along with the log of its attempts.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID       `json:"_id,omitempty" bson:"_id,omitempty"`
+	WebhookID      primitive.ObjectID       `json:"webhook_id,omitempty" bson:"webhook_id,omitempty"`
+	OrganizationID primitive.ObjectID       `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	EventID        string                   `json:"event_id,omitempty" bson:"event_id,omitempty"`
+	Event          string                   `json:"event,omitempty" bson:"event,omitempty"`
+	Payload        json.RawMessage          `json:"payload,omitempty" bson:"payload,omitempty"`
+	Status         string                   `json:"status,omitempty" bson:"status,omitempty"`
+	AttemptCount   int                      `json:"attempt_count" bson:"attempt_count"`
+	NextAttemptAt  time.Time                `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	Attempts       []WebhookDeliveryAttempt `json:"attempts,omitempty" bson:"attempts,omitempty"`
+	RedeliveryOf   primitive.ObjectID       `json:"redelivery_of,omitempty" bson:"redelivery_of,omitempty"`
+	CreatedAt      time.Time                `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	CompletedAt    time.Time                `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// WebhookDeliveryAttempt records the response of the endpoint to one attempt of a delivery.
+type WebhookDeliveryAttempt struct {
+	AttemptedAt    time.Time `json:"attempted_at,omitempty" bson:"attempted_at,omitempty"`
+	ResponseStatus int       `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ResponseBody   string    `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	Error          string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS     int64     `json:"duration_ms" bson:"duration_ms"`
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// apiTokenCacheTTL bounds how long a revoked token may still be accepted by other instances
+// sharing an in-process cache.
+const apiTokenCacheTTL = time.Minute
+
+// lastUsedPrecision is how often the last use of a token is written to the database.
+const lastUsedPrecision = time.Minute
+
+type APITokenRepository struct {
+	collection *mongo.Collection
+	cache      cache.Cache
+}
+
+func NewAPITokenRepository(database *mongo.Database, cache cache.Cache) *APITokenRepository {
+	return &APITokenRepository{
+		collection: database.Collection("api_tokens"),
+		cache:      cache,
+	}
+}
+
+func (tr *APITokenRepository) CreateToken(ctx context.Context, token *models.APIToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := tr.collection.InsertOne(ctx, token)
+	if err != nil {
+		log.Println("Error inserting API token:", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetTokenByHash retrieves the token with the given hash, which is how presented tokens are
+// looked up.
+func (tr *APITokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+
+	// Serve the token from the cache when possible
+	found, err := tr.cache.Get(ctx, apiTokenCacheKey(tokenHash), &token)
+	if err != nil {
+		log.Println("Error getting API token from cache:", err)
+	}
+	if found {
+		return &token, nil
+	}
+
+	err = tr.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting API token:", err)
+		}
+		return nil, err
+	}
+
+	if err := tr.cache.Set(ctx, apiTokenCacheKey(tokenHash), &token, apiTokenCacheTTL); err != nil {
+		log.Println("Error caching API token:", err)
+	}
+
+	return &token, nil
+}
+
+// GetUserTokens retrieves the personal access tokens of a user.
+func (tr *APITokenRepository) GetUserTokens(ctx context.Context, userID primitive.ObjectID) ([]*models.APIToken, error) {
+	return tr.find(ctx, bson.M{"type": models.TokenTypePersonalAccessToken, "user_id": userID})
+}
+
+// GetOrganizationTokens retrieves the API keys of an organization.
+func (tr *APITokenRepository) GetOrganizationTokens(ctx context.Context, organizationID primitive.ObjectID) ([]*models.APIToken, error) {
+	return tr.find(ctx, bson.M{"type": models.TokenTypeAPIKey, "organization_id": organizationID})
+}
+
+// DeleteUserToken revokes a personal access token of a user.
+func (tr *APITokenRepository) DeleteUserToken(ctx context.Context, id, userID primitive.ObjectID) error {
+	return tr.delete(ctx, bson.M{"_id": id, "type": models.TokenTypePersonalAccessToken, "user_id": userID})
+}
+
+// DeleteOrganizationToken revokes an API key of an organization.
+func (tr *APITokenRepository) DeleteOrganizationToken(ctx context.Context, id, organizationID primitive.ObjectID) error {
+	return tr.delete(ctx, bson.M{"_id": id, "type": models.TokenTypeAPIKey, "organization_id": organizationID})
+}
+
+// TakeClientToken retrieves and deletes a token issued to an OAuth client, of the given type
+// unless tokenType is empty.
+func (tr *APITokenRepository) TakeClientToken(ctx context.Context, tokenHash, clientID, tokenType string) (*models.APIToken, error) {
+	filter := bson.M{"token_hash": tokenHash, "client_id": clientID}
+	if tokenType != "" {
+		filter["type"] = tokenType
+	}
+	return tr.take(ctx, filter)
+}
+
+// DeleteClientTokens revokes every token issued to an OAuth client.
+func (tr *APITokenRepository) DeleteClientTokens(ctx context.Context, clientID string) error {
+	return tr.deleteAll(ctx, bson.M{"client_id": clientID})
+}
+
+// DeleteOrganizationTokens revokes every token acting on an organization, such as its API keys.
+func (tr *APITokenRepository) DeleteOrganizationTokens(ctx context.Context, organizationID primitive.ObjectID) error {
+	return tr.deleteAll(ctx, bson.M{"organization_id": organizationID})
+}
+
+// TouchLastUsed records that a token was used. Writes are skipped while the recorded time is
+// within lastUsedPrecision, so busy tokens don't cause a write per request.
+func (tr *APITokenRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"last_used_at": bson.M{"$exists": false}},
+			{"last_used_at": bson.M{"$lt": now.Add(-lastUsedPrecision)}},
+		},
+	}
+
+	_, err := tr.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_used_at": now}})
+	if err != nil {
+		log.Println("Error updating API token last use:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (tr *APITokenRepository) find(ctx context.Context, filter bson.M) ([]*models.APIToken, error) {
+	tokens := []*models.APIToken{}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := tr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error retrieving API tokens:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &tokens); err != nil {
+		log.Println("Error decoding API tokens:", err)
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (tr *APITokenRepository) delete(ctx context.Context, filter bson.M) error {
+	_, err := tr.take(ctx, filter)
+	return err
+}
+
+// deleteAll deletes the tokens matching the filter one by one, so that they are dropped from
+// the cache.
+func (tr *APITokenRepository) deleteAll(ctx context.Context, filter bson.M) error {
+	tokens, err := tr.find(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if _, err := tr.take(ctx, bson.M{"_id": token.ID}); err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// take deletes the token matching the filter and returns it.
+func (tr *APITokenRepository) take(ctx context.Context, filter bson.M) (*models.APIToken, error) {
+	var token models.APIToken
+	err := tr.collection.FindOneAndDelete(ctx, filter).Decode(&token)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error deleting API token:", err)
+		}
+		return nil, err
+	}
+
+	if err := tr.cache.Delete(ctx, apiTokenCacheKey(token.TokenHash)); err != nil {
+		log.Println("Error invalidating cached API token:", err)
+	}
+
+	return &token, nil
+}
+
+func apiTokenCacheKey(tokenHash string) string {
+	return "api-token:" + tokenHash
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// AuditEventRepository stores the audit log. It can only append events, never change them.
+type AuditEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditEventRepository(database *mongo.Database) *AuditEventRepository {
+	return &AuditEventRepository{
+		collection: database.Collection("audit_events"),
+	}
+}
+
+// AuditEventFilter selects events of an organization. Zero fields don't filter, and Before is
+// the ID of the last event of the previous page.
+type AuditEventFilter struct {
+	OrganizationID primitive.ObjectID
+	Action         string
+	ActorID        primitive.ObjectID
+	TargetType     string
+	TargetID       string
+	Since          time.Time
+	Until          time.Time
+	Before         primitive.ObjectID
+	Limit          int64
+}
+
+func (ar *AuditEventRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	_, err := ar.collection.InsertOne(ctx, event)
+	if err != nil {
+		log.Println("Error inserting audit event:", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetEvents retrieves the events matching the filter, most recent first.
+func (ar *AuditEventRepository) GetEvents(ctx context.Context, filter AuditEventFilter) ([]*models.AuditEvent, error) {
+	events := []*models.AuditEvent{}
+
+	query := bson.M{"organization_id": filter.OrganizationID}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if !filter.ActorID.IsZero() {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+
+	createdAt := bson.M{}
+	if !filter.Since.IsZero() {
+		createdAt["$gte"] = filter.Since
+	}
+	if !filter.Until.IsZero() {
+		createdAt["$lt"] = filter.Until
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	// Object IDs grow with time, so they order events and serve as the page cursor
+	if !filter.Before.IsZero() {
+		query["_id"] = bson.M{"$lt": filter.Before}
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(filter.Limit)
+	cursor, err := ar.collection.Find(ctx, query, opts)
+	if err != nil {
+		log.Println("Error retrieving audit events:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &events); err != nil {
+		log.Println("Error decoding audit events:", err)
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+type AuthorizationCodeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuthorizationCodeRepository(database *mongo.Database) *AuthorizationCodeRepository {
+	return &AuthorizationCodeRepository{
+		collection: database.Collection("oauth_authorization_codes"),
+	}
+}
+
+func (ar *AuthorizationCodeRepository) CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	code.CreatedAt = time.Now()
+
+	_, err := ar.collection.InsertOne(ctx, code)
+	if err != nil {
+		log.Println("Error inserting authorization code:", err)
+		return err
+	}
+
+	return nil
+}
+
+// TakeCode retrieves and deletes the code with the given hash issued to the client, so each
+// code is exchanged once. Codes of other clients are left as is.
+func (ar *AuthorizationCodeRepository) TakeCode(ctx context.Context, codeHash, clientID string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	err := ar.collection.FindOneAndDelete(ctx, bson.M{"code_hash": codeHash, "client_id": clientID}).Decode(&code)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error taking authorization code:", err)
+		}
+		return nil, err
+	}
+
+	return &code, nil
+}
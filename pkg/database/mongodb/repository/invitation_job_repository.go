@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// InvitationJobRepository stores bulk invitation jobs until they expire.
+type InvitationJobRepository struct {
+	collection *mongo.Collection
+}
+
+func NewInvitationJobRepository(database *mongo.Database) *InvitationJobRepository {
+	return &InvitationJobRepository{
+		collection: database.Collection("invitation_jobs"),
+	}
+}
+
+// EnsureIndexes creates the index jobs are claimed with, and the one deleting expired jobs.
+func (jr *InvitationJobRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := jr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Println("Error creating invitation job indexes:", err)
+		return err
+	}
+
+	return nil
+}
+
+// CreateJob queues a job, to be claimed right away.
+func (jr *InvitationJobRepository) CreateJob(ctx context.Context, job *models.InvitationJob) error {
+	job.Status = models.InvitationJobPending
+	job.LeaseUntil = job.CreatedAt
+
+	_, err := jr.collection.InsertOne(ctx, job)
+	if err != nil {
+		log.Println("Error inserting invitation job:", err)
+		return err
+	}
+	return nil
+}
+
+// GetJob returns a job of the organization.
+func (jr *InvitationJobRepository) GetJob(ctx context.Context, organizationID, id primitive.ObjectID) (*models.InvitationJob, error) {
+	var job models.InvitationJob
+	err := jr.collection.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimNext retrieves the oldest job which isn't done and isn't being processed, and hides it
+// from other processors for lease. It returns mongo.ErrNoDocuments when there is none.
+func (jr *InvitationJobRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*models.InvitationJob, error) {
+	filter := bson.M{
+		"status":      bson.M{"$in": bson.A{models.InvitationJobPending, models.InvitationJobRunning}},
+		"lease_until": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"status": models.InvitationJobRunning, "lease_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"_id": 1}).
+		SetReturnDocument(options.After)
+
+	var job models.InvitationJob
+	err := jr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error claiming invitation job:", err)
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SaveRow records the result of a row, and extends the lease of the job since it progresses.
+func (jr *InvitationJobRepository) SaveRow(ctx context.Context, id primitive.ObjectID, index int, row models.InvitationJobRow, leaseUntil time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"rows." + strconv.Itoa(index): row,
+		"lease_until":                 leaseUntil,
+	}}
+
+	_, err := jr.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Println("Error saving invitation job row:", err)
+		return err
+	}
+	return nil
+}
+
+// Finish records that a job completed, or failed with the given error when it isn't empty.
+func (jr *InvitationJobRepository) Finish(ctx context.Context, id primitive.ObjectID, jobError string, completedAt time.Time) error {
+	status := models.InvitationJobCompleted
+	if jobError != "" {
+		status = models.InvitationJobFailed
+	}
+	update := bson.M{"$set": bson.M{
+		"status":       status,
+		"error":        jobError,
+		"completed_at": completedAt,
+	}}
+
+	_, err := jr.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Println("Error finishing invitation job:", err)
+		return err
+	}
+	return nil
+}
+
+// TakeInvitationTokens clears the invitation tokens of a finished job, and returns the job as
+// it was before. Only the first call gets the tokens, later ones return the job without them.
+func (jr *InvitationJobRepository) TakeInvitationTokens(ctx context.Context, organizationID, id primitive.ObjectID) (*models.InvitationJob, error) {
+	filter := bson.M{
+		"_id":             id,
+		"organization_id": organizationID,
+		"status":          bson.M{"$in": bson.A{models.InvitationJobCompleted, models.InvitationJobFailed}},
+	}
+	update := bson.M{"$unset": bson.M{"rows.$[].invitation_token": ""}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var job models.InvitationJob
+	err := jr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error taking invitation job tokens:", err)
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DeleteOrganizationJobs deletes every job of an organization.
+func (jr *InvitationJobRepository) DeleteOrganizationJobs(ctx context.Context, organizationID primitive.ObjectID) error {
+	if _, err := jr.collection.DeleteMany(ctx, bson.M{"organization_id": organizationID}); err != nil {
+		log.Println("Error deleting organization invitation jobs:", err)
+		return err
+	}
+	return nil
+}
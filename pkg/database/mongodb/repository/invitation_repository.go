@@ -8,17 +8,20 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 )
 
 type InvitationRepository struct {
     collection *mongo.Collection
+    uses       *mongo.Collection
 }
 
 func NewInvitationRepository(database *mongo.Database) *InvitationRepository {
     return &InvitationRepository{
         collection: database.Collection("invitations"),
+        uses:       database.Collection("invitation_uses"),
     }
 }
 
@@ -60,4 +63,186 @@ func (ir *InvitationRepository) DeleteInvitation(ctx context.Context, id primiti
         return err
     }
     return nil
-}
\ No newline at end of file
+}
+
+// DeleteOrganizationInvitations deletes every invitation to an organization, and the uses of
+// its invitation links.
+func (ir *InvitationRepository) DeleteOrganizationInvitations(ctx context.Context, organizationID primitive.ObjectID) error {
+    _, err := ir.uses.DeleteMany(ctx, bson.M{"organization_id": organizationID})
+    if err != nil {
+        log.Println("Error deleting organization invitation uses:", err)
+        return err
+    }
+
+    _, err = ir.collection.DeleteMany(ctx, bson.M{"organization_id": organizationID})
+    if err != nil {
+        log.Println("Error deleting organization invitations:", err)
+        return err
+    }
+    return nil
+}
+// GetInvitationByTokenHash retrieves the invitation with the given token hash.
+func (ir *InvitationRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+    var invitation models.Invitation
+    err := ir.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&invitation)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting invitation by token:", err)
+        }
+        return nil, err
+    }
+    return &invitation, nil
+}
+
+// GetPendingInvitation retrieves an invitation of the email to the organization that was
+// neither accepted nor expired.
+func (ir *InvitationRepository) GetPendingInvitation(ctx context.Context, organizationID primitive.ObjectID, email string) (*models.Invitation, error) {
+    filter := bson.M{
+        "organization_id": organizationID,
+        "invited_email":   email,
+        "accepted_at":     bson.M{"$exists": false},
+        "revoked_at":      bson.M{"$exists": false},
+        "expires_at":      bson.M{"$gt": time.Now()},
+    }
+
+    var invitation models.Invitation
+    err := ir.collection.FindOne(ctx, filter).Decode(&invitation)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting pending invitation:", err)
+        }
+        return nil, err
+    }
+    return &invitation, nil
+}
+
+// MarkAccepted records that an invitation was accepted. It returns mongo.ErrNoDocuments when
+// the invitation was already accepted, so it can only be used once.
+func (ir *InvitationRepository) MarkAccepted(ctx context.Context, id primitive.ObjectID) error {
+    filter := bson.M{"_id": id, "accepted_at": bson.M{"$exists": false}}
+    update := bson.M{"$set": bson.M{"accepted_at": time.Now()}}
+
+    result, err := ir.collection.UpdateOne(ctx, filter, update)
+    if err != nil {
+        log.Println("Error accepting invitation:", err)
+        return err
+    }
+
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+
+    return nil
+}
+
+// EnsureIndexes creates the index the uses of invitation links are listed with.
+func (ir *InvitationRepository) EnsureIndexes(ctx context.Context) error {
+    _, err := ir.uses.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "invitation_id", Value: 1}, {Key: "used_at", Value: 1}},
+    })
+    if err != nil {
+        log.Println("Error creating invitation use indexes:", err)
+        return err
+    }
+    return nil
+}
+
+// GetOrganizationInvitation retrieves an invitation to the organization.
+func (ir *InvitationRepository) GetOrganizationInvitation(ctx context.Context, organizationID, id primitive.ObjectID) (*models.Invitation, error) {
+    var invitation models.Invitation
+    err := ir.collection.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID}).Decode(&invitation)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting organization invitation:", err)
+        }
+        return nil, err
+    }
+    return &invitation, nil
+}
+
+// GetOrganizationLinks retrieves the invitation links of an organization, including revoked
+// and expired ones, most recent first.
+func (ir *InvitationRepository) GetOrganizationLinks(ctx context.Context, organizationID primitive.ObjectID) ([]*models.Invitation, error) {
+    filter := bson.M{"organization_id": organizationID, "type": models.InvitationTypeLink}
+    cursor, err := ir.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}))
+    if err != nil {
+        log.Println("Error getting organization invitation links:", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    links := []*models.Invitation{}
+    if err := cursor.All(ctx, &links); err != nil {
+        log.Println("Error decoding organization invitation links:", err)
+        return nil, err
+    }
+    return links, nil
+}
+
+// UseLink counts a use of an invitation link and records it. It returns mongo.ErrNoDocuments
+// when the link was revoked, expired or used up in the meantime, so it is never used more than
+// its maximum.
+func (ir *InvitationRepository) UseLink(ctx context.Context, use *models.InvitationUse) error {
+    now := time.Now()
+    filter := bson.M{
+        "_id":        use.InvitationID,
+        "type":       models.InvitationTypeLink,
+        "revoked_at": bson.M{"$exists": false},
+        "expires_at": bson.M{"$gt": now},
+        "$or": bson.A{
+            bson.M{"max_uses": bson.M{"$exists": false}},
+            bson.M{"$expr": bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$use_count", 0}}, "$max_uses"}}},
+        },
+    }
+
+    result, err := ir.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"use_count": 1}})
+    if err != nil {
+        log.Println("Error using invitation link:", err)
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+
+    use.ID = primitive.NewObjectID()
+    use.UsedAt = now
+    if _, err := ir.uses.InsertOne(ctx, use); err != nil {
+        log.Println("Error inserting invitation use:", err)
+        return err
+    }
+    return nil
+}
+
+// GetInvitationUses retrieves the uses of an invitation link, oldest first.
+func (ir *InvitationRepository) GetInvitationUses(ctx context.Context, invitationID primitive.ObjectID) ([]*models.InvitationUse, error) {
+    cursor, err := ir.uses.Find(ctx, bson.M{"invitation_id": invitationID}, options.Find().SetSort(bson.M{"used_at": 1}))
+    if err != nil {
+        log.Println("Error getting invitation uses:", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    uses := []*models.InvitationUse{}
+    if err := cursor.All(ctx, &uses); err != nil {
+        log.Println("Error decoding invitation uses:", err)
+        return nil, err
+    }
+    return uses, nil
+}
+
+// RevokeInvitation prevents an invitation from being accepted any more. It returns
+// mongo.ErrNoDocuments when the organization has no such invitation or it was already revoked.
+func (ir *InvitationRepository) RevokeInvitation(ctx context.Context, organizationID, id primitive.ObjectID) error {
+    filter := bson.M{"_id": id, "organization_id": organizationID, "revoked_at": bson.M{"$exists": false}}
+    update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+    result, err := ir.collection.UpdateOne(ctx, filter, update)
+    if err != nil {
+        log.Println("Error revoking invitation:", err)
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
@@ -0,0 +1,604 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// membershipCacheTTL bounds how long a cached membership may be served after a change made
+// outside of this repository.
+const membershipCacheTTL = 5 * time.Minute
+
+// ErrNotMember is returned when a user is not a member of an organization.
+var ErrNotMember = errors.New("user is not a member of the organization")
+
+// ErrMemberExists is returned when a user is already a member of an organization.
+var ErrMemberExists = errors.New("user is already a member of the organization")
+
+// ErrLastAdmin is returned when a change would leave an organization without an active admin.
+var ErrLastAdmin = errors.New("organization must keep an admin")
+
+// ErrOwner is returned when the owner of an organization would be removed from it or demoted.
+var ErrOwner = errors.New("user owns the organization")
+
+// MemberFilter selects a page of the members of an organization, ordered by email. Pages
+// start after the member with AfterEmail and AfterID, when given.
+type MemberFilter struct {
+	OrganizationID primitive.ObjectID
+	Query          string
+	AfterEmail     string
+	AfterID        primitive.ObjectID
+	Limit          int64
+}
+
+// MembershipRepository stores the members of organizations, one document per user and
+// organization. It also reads the teams members belong to, for the access they grant.
+type MembershipRepository struct {
+	collection    *mongo.Collection
+	organizations *mongo.Collection
+	teams         *mongo.Collection
+	teamMembers   *mongo.Collection
+	cache         cache.Cache
+}
+
+func NewMembershipRepository(database *mongo.Database, cache cache.Cache) *MembershipRepository {
+	return &MembershipRepository{
+		collection:    database.Collection("memberships"),
+		organizations: database.Collection("organizations"),
+		teams:         database.Collection("teams"),
+		teamMembers:   database.Collection("team_members"),
+		cache:         cache,
+	}
+}
+
+// EnsureIndexes creates the indexes memberships are looked up with, by organization and by
+// user. The first one also keeps a user from joining an organization twice.
+func (mr *MembershipRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := mr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "organization_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "email", Value: 1}, {Key: "_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Println("Error creating membership indexes:", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddMember adds a user to an organization. It fails with ErrMemberExists when the user
+// already is a member, and with mongo.ErrNoDocuments when the organization doesn't exist or
+// was deleted.
+func (mr *MembershipRepository) AddMember(ctx context.Context, orgID primitive.ObjectID, member models.OrganizationMember) error {
+	if err := mr.lockOrganization(ctx, orgID); err != nil {
+		return err
+	}
+
+	member.ID = primitive.NewObjectID()
+	member.OrganizationID = orgID
+	member.CreatedAt = time.Now()
+	member.UpdatedAt = member.CreatedAt
+
+	if _, err := mr.collection.InsertOne(ctx, member); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrMemberExists
+		}
+		log.Println("Error adding member to organization:", err)
+		return err
+	}
+	mr.invalidate(ctx, orgID, member.UserID)
+
+	return nil
+}
+
+// ImportMembers adds members which may already exist, such as the members of an
+// organization being migrated again after an interruption. Existing members are left as is.
+func (mr *MembershipRepository) ImportMembers(ctx context.Context, orgID primitive.ObjectID, members []models.OrganizationMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, len(members))
+	for i, member := range members {
+		member.OrganizationID = orgID
+		if member.CreatedAt.IsZero() {
+			member.CreatedAt = time.Now()
+		}
+		member.UpdatedAt = time.Now()
+
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"organization_id": orgID, "user_id": member.UserID}).
+			SetUpdate(bson.M{"$setOnInsert": member}).
+			SetUpsert(true)
+	}
+
+	if _, err := mr.collection.BulkWrite(ctx, writes); err != nil {
+		log.Println("Error importing organization members:", err)
+		return err
+	}
+	for _, member := range members {
+		mr.invalidate(ctx, orgID, member.UserID)
+	}
+
+	return nil
+}
+
+// GetMember returns the membership of a user, whether or not it is suspended, or
+// ErrNotMember when the user isn't a member of the organization.
+func (mr *MembershipRepository) GetMember(ctx context.Context, orgID, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+
+	// Transactions read their own changes, which must not be cached before they are committed
+	if !inTransaction(ctx) {
+		found, err := mr.cache.Get(ctx, membershipCacheKey(orgID, userID), &member)
+		if err != nil {
+			log.Println("Error getting membership from cache:", err)
+		}
+		if found {
+			return &member, nil
+		}
+	}
+
+	err := mr.collection.FindOne(ctx, bson.M{"organization_id": orgID, "user_id": userID}).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		log.Println("Error getting membership:", err)
+		return nil, err
+	}
+
+	if !inTransaction(ctx) {
+		if err := mr.cache.Set(ctx, membershipCacheKey(orgID, userID), &member, membershipCacheTTL); err != nil {
+			log.Println("Error caching membership:", err)
+		}
+	}
+
+	return &member, nil
+}
+
+// GetMemberByEmail returns the member with the given email, or ErrNotMember when the
+// organization has no such member.
+func (mr *MembershipRepository) GetMemberByEmail(ctx context.Context, orgID primitive.ObjectID, email string) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+
+	err := mr.collection.FindOne(ctx, bson.M{"organization_id": orgID, "email": email}).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		log.Println("Error getting membership by email:", err)
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetMembers returns a page of the members of an organization ordered by email, optionally
+// only those whose name or email contains the query. A zero limit returns every member.
+func (mr *MembershipRepository) GetMembers(ctx context.Context, filter MemberFilter) ([]*models.OrganizationMember, error) {
+	members := []*models.OrganizationMember{}
+
+	query := bson.M{"organization_id": filter.OrganizationID}
+	conditions := bson.A{}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"name": pattern},
+			bson.M{"email": pattern},
+		}})
+	}
+
+	// Members may share an email, so their ID breaks ties in the order and the page cursor
+	if !filter.AfterID.IsZero() {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"email": bson.M{"$gt": filter.AfterEmail}},
+			bson.M{"email": filter.AfterEmail, "_id": bson.M{"$gt": filter.AfterID}},
+		}})
+	}
+	if len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	sort := bson.D{{Key: "email", Value: 1}, {Key: "_id", Value: 1}}
+	opts := options.Find().SetSort(sort).SetLimit(filter.Limit)
+	cursor, err := mr.collection.Find(ctx, query, opts)
+	if err != nil {
+		log.Println("Error retrieving organization members:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		log.Println("Error decoding organization members:", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetMembersByUserIDs returns the memberships of the given users in an organization, leaving
+// out users who aren't members.
+func (mr *MembershipRepository) GetMembersByUserIDs(ctx context.Context, orgID primitive.ObjectID, userIDs []primitive.ObjectID) ([]*models.OrganizationMember, error) {
+	members := []*models.OrganizationMember{}
+
+	cursor, err := mr.collection.Find(ctx, bson.M{"organization_id": orgID, "user_id": bson.M{"$in": userIDs}}, options.Find().SetSort(bson.M{"email": 1}))
+	if err != nil {
+		log.Println("Error retrieving organization members by user IDs:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		log.Println("Error decoding organization members:", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetUserMemberships returns the memberships of a user in every organization.
+func (mr *MembershipRepository) GetUserMemberships(ctx context.Context, userID primitive.ObjectID) ([]*models.OrganizationMember, error) {
+	members := []*models.OrganizationMember{}
+
+	cursor, err := mr.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		log.Println("Error retrieving user memberships:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		log.Println("Error decoding user memberships:", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// UpdateMember updates the name, external ID and suspension of a member. It fails with ErrOwner
+// rather than suspend the owner of the organization, and with ErrLastAdmin rather than suspend
+// its last active admin. It must run in a transaction for the checks to hold against concurrent
+// changes.
+func (mr *MembershipRepository) UpdateMember(ctx context.Context, orgID primitive.ObjectID, member models.OrganizationMember) error {
+	if member.Suspended {
+		if err := mr.lockOrganization(ctx, orgID); err != nil {
+			return err
+		}
+		if err := mr.checkNotOwner(ctx, orgID, member.UserID); err != nil {
+			return err
+		}
+		if err := mr.checkAdminLeft(ctx, orgID, member.UserID); err != nil {
+			return err
+		}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":        member.Name,
+		"external_id": member.ExternalID,
+		"suspended":   member.Suspended,
+		"updated_at":  time.Now(),
+	}}
+
+	result, err := mr.collection.UpdateOne(ctx, bson.M{"organization_id": orgID, "user_id": member.UserID}, update)
+	if err != nil {
+		log.Println("Error updating organization member:", err)
+		return err
+	}
+	mr.invalidate(ctx, orgID, member.UserID)
+
+	if result.MatchedCount == 0 {
+		return ErrNotMember
+	}
+
+	return nil
+}
+
+// SetMemberAccessLevel changes the access level of a member. It fails with ErrLastAdmin when
+// the member is the last active admin and would no longer be one, and with ErrOwner when they
+// own the organization. It must run in a transaction for the checks to hold against concurrent
+// changes.
+func (mr *MembershipRepository) SetMemberAccessLevel(ctx context.Context, orgID, userID primitive.ObjectID, accessLevel string) error {
+	if err := mr.lockOrganization(ctx, orgID); err != nil {
+		return err
+	}
+	if accessLevel != models.AccessLevelAdmin {
+		if err := mr.checkNotOwner(ctx, orgID, userID); err != nil {
+			return err
+		}
+		if err := mr.checkAdminLeft(ctx, orgID, userID); err != nil {
+			return err
+		}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"access_level": accessLevel,
+		"updated_at":   time.Now(),
+	}}
+
+	result, err := mr.collection.UpdateOne(ctx, bson.M{"organization_id": orgID, "user_id": userID}, update)
+	if err != nil {
+		log.Println("Error changing member access level:", err)
+		return err
+	}
+	mr.invalidate(ctx, orgID, userID)
+
+	if result.MatchedCount == 0 {
+		return ErrNotMember
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from an organization and from its teams.
+func (mr *MembershipRepository) RemoveMember(ctx context.Context, orgID, userID primitive.ObjectID) error {
+	result, err := mr.collection.DeleteOne(ctx, bson.M{"organization_id": orgID, "user_id": userID})
+	if err != nil {
+		log.Println("Error removing member from organization:", err)
+		return err
+	}
+	mr.invalidate(ctx, orgID, userID)
+
+	if result.DeletedCount == 0 {
+		return ErrNotMember
+	}
+
+	if _, err := mr.teamMembers.DeleteMany(ctx, bson.M{"organization_id": orgID, "user_id": userID}); err != nil {
+		log.Println("Error removing member from teams:", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetTeamAccessLevel returns the highest access level granted to a user by the teams they
+// belong to in an organization, directly or through a child team, or an empty string when
+// their teams grant none.
+func (mr *MembershipRepository) GetTeamAccessLevel(ctx context.Context, orgID, userID primitive.ObjectID) (string, error) {
+	var accessLevel string
+
+	// Transactions read their own changes, which must not be cached before they are committed
+	if !inTransaction(ctx) {
+		found, err := mr.cache.Get(ctx, teamAccessCacheKey(orgID, userID), &accessLevel)
+		if err != nil {
+			log.Println("Error getting team access level from cache:", err)
+		}
+		if found {
+			return accessLevel, nil
+		}
+	}
+
+	accessLevel, err := mr.teamAccessLevel(ctx, orgID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !inTransaction(ctx) {
+		if err := mr.cache.Set(ctx, teamAccessCacheKey(orgID, userID), accessLevel, membershipCacheTTL); err != nil {
+			log.Println("Error caching team access level:", err)
+		}
+	}
+
+	return accessLevel, nil
+}
+
+// teamAccessLevel is GetTeamAccessLevel read from the database.
+func (mr *MembershipRepository) teamAccessLevel(ctx context.Context, orgID, userID primitive.ObjectID) (string, error) {
+	teamMemberships, err := findUserTeamMemberships(ctx, mr.teamMembers, orgID, userID)
+	if err != nil || len(teamMemberships) == 0 {
+		return "", err
+	}
+
+	teams, err := findTeams(ctx, mr.teams, orgID)
+	if err != nil {
+		return "", err
+	}
+	accessLevels := make(map[primitive.ObjectID]string, len(teams))
+	for _, team := range teams {
+		accessLevels[team.ID] = team.AccessLevel
+	}
+
+	accessLevel := ""
+	for _, teamMembership := range teamMemberships {
+		for _, teamID := range models.TeamAncestors(teams, teamMembership.TeamID) {
+			switch accessLevels[teamID] {
+			case models.AccessLevelAdmin:
+				return models.AccessLevelAdmin, nil
+			case models.AccessLevelMember:
+				accessLevel = models.AccessLevelMember
+			}
+		}
+	}
+
+	return accessLevel, nil
+}
+
+// GetAccessLevel returns the access level of a user in an organization, or an empty string
+// when they have no access to it. It is the highest of their own access level, unless their
+// membership is suspended, the access level granted by their teams, and the access level
+// inherited as an admin of the parent organization.
+func (mr *MembershipRepository) GetAccessLevel(ctx context.Context, organization *models.Organization, userID primitive.ObjectID) (string, error) {
+	return mr.getAccessLevel(ctx, organization, userID, 1)
+}
+
+// getAccessLevel is GetAccessLevel for an organization at the given depth below the one access
+// is checked to, which bounds how far parents are followed.
+func (mr *MembershipRepository) getAccessLevel(ctx context.Context, organization *models.Organization, userID primitive.ObjectID, depth int) (string, error) {
+	accessLevel := ""
+	member, err := mr.GetMember(ctx, organization.ID, userID)
+	if err != nil && !errors.Is(err, ErrNotMember) {
+		return "", err
+	}
+	if member != nil {
+		if member.Suspended {
+			return "", nil
+		}
+		accessLevel = member.AccessLevel
+	}
+
+	// Teams of the member may grant them more access than their own
+	if accessLevel == models.AccessLevelMember {
+		teamAccessLevel, err := mr.GetTeamAccessLevel(ctx, organization.ID, userID)
+		if err != nil {
+			return "", err
+		}
+		if teamAccessLevel == models.AccessLevelAdmin {
+			return teamAccessLevel, nil
+		}
+	}
+
+	// Admins of the parent organization are granted its parent admin access
+	inherited := organization.ParentAdminAccess
+	if accessLevel == models.AccessLevelAdmin || organization.ParentID.IsZero() || depth >= models.MaxOrganizationDepth {
+		return accessLevel, nil
+	}
+	if inherited != models.AccessLevelAdmin && (inherited != models.AccessLevelMember || accessLevel != "") {
+		return accessLevel, nil
+	}
+
+	var parent models.Organization
+	err = mr.organizations.FindOne(ctx, notDeleted(bson.M{"_id": organization.ParentID})).Decode(&parent)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return accessLevel, nil
+	}
+	if err != nil {
+		log.Println("Error getting parent organization:", err)
+		return "", err
+	}
+	parentAccessLevel, err := mr.getAccessLevel(ctx, &parent, userID, depth+1)
+	if err != nil {
+		return "", err
+	}
+	if parentAccessLevel == models.AccessLevelAdmin {
+		return inherited, nil
+	}
+
+	return accessLevel, nil
+}
+
+// RemoveMemberKeepingAdmin is RemoveMember for changes made by members themselves, which fails
+// with ErrLastAdmin rather than leave the organization without an active admin, and with
+// ErrOwner rather than remove its owner. It must run in a transaction for the checks to hold
+// against concurrent changes.
+func (mr *MembershipRepository) RemoveMemberKeepingAdmin(ctx context.Context, orgID, userID primitive.ObjectID) error {
+	if err := mr.lockOrganization(ctx, orgID); err != nil {
+		return err
+	}
+	if err := mr.checkNotOwner(ctx, orgID, userID); err != nil {
+		return err
+	}
+	if err := mr.checkAdminLeft(ctx, orgID, userID); err != nil {
+		return err
+	}
+
+	return mr.RemoveMember(ctx, orgID, userID)
+}
+
+// DeleteOrganizationMemberships deletes every membership of an organization.
+func (mr *MembershipRepository) DeleteOrganizationMemberships(ctx context.Context, orgID primitive.ObjectID) error {
+	// Cached memberships expire on their own, and can't grant access to a purged organization
+	if _, err := mr.collection.DeleteMany(ctx, bson.M{"organization_id": orgID}); err != nil {
+		log.Println("Error deleting organization memberships:", err)
+		return err
+	}
+
+	return nil
+}
+
+// checkAdminLeft returns ErrNotMember when the user isn't a member, and ErrLastAdmin when
+// they are the last active admin of the organization.
+func (mr *MembershipRepository) checkAdminLeft(ctx context.Context, orgID, userID primitive.ObjectID) error {
+	member, err := mr.GetMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member.AccessLevel != models.AccessLevelAdmin || member.Suspended {
+		return nil
+	}
+
+	admins, err := mr.collection.CountDocuments(ctx, bson.M{
+		"organization_id": orgID,
+		"user_id":         bson.M{"$ne": userID},
+		"access_level":    models.AccessLevelAdmin,
+		"suspended":       bson.M{"$ne": true},
+	})
+	if err != nil {
+		log.Println("Error counting organization admins:", err)
+		return err
+	}
+	if admins == 0 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// checkNotOwner returns ErrOwner when the user owns the organization.
+func (mr *MembershipRepository) checkNotOwner(ctx context.Context, orgID, userID primitive.ObjectID) error {
+	owned, err := mr.organizations.CountDocuments(ctx, bson.M{"_id": orgID, "owner_id": userID})
+	if err != nil {
+		log.Println("Error checking organization owner:", err)
+		return err
+	}
+	if owned > 0 {
+		return ErrOwner
+	}
+
+	return nil
+}
+
+// lockOrganization writes to the organization, so that transactions changing its members
+// concurrently conflict rather than each miss the other's changes. It fails with
+// mongo.ErrNoDocuments when the organization doesn't exist or was deleted.
+func (mr *MembershipRepository) lockOrganization(ctx context.Context, orgID primitive.ObjectID) error {
+	update := bson.M{"$inc": bson.M{"membership_version": 1}}
+
+	result, err := mr.organizations.UpdateOne(ctx, notDeleted(bson.M{"_id": orgID}), update)
+	if err != nil {
+		log.Println("Error locking organization:", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// invalidate drops the cached copy of a membership after it changed.
+func (mr *MembershipRepository) invalidate(ctx context.Context, orgID, userID primitive.ObjectID) {
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := mr.cache.Delete(ctx, membershipCacheKey(orgID, userID), teamAccessCacheKey(orgID, userID)); err != nil {
+			log.Println("Error invalidating cached membership:", err)
+		}
+	})
+}
+
+func membershipCacheKey(orgID, userID primitive.ObjectID) string {
+	return "membership:" + orgID.Hex() + ":" + userID.Hex()
+}
+
+// teamAccessCacheKey is the key the access level granted to a user by their teams is cached
+// under, next to their membership.
+func teamAccessCacheKey(orgID, userID primitive.ObjectID) string {
+	return membershipCacheKey(orgID, userID) + ":teams"
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+type OAuthClientRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOAuthClientRepository(database *mongo.Database) *OAuthClientRepository {
+	return &OAuthClientRepository{
+		collection: database.Collection("oauth_clients"),
+	}
+}
+
+func (cr *OAuthClientRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	client.CreatedAt = time.Now()
+
+	_, err := cr.collection.InsertOne(ctx, client)
+	if err != nil {
+		log.Println("Error inserting OAuth client:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (cr *OAuthClientRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := cr.collection.FindOne(ctx, bson.M{"client_id": clientID}).Decode(&client)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting OAuth client:", err)
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetOrganizationClients retrieves the clients registered by an organization.
+func (cr *OAuthClientRepository) GetOrganizationClients(ctx context.Context, organizationID primitive.ObjectID) ([]*models.OAuthClient, error) {
+	clients := []*models.OAuthClient{}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := cr.collection.Find(ctx, bson.M{"organization_id": organizationID}, opts)
+	if err != nil {
+		log.Println("Error retrieving OAuth clients:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &clients); err != nil {
+		log.Println("Error decoding OAuth clients:", err)
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// DeleteOrganizationClient deletes a client registered by an organization.
+func (cr *OAuthClientRepository) DeleteOrganizationClient(ctx context.Context, clientID string, organizationID primitive.ObjectID) error {
+	result, err := cr.collection.DeleteOne(ctx, bson.M{"client_id": clientID, "organization_id": organizationID})
+	if err != nil {
+		log.Println("Error deleting OAuth client:", err)
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// DeleteOrganizationClients deletes every client registered by an organization.
+func (cr *OAuthClientRepository) DeleteOrganizationClients(ctx context.Context, organizationID primitive.ObjectID) error {
+	_, err := cr.collection.DeleteMany(ctx, bson.M{"organization_id": organizationID})
+	if err != nil {
+		log.Println("Error deleting organization OAuth clients:", err)
+		return err
+	}
+	return nil
+}
@@ -9,23 +9,56 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 )
 
+// organizationCacheTTL bounds how long a cached organization may be served after a change
+// made outside of this repository.
+const organizationCacheTTL = 5 * time.Minute
+
+// ErrVersionMismatch is returned when an organization changed since the version an update
+// was based on.
+var ErrVersionMismatch = errors.New("organization was modified")
+
+// ErrDomainExists is returned when an organization already claimed a domain.
+var ErrDomainExists = errors.New("domain already added to the organization")
+
+// ErrOrganizationHasChildren is returned when deleting an organization other organizations are
+// nested in.
+var ErrOrganizationHasChildren = errors.New("organization has child organizations")
+
 type OrganizationRepository struct {
     collection *mongo.Collection
+    cache      cache.Cache
 }
 
-func NewOrganizationRepository(database *mongo.Database) *OrganizationRepository {
+func NewOrganizationRepository(database *mongo.Database, cache cache.Cache) *OrganizationRepository {
     return &OrganizationRepository{
         collection: database.Collection("organizations"),
+        cache:      cache,
     }
 }
 
+// EnsureIndexes creates the indexes organizations are looked up with by parent and by owner.
+func (or *OrganizationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := or.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+		{Keys: bson.D{{Key: "owner_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Error creating organization indexes:", err)
+		return err
+	}
+	return nil
+}
+
 func (or *OrganizationRepository) CreateOrganization(ctx context.Context, org *models.Organization) (primitive.ObjectID, error) {
 	org.CreatedAt = time.Now()
 	org.UpdatedAt = time.Now()
+	org.Version = 1
 
 	result, err := or.collection.InsertOne(ctx, org)
 	if err != nil {
@@ -45,49 +78,216 @@ func (or *OrganizationRepository) CreateOrganization(ctx context.Context, org *m
 
 func (or *OrganizationRepository) GetOrganizationByID(ctx context.Context, id primitive.ObjectID) (*models.Organization, error) {
     var org models.Organization
-    err := or.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&org)
+
+    // Transactions read their own changes, which must not be cached before they are committed
+    if inTransaction(ctx) {
+        err := or.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&org)
+        if err != nil {
+            log.Println("Error getting organization by ID:", err)
+            return nil, err
+        }
+        return &org, nil
+    }
+
+    // Serve the organization from the cache when possible
+    found, err := or.cache.Get(ctx, organizationCacheKey(id), &org)
+    if err != nil {
+        log.Println("Error getting organization from cache:", err)
+    }
+    if found {
+        return &org, nil
+    }
+
+    err = or.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&org)
     if err != nil {
         log.Println("Error getting organization by ID:", err)
         return nil, err
     }
+
+    if err := or.cache.Set(ctx, organizationCacheKey(id), &org, organizationCacheTTL); err != nil {
+        log.Println("Error caching organization:", err)
+    }
+
     return &org, nil
 }
 
-func (or *OrganizationRepository) UpdateOrganization(ctx context.Context, id primitive.ObjectID, updatedOrg *models.Organization) (primitive.ObjectID, error) {
-	updatedOrg.UpdatedAt = time.Now()
+// UpdateOrganization sets the name and description of an organization, unless they are empty.
+// When expectedVersion isn't nil, the organization is only updated if it is still at that
+// version, and ErrVersionMismatch is returned otherwise.
+func (or *OrganizationRepository) UpdateOrganization(ctx context.Context, id primitive.ObjectID, name, description string, expectedVersion *int64) (primitive.ObjectID, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if name != "" {
+		set["name"] = name
+	}
+	if description != "" {
+		set["description"] = description
+	}
+
+	filter := notDeleted(bson.M{"_id": id})
+	if expectedVersion != nil {
+		filter["version"] = versionFilter(*expectedVersion)
+	}
 
-	result, err := or.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": updatedOrg})
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(bson.M{"$set": set}))
 	if err != nil {
 		log.Println("Error updating organization:", err)
 		return primitive.NilObjectID, err
 	}
+	or.invalidate(ctx, id)
 
 	// Check if the document was found and updated
-	if result.ModifiedCount == 0 {
+	if result.MatchedCount == 0 {
+		if expectedVersion != nil {
+			return primitive.NilObjectID, ErrVersionMismatch
+		}
 		return primitive.NilObjectID, mongo.ErrNoDocuments
 	}
 
 	return id, nil
 }
 
-func (or *OrganizationRepository) DeleteOrganization(ctx context.Context, id primitive.ObjectID) error {
-    _, err := or.collection.DeleteOne(ctx, bson.M{"_id": id})
+// PatchOrganization sets the given attributes of an organization, removing those whose value is
+// nil, provided it is still at expectedVersion. It returns ErrVersionMismatch otherwise.
+func (or *OrganizationRepository) PatchOrganization(ctx context.Context, id primitive.ObjectID, changes map[string]interface{}, expectedVersion int64) error {
+    set := bson.M{"updated_at": time.Now()}
+    unset := bson.M{}
+    for field, value := range changes {
+        if value == nil {
+            unset[field] = ""
+        } else {
+            set[field] = value
+        }
+    }
+
+    update := bson.M{"$set": set}
+    if len(unset) > 0 {
+        update["$unset"] = unset
+    }
+
+    filter := notDeleted(bson.M{"_id": id, "version": versionFilter(expectedVersion)})
+    result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+    if err != nil {
+        log.Println("Error patching organization:", err)
+        return err
+    }
+    or.invalidate(ctx, id)
+
+    if result.MatchedCount == 0 {
+        return ErrVersionMismatch
+    }
+
+    return nil
+}
+
+// DeleteOrganization marks an organization deleted, hiding it from every other query until it
+// is restored or purged. It returns mongo.ErrNoDocuments when the organization doesn't exist or
+// was already deleted, and ErrOrganizationHasChildren when organizations are nested in it, so it
+// must run in a transaction for the check to hold.
+func (or *OrganizationRepository) DeleteOrganization(ctx context.Context, id primitive.ObjectID, deletedAt time.Time) error {
+    children, err := or.collection.CountDocuments(ctx, notDeleted(bson.M{"parent_id": id}))
+    if err != nil {
+        log.Println("Error counting child organizations:", err)
+        return err
+    }
+    if children > 0 {
+        return ErrOrganizationHasChildren
+    }
+
+    update := bson.M{"$set": bson.M{"deleted_at": deletedAt, "updated_at": deletedAt}}
+
+    result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
     if err != nil {
         log.Println("Error deleting organization:", err)
         return err
     }
+    or.invalidate(ctx, id)
+
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+
     return nil
 }
 
-func (or *OrganizationRepository) AddMember(ctx context.Context, orgID primitive.ObjectID, member models.OrganizationMember) error {
-	filter := bson.M{"_id": orgID}
-	update := bson.M{"$push": bson.M{"organization_members": member}}
+// GetDeletedOrganizationByID retrieves an organization which was deleted but not purged yet.
+func (or *OrganizationRepository) GetDeletedOrganizationByID(ctx context.Context, id primitive.ObjectID) (*models.Organization, error) {
+    var org models.Organization
+    err := or.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}).Decode(&org)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting deleted organization:", err)
+        }
+        return nil, err
+    }
+
+    return &org, nil
+}
 
-	_, err := or.collection.UpdateOne(ctx, filter, update)
+// RestoreOrganization undoes the deletion of an organization deleted after the given time. It
+// returns mongo.ErrNoDocuments when there is no such organization.
+func (or *OrganizationRepository) RestoreOrganization(ctx context.Context, id primitive.ObjectID, deletedAfter time.Time) error {
+    filter := bson.M{"_id": id, "deleted_at": bson.M{"$gt": deletedAfter}}
+    update := bson.M{"$unset": bson.M{"deleted_at": ""}, "$set": bson.M{"updated_at": time.Now()}}
+
+    result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+    if err != nil {
+        log.Println("Error restoring organization:", err)
+        return err
+    }
+    or.invalidate(ctx, id)
+
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+
+    return nil
+}
+
+// GetOrganizationsDeletedBefore retrieves the organizations deleted before the given time,
+// which are due to be purged.
+func (or *OrganizationRepository) GetOrganizationsDeletedBefore(ctx context.Context, before time.Time) ([]*models.Organization, error) {
+    organizations := []*models.Organization{}
+
+    cursor, err := or.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lte": before}})
+    if err != nil {
+        log.Println("Error retrieving deleted organizations:", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    if err := cursor.All(ctx, &organizations); err != nil {
+        log.Println("Error decoding deleted organizations:", err)
+        return nil, err
+    }
+
+    return organizations, nil
+}
+
+// PurgeOrganization permanently deletes an organization which was deleted before.
+func (or *OrganizationRepository) PurgeOrganization(ctx context.Context, id primitive.ObjectID) error {
+    _, err := or.collection.DeleteOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+    if err != nil {
+        log.Println("Error purging organization:", err)
+        return err
+    }
+    or.invalidate(ctx, id)
+    return nil
+}
+
+// SetRequireMFA turns the requirement for members to use two-factor authentication on or off.
+func (or *OrganizationRepository) SetRequireMFA(ctx context.Context, id primitive.ObjectID, requireMFA bool) error {
+	update := bson.M{"$set": bson.M{"require_mfa": requireMFA, "updated_at": time.Now()}}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
 	if err != nil {
-		log.Println("Error adding member to organization:", err)
+		log.Println("Error updating organization MFA policy:", err)
 		return err
 	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 
 	return nil
 }
@@ -97,7 +297,7 @@ func (or *OrganizationRepository) GetAllOrganizations(ctx context.Context) ([]*m
     var organizations []*models.Organization
 
     // Execute the find operation to retrieve all organizations
-    cursor, err := or.collection.Find(ctx, bson.M{})
+    cursor, err := or.collection.Find(ctx, notDeleted(bson.M{}))
     if err != nil {
         log.Println("Error retrieving organizations:", err)
         return nil, err
@@ -122,63 +322,467 @@ func (or *OrganizationRepository) GetAllOrganizations(ctx context.Context) ([]*m
     return organizations, nil
 }
 
-func (or *OrganizationRepository) IsUserMemberOfOrganization(ctx context.Context, userID string, organizationID string) bool {
-    // Convert organizationID to ObjectID
-    oid, err := primitive.ObjectIDFromHex(organizationID)
-    if err != nil {
-        // Handle error
-        return false
-    }
+// GetOrganizationsByIDs returns the organizations with the given IDs which weren't deleted.
+func (or *OrganizationRepository) GetOrganizationsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Organization, error) {
+	organizations := []*models.Organization{}
+	if len(ids) == 0 {
+		return organizations, nil
+	}
 
-    // Query the database to check if the user is a member of the organization
-    filter := bson.M{
-        "_id":              oid,
-    }
+	cursor, err := or.collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		log.Println("Error retrieving organizations by ID:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-    // Execute the query
-    var result models.Organization
-    err = or.collection.FindOne(ctx, filter).Decode(&result)
-    if err != nil {
-        
-        return false
-    }
+	if err := cursor.All(ctx, &organizations); err != nil {
+		log.Println("Error decoding organizations:", err)
+		return nil, err
+	}
 
-    // Check if the user is a member of the organization
-    for _, member := range result.Members {
-        user, err := or.GetUserByEmail(ctx, member.Email)
-        if err != nil {
-            return false
-        }
+	return organizations, nil
+}
 
-        if user.ID.String() == userID {
-            return true
-        }
-    }
+// GetOrganizationsWithEmbeddedMembers returns the organizations, deleted or not, which still
+// embed members from before memberships had their own collection.
+func (or *OrganizationRepository) GetOrganizationsWithEmbeddedMembers(ctx context.Context) ([]*models.Organization, error) {
+	organizations := []*models.Organization{}
 
-    return false
+	cursor, err := or.collection.Find(ctx, bson.M{"members.0": bson.M{"$exists": true}})
+	if err != nil {
+		log.Println("Error retrieving organizations with embedded members:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &organizations); err != nil {
+		log.Println("Error decoding organizations:", err)
+		return nil, err
+	}
+
+	return organizations, nil
 }
 
-func (or *OrganizationRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-    pipeline := bson.A{
-        bson.M{"$match": bson.M{"email": email}},
-    }
+// RemoveEmbeddedMembers removes the embedded members with the given emails once they were
+// moved to the memberships collection.
+func (or *OrganizationRepository) RemoveEmbeddedMembers(ctx context.Context, id primitive.ObjectID, emails []string) error {
+	update := bson.M{"$pull": bson.M{"members": bson.M{"email": bson.M{"$in": emails}}}}
 
-    // Perform aggregation
-    cursor, err := or.collection.Aggregate(ctx, pipeline)
-    if err != nil {
-        return nil, err
-    }
-    defer cursor.Close(ctx)
+	if _, err := or.collection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Println("Error removing embedded members:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
 
-    // Decode the result
-    var user models.User
-    if cursor.Next(ctx) {
-        if err := cursor.Decode(&user); err != nil {
-            return nil, err
+	return nil
+}
+
+// AddDomain claims an unverified email domain for an organization.
+func (or *OrganizationRepository) AddDomain(ctx context.Context, id primitive.ObjectID, domain models.OrganizationDomain) error {
+	// Don't add the same domain twice
+	filter := notDeleted(bson.M{"_id": id, "domains.domain": bson.M{"$ne": domain.Domain}})
+	update := bson.M{"$push": bson.M{"domains": domain}, "$set": bson.M{"updated_at": time.Now()}}
+
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+	if err != nil {
+		log.Println("Error adding domain to organization:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return ErrDomainExists
+	}
+
+	return nil
+}
+
+// MarkDomainVerified records that an organization proved ownership of one of its domains.
+func (or *OrganizationRepository) MarkDomainVerified(ctx context.Context, id primitive.ObjectID, domain string) error {
+	filter := notDeleted(bson.M{"_id": id, "domains.domain": domain})
+	update := bson.M{"$set": bson.M{
+		"domains.$.verified":    true,
+		"domains.$.verified_at": time.Now(),
+		"updated_at":            time.Now(),
+	}}
+
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+	if err != nil {
+		log.Println("Error verifying organization domain:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// SetDomainJoinPolicy sets the policy for users with an email at a domain of an organization,
+// and the access level they join with.
+func (or *OrganizationRepository) SetDomainJoinPolicy(ctx context.Context, id primitive.ObjectID, domain, policy, accessLevel string) error {
+	filter := notDeleted(bson.M{"_id": id, "domains.domain": domain})
+	update := bson.M{"$set": bson.M{
+		"domains.$.join_policy":       policy,
+		"domains.$.join_access_level": accessLevel,
+		"updated_at":                  time.Now(),
+	}}
+
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+	if err != nil {
+		log.Println("Error setting organization domain join policy:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RemoveDomain removes a domain from an organization.
+func (or *OrganizationRepository) RemoveDomain(ctx context.Context, id primitive.ObjectID, domain string) error {
+	update := bson.M{
+		"$pull": bson.M{"domains": bson.M{"domain": domain}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id, "domains.domain": domain}), versioned(update))
+	if err != nil {
+		log.Println("Error removing organization domain:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetOrganizationByVerifiedDomain retrieves the organization that verified ownership of an
+// email domain.
+func (or *OrganizationRepository) GetOrganizationByVerifiedDomain(ctx context.Context, domain string) (*models.Organization, error) {
+	filter := notDeleted(bson.M{
+		"domains": bson.M{"$elemMatch": bson.M{"domain": domain, "verified": true}},
+	})
+
+	var org models.Organization
+	err := or.collection.FindOne(ctx, filter).Decode(&org)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting organization by domain:", err)
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// SetSAMLConfig stores the SAML identity provider of an organization, or removes it when
+// config is nil.
+func (or *OrganizationRepository) SetSAMLConfig(ctx context.Context, id primitive.ObjectID, config *models.SAMLConfig) error {
+	update := bson.M{"$set": bson.M{"saml": config, "updated_at": time.Now()}}
+	if config == nil {
+		update = bson.M{"$unset": bson.M{"saml": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
+	if err != nil {
+		log.Println("Error updating organization SAML configuration:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// SetSCIMTokenHash stores the hash of the token the organization's identity provider uses
+// for SCIM provisioning, or revokes it when tokenHash is empty.
+func (or *OrganizationRepository) SetSCIMTokenHash(ctx context.Context, id primitive.ObjectID, tokenHash string) error {
+	update := bson.M{"$set": bson.M{"scim_token_hash": tokenHash, "updated_at": time.Now()}}
+	if tokenHash == "" {
+		update = bson.M{"$unset": bson.M{"scim_token_hash": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
+	if err != nil {
+		log.Println("Error updating organization SCIM token:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetOrganizationBySCIMTokenHash retrieves the organization a SCIM token was issued for.
+func (or *OrganizationRepository) GetOrganizationBySCIMTokenHash(ctx context.Context, tokenHash string) (*models.Organization, error) {
+	var org models.Organization
+	err := or.collection.FindOne(ctx, notDeleted(bson.M{"scim_token_hash": tokenHash})).Decode(&org)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting organization by SCIM token:", err)
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// SetParent nests an organization in a parent, whose admins are granted the given access level
+// in it, or makes it a top organization when the parent is nil. Cycles must be prevented by the
+// caller, with LockAncestors in the same transaction. The cached organization is only dropped
+// once the transaction is committed.
+func (or *OrganizationRepository) SetParent(ctx context.Context, id, parentID primitive.ObjectID, parentAdminAccess string) error {
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if parentID.IsZero() {
+		unset["parent_id"] = ""
+	} else {
+		set["parent_id"] = parentID
+	}
+	if parentID.IsZero() || parentAdminAccess == "" {
+		unset["parent_admin_access"] = ""
+	} else {
+		set["parent_admin_access"] = parentAdminAccess
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
+	if err != nil {
+		log.Println("Error setting organization parent:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// LockAncestors returns the organization with the given ID and every organization it is nested
+// in, from it up. It writes to each of them, so that transactions moving any of them
+// concurrently conflict rather than create a cycle together. It returns mongo.ErrNoDocuments
+// when the organization doesn't exist or was deleted, and stops at a parent which was.
+func (or *OrganizationRepository) LockAncestors(ctx context.Context, id primitive.ObjectID) ([]*models.Organization, error) {
+	ancestors := []*models.Organization{}
+	update := bson.M{"$inc": bson.M{"hierarchy_version": 1}}
+
+	seen := map[primitive.ObjectID]bool{}
+	for current := id; !current.IsZero() && !seen[current]; {
+		seen[current] = true
+
+		var org models.Organization
+		err := or.collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": current}), update).Decode(&org)
+		if errors.Is(err, mongo.ErrNoDocuments) && len(ancestors) > 0 {
+			break
+		}
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Error locking organization:", err)
+			}
+			return nil, err
+		}
+
+		ancestors = append(ancestors, &org)
+		current = org.ParentID
+	}
+
+	return ancestors, nil
+}
+
+// GetChildOrganizations returns the organizations nested in any of the given organizations,
+// ordered by name, except those which were deleted.
+func (or *OrganizationRepository) GetChildOrganizations(ctx context.Context, parentIDs []primitive.ObjectID) ([]*models.Organization, error) {
+	organizations := []*models.Organization{}
+	if len(parentIDs) == 0 {
+		return organizations, nil
+	}
+
+	filter := notDeleted(bson.M{"parent_id": bson.M{"$in": parentIDs}})
+	cursor, err := or.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		log.Println("Error retrieving child organizations:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &organizations); err != nil {
+		log.Println("Error decoding child organizations:", err)
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// DetachChildren makes the organizations nested in an organization, deleted or not, top
+// organizations. It is used once the organization is purged. Like SetParent, it drops the
+// cached children once the transaction of ctx, if any, is committed.
+func (or *OrganizationRepository) DetachChildren(ctx context.Context, parentID primitive.ObjectID) error {
+	filter := bson.M{"parent_id": parentID}
+	update := bson.M{
+		"$unset": bson.M{"parent_id": "", "parent_admin_access": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	// Find the children first, to drop their cached copies
+	children, err := or.collection.Distinct(ctx, "_id", filter)
+	if err != nil {
+		log.Println("Error retrieving child organizations:", err)
+		return err
+	}
+
+	if _, err := or.collection.UpdateMany(ctx, filter, versioned(update)); err != nil {
+		log.Println("Error detaching child organizations:", err)
+		return err
+	}
+	for _, child := range children {
+		if id, ok := child.(primitive.ObjectID); ok {
+			or.invalidate(ctx, id)
+		}
+	}
+
+	return nil
+}
+
+// GetOwnedOrganizations returns the organizations a user owns, except those which were deleted.
+func (or *OrganizationRepository) GetOwnedOrganizations(ctx context.Context, userID primitive.ObjectID) ([]*models.Organization, error) {
+	organizations := []*models.Organization{}
+
+	cursor, err := or.collection.Find(ctx, notDeleted(bson.M{"owner_id": userID}))
+	if err != nil {
+		log.Println("Error retrieving owned organizations:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &organizations); err != nil {
+		log.Println("Error decoding owned organizations:", err)
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// RequestOwnershipTransfer records a transfer of the ownership of an organization, replacing
+// the one pending.
+func (or *OrganizationRepository) RequestOwnershipTransfer(ctx context.Context, id primitive.ObjectID, transfer *models.OwnershipTransfer) error {
+	update := bson.M{"$set": bson.M{"ownership_transfer": transfer, "updated_at": time.Now()}}
+
+	result, err := or.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), versioned(update))
+	if err != nil {
+		log.Println("Error requesting ownership transfer:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// CancelOwnershipTransfer drops the transfer of the ownership of an organization. It returns
+// mongo.ErrNoDocuments when none was requested.
+func (or *OrganizationRepository) CancelOwnershipTransfer(ctx context.Context, id primitive.ObjectID) error {
+	filter := notDeleted(bson.M{"_id": id, "ownership_transfer": bson.M{"$exists": true}})
+	update := bson.M{"$unset": bson.M{"ownership_transfer": ""}, "$set": bson.M{"updated_at": time.Now()}}
+
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+	if err != nil {
+		log.Println("Error canceling ownership transfer:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// AcceptOwnershipTransfer makes a user the owner of an organization, when its ownership is
+// being transferred to them and the transfer didn't expire. It returns mongo.ErrNoDocuments
+// otherwise.
+func (or *OrganizationRepository) AcceptOwnershipTransfer(ctx context.Context, id, userID primitive.ObjectID) error {
+	now := time.Now()
+	filter := notDeleted(bson.M{
+		"_id":                           id,
+		"ownership_transfer.to_user_id": userID,
+		"ownership_transfer.expires_at": bson.M{"$gt": now},
+	})
+	update := bson.M{
+		"$set":   bson.M{"owner_id": userID, "updated_at": now},
+		"$unset": bson.M{"ownership_transfer": ""},
+	}
+
+	result, err := or.collection.UpdateOne(ctx, filter, versioned(update))
+	if err != nil {
+		log.Println("Error accepting ownership transfer:", err)
+		return err
+	}
+	or.invalidate(ctx, id)
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// invalidate drops the cached copy of an organization after it changed.
+func (or *OrganizationRepository) invalidate(ctx context.Context, id primitive.ObjectID) {
+    // Changes made in a transaction are only visible to others once it is committed
+    afterCommit(ctx, func(ctx context.Context) {
+        if err := or.cache.Delete(ctx, organizationCacheKey(id)); err != nil {
+            log.Println("Error invalidating cached organization:", err)
         }
-        return &user, nil
+    })
+}
+
+// versioned increments the version of the organization along with an update, so that
+// concurrent updates based on the same version conflict.
+func versioned(update bson.M) bson.M {
+    update["$inc"] = bson.M{"version": 1}
+    return update
+}
+
+// versionFilter matches the given version. Organizations created before versions were
+// introduced have none, which is version 0.
+func versionFilter(version int64) interface{} {
+    if version == 0 {
+        return bson.M{"$in": bson.A{0, nil}}
     }
+    return version
+}
+
+// notDeleted restricts a filter to organizations which weren't deleted.
+func notDeleted(filter bson.M) bson.M {
+    filter["deleted_at"] = bson.M{"$exists": false}
+    return filter
+}
 
-    // If no user found with the given email
-    return nil, errors.New("user not found")
-}
\ No newline at end of file
+func organizationCacheKey(id primitive.ObjectID) string {
+    return "organization:" + id.Hex()
+}
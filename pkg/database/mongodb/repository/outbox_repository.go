@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// OutboxRepository stores domain events until they are published. Events are added in the
+// transaction of the change they describe, so they are recorded if and only if it is committed.
+type OutboxRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(database *mongo.Database) *OutboxRepository {
+	return &OutboxRepository{
+		collection: database.Collection("outbox_events"),
+	}
+}
+
+// EnsureIndexes creates the index the relay claims unpublished events with, which also serves
+// deleting published ones.
+func (or *OutboxRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := or.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+	})
+	if err != nil {
+		log.Println("Error creating outbox indexes:", err)
+		return err
+	}
+
+	return nil
+}
+
+// outboxPayload is the document sinks publish for an event.
+type outboxPayload struct {
+	ID             string      `json:"id"`
+	Type           string      `json:"type"`
+	OrganizationID string      `json:"organization_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Data           interface{} `json:"data"`
+}
+
+// AddEvent records an event of the organization, to be published by the relay. It should be
+// called with the context of the transaction making the change.
+func (or *OutboxRepository) AddEvent(ctx context.Context, organizationID primitive.ObjectID, eventType string, data interface{}) error {
+	event := &models.OutboxEvent{
+		ID:             primitive.NewObjectID(),
+		Type:           eventType,
+		OrganizationID: organizationID,
+		CreatedAt:      time.Now().UTC(),
+	}
+	event.NextAttemptAt = event.CreatedAt
+
+	payload, err := json.Marshal(outboxPayload{
+		ID:             event.ID.Hex(),
+		Type:           eventType,
+		OrganizationID: organizationID.Hex(),
+		CreatedAt:      event.CreatedAt,
+		Data:           data,
+	})
+	if err != nil {
+		log.Println("Error encoding outbox event:", err)
+		return err
+	}
+	event.Payload = payload
+
+	_, err = or.collection.InsertOne(ctx, event)
+	if err != nil {
+		log.Println("Error inserting outbox event:", err)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimNext retrieves the oldest unpublished event whose next attempt is due, and postpones its
+// next attempt by lease so other relays don't publish it at the same time. It returns
+// mongo.ErrNoDocuments when no event is due.
+func (or *OutboxRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*models.OutboxEvent, error) {
+	filter := bson.M{"published_at": bson.M{"$exists": false}, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"_id": 1}).
+		SetReturnDocument(options.After)
+
+	var event models.OutboxEvent
+	err := or.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error claiming outbox event:", err)
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// MarkPublishedTo records that an event was published to a sink, so it isn't published to it
+// again when publishing to another sink is retried.
+func (or *OutboxRepository) MarkPublishedTo(ctx context.Context, id primitive.ObjectID, sink string) error {
+	_, err := or.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"published_to": sink}})
+	if err != nil {
+		log.Println("Error marking outbox event published to sink:", err)
+		return err
+	}
+	return nil
+}
+
+// MarkPublished records that an event was published to every sink.
+func (or *OutboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID, publishedAt time.Time) error {
+	_, err := or.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"published_at": publishedAt}})
+	if err != nil {
+		log.Println("Error marking outbox event published:", err)
+		return err
+	}
+	return nil
+}
+
+// RecordFailure records a failed attempt to publish an event, to be attempted again at
+// nextAttemptAt.
+func (or *OutboxRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, lastError string, nextAttemptAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"last_error": lastError, "next_attempt_at": nextAttemptAt},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	_, err := or.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Println("Error recording outbox event failure:", err)
+		return err
+	}
+	return nil
+}
+
+// DeletePublishedBefore deletes the events published before the given time.
+func (or *OutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) error {
+	_, err := or.collection.DeleteMany(ctx, bson.M{"published_at": bson.M{"$lt": before}})
+	if err != nil {
+		log.Println("Error deleting published outbox events:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+type SigningKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSigningKeyRepository(database *mongo.Database) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		collection: database.Collection("signing_keys"),
+	}
+}
+
+func (kr *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	key.CreatedAt = time.Now()
+
+	_, err := kr.collection.InsertOne(ctx, key)
+	if err != nil {
+		log.Println("Error inserting signing key:", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetSigningKeys retrieves every signing key, the most recently activated first.
+func (kr *SigningKeyRepository) GetSigningKeys(ctx context.Context) ([]*models.SigningKey, error) {
+	keys := []*models.SigningKey{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "activates_at", Value: -1}, {Key: "created_at", Value: -1}})
+	cursor, err := kr.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		log.Println("Error retrieving signing keys:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &keys); err != nil {
+		log.Println("Error decoding signing keys:", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (kr *SigningKeyRepository) DeleteSigningKey(ctx context.Context, id primitive.ObjectID) error {
+	_, err := kr.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		log.Println("Error deleting signing key:", err)
+		return err
+	}
+	return nil
+}
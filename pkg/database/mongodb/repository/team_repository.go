@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// ErrTeamExists is returned when an organization already has a team with the same name.
+var ErrTeamExists = errors.New("organization already has a team with this name")
+
+// ErrTeamHasChildren is returned when deleting a team other teams are nested in.
+var ErrTeamHasChildren = errors.New("team has child teams")
+
+// ErrNotTeamMember is returned when a user is not a member of a team.
+var ErrNotTeamMember = errors.New("user is not a member of the team")
+
+// ErrTeamMemberExists is returned when a user already is a member of a team.
+var ErrTeamMemberExists = errors.New("user is already a member of the team")
+
+// TeamRepository stores the teams of organizations and their members. Changes to them
+// invalidate the team access levels cached by MembershipRepository, which must share the cache.
+type TeamRepository struct {
+	collection *mongo.Collection
+	members    *mongo.Collection
+	cache      cache.Cache
+}
+
+func NewTeamRepository(database *mongo.Database, cache cache.Cache) *TeamRepository {
+	return &TeamRepository{
+		collection: database.Collection("teams"),
+		members:    database.Collection("team_members"),
+		cache:      cache,
+	}
+}
+
+// EnsureIndexes creates the indexes teams and their members are looked up with. They also
+// keep team names unique within an organization, and users from joining a team twice.
+func (tr *TeamRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := tr.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("Error creating team indexes:", err)
+		return err
+	}
+
+	_, err = tr.members.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "team_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Println("Error creating team member indexes:", err)
+		return err
+	}
+
+	return nil
+}
+
+// CreateTeam creates a team, failing with ErrTeamExists when its name is taken.
+func (tr *TeamRepository) CreateTeam(ctx context.Context, team *models.Team) error {
+	team.CreatedAt = time.Now()
+	team.UpdatedAt = team.CreatedAt
+
+	if _, err := tr.collection.InsertOne(ctx, team); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrTeamExists
+		}
+		log.Println("Error inserting team:", err)
+		return err
+	}
+	return nil
+}
+
+// GetTeam retrieves a team of an organization.
+func (tr *TeamRepository) GetTeam(ctx context.Context, orgID, id primitive.ObjectID) (*models.Team, error) {
+	var team models.Team
+	err := tr.collection.FindOne(ctx, bson.M{"_id": id, "organization_id": orgID}).Decode(&team)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting team:", err)
+		}
+		return nil, err
+	}
+	return &team, nil
+}
+
+// GetTeams retrieves every team of an organization, ordered by name.
+func (tr *TeamRepository) GetTeams(ctx context.Context, orgID primitive.ObjectID) ([]*models.Team, error) {
+	return findTeams(ctx, tr.collection, orgID)
+}
+
+// UpdateTeam updates the name, description, parent and access level of a team, failing with
+// ErrTeamExists when the new name is taken.
+func (tr *TeamRepository) UpdateTeam(ctx context.Context, team *models.Team) error {
+	team.UpdatedAt = time.Now()
+	set := bson.M{
+		"name":         team.Name,
+		"description":  team.Description,
+		"access_level": team.AccessLevel,
+		"updated_at":   team.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if team.ParentID.IsZero() {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		set["parent_id"] = team.ParentID
+	}
+
+	result, err := tr.collection.UpdateOne(ctx, bson.M{"_id": team.ID, "organization_id": team.OrganizationID}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrTeamExists
+		}
+		log.Println("Error updating team:", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	// A new access level or parent changes the access of the members of its child teams too
+	return tr.invalidateOrganization(ctx, team.OrganizationID)
+}
+
+// DeleteTeam deletes a team and its members. It fails with ErrTeamHasChildren when other
+// teams are nested in it, so it must run in a transaction for the check to hold.
+func (tr *TeamRepository) DeleteTeam(ctx context.Context, orgID, id primitive.ObjectID) error {
+	children, err := tr.collection.CountDocuments(ctx, bson.M{"organization_id": orgID, "parent_id": id})
+	if err != nil {
+		log.Println("Error counting child teams:", err)
+		return err
+	}
+	if children > 0 {
+		return ErrTeamHasChildren
+	}
+	if err := tr.invalidateOrganization(ctx, orgID); err != nil {
+		return err
+	}
+
+	result, err := tr.collection.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+	if err != nil {
+		log.Println("Error deleting team:", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	if _, err := tr.members.DeleteMany(ctx, bson.M{"team_id": id}); err != nil {
+		log.Println("Error deleting team members:", err)
+		return err
+	}
+	return nil
+}
+
+// AddTeamMember adds a member of the organization to a team, failing with
+// ErrTeamMemberExists when they already are a member of it.
+func (tr *TeamRepository) AddTeamMember(ctx context.Context, member *models.TeamMember) error {
+	member.ID = primitive.NewObjectID()
+	member.CreatedAt = time.Now()
+	member.UpdatedAt = member.CreatedAt
+
+	if _, err := tr.members.InsertOne(ctx, member); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrTeamMemberExists
+		}
+		log.Println("Error adding team member:", err)
+		return err
+	}
+
+	tr.invalidate(ctx, member.OrganizationID, member.UserID)
+	return nil
+}
+
+// GetTeamMember returns the membership of a user in a team, or ErrNotTeamMember.
+func (tr *TeamRepository) GetTeamMember(ctx context.Context, teamID, userID primitive.ObjectID) (*models.TeamMember, error) {
+	var member models.TeamMember
+	err := tr.members.FindOne(ctx, bson.M{"team_id": teamID, "user_id": userID}).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotTeamMember
+	}
+	if err != nil {
+		log.Println("Error getting team member:", err)
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetTeamMembers returns the members of the given teams.
+func (tr *TeamRepository) GetTeamMembers(ctx context.Context, teamIDs []primitive.ObjectID) ([]*models.TeamMember, error) {
+	members := []*models.TeamMember{}
+
+	cursor, err := tr.members.Find(ctx, bson.M{"team_id": bson.M{"$in": teamIDs}}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		log.Println("Error retrieving team members:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		log.Println("Error decoding team members:", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetUserTeamMemberships returns the memberships of a user in the teams of an organization.
+func (tr *TeamRepository) GetUserTeamMemberships(ctx context.Context, orgID, userID primitive.ObjectID) ([]*models.TeamMember, error) {
+	return findUserTeamMemberships(ctx, tr.members, orgID, userID)
+}
+
+// SetTeamMemberRole changes the role of a member of a team.
+func (tr *TeamRepository) SetTeamMemberRole(ctx context.Context, teamID, userID primitive.ObjectID, role string) error {
+	update := bson.M{"$set": bson.M{"role": role, "updated_at": time.Now()}}
+
+	result, err := tr.members.UpdateOne(ctx, bson.M{"team_id": teamID, "user_id": userID}, update)
+	if err != nil {
+		log.Println("Error changing team member role:", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotTeamMember
+	}
+	return nil
+}
+
+// RemoveTeamMember removes a user from a team.
+func (tr *TeamRepository) RemoveTeamMember(ctx context.Context, teamID, userID primitive.ObjectID) error {
+	var member models.TeamMember
+	err := tr.members.FindOneAndDelete(ctx, bson.M{"team_id": teamID, "user_id": userID}).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotTeamMember
+	}
+	if err != nil {
+		log.Println("Error removing team member:", err)
+		return err
+	}
+
+	tr.invalidate(ctx, member.OrganizationID, member.UserID)
+	return nil
+}
+
+// DeleteOrganizationTeams deletes every team of an organization and their members.
+func (tr *TeamRepository) DeleteOrganizationTeams(ctx context.Context, orgID primitive.ObjectID) error {
+	if err := tr.invalidateOrganization(ctx, orgID); err != nil {
+		return err
+	}
+	if _, err := tr.members.DeleteMany(ctx, bson.M{"organization_id": orgID}); err != nil {
+		log.Println("Error deleting organization team members:", err)
+		return err
+	}
+	if _, err := tr.collection.DeleteMany(ctx, bson.M{"organization_id": orgID}); err != nil {
+		log.Println("Error deleting organization teams:", err)
+		return err
+	}
+	return nil
+}
+
+// invalidate drops the team access level cached for a user once the changes of ctx are
+// committed.
+func (tr *TeamRepository) invalidate(ctx context.Context, orgID, userID primitive.ObjectID) {
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := tr.cache.Delete(ctx, teamAccessCacheKey(orgID, userID)); err != nil {
+			log.Println("Error invalidating cached team access level:", err)
+		}
+	})
+}
+
+// invalidateOrganization drops the team access levels cached for every user in a team of an
+// organization once the changes of ctx are committed. It must be called before team members
+// are deleted.
+func (tr *TeamRepository) invalidateOrganization(ctx context.Context, orgID primitive.ObjectID) error {
+	userIDs, err := tr.members.Distinct(ctx, "user_id", bson.M{"organization_id": orgID})
+	if err != nil {
+		log.Println("Error retrieving team member users:", err)
+		return err
+	}
+
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if id, ok := userID.(primitive.ObjectID); ok {
+			keys = append(keys, teamAccessCacheKey(orgID, id))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := tr.cache.Delete(ctx, keys...); err != nil {
+			log.Println("Error invalidating cached team access levels:", err)
+		}
+	})
+	return nil
+}
+
+// findTeams retrieves every team of an organization, ordered by name.
+func findTeams(ctx context.Context, collection *mongo.Collection, orgID primitive.ObjectID) ([]*models.Team, error) {
+	teams := []*models.Team{}
+
+	cursor, err := collection.Find(ctx, bson.M{"organization_id": orgID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		log.Println("Error retrieving teams:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &teams); err != nil {
+		log.Println("Error decoding teams:", err)
+		return nil, err
+	}
+	return teams, nil
+}
+
+// findUserTeamMemberships retrieves the memberships of a user in the teams of an organization.
+func findUserTeamMemberships(ctx context.Context, collection *mongo.Collection, orgID, userID primitive.ObjectID) ([]*models.TeamMember, error) {
+	members := []*models.TeamMember{}
+
+	cursor, err := collection.Find(ctx, bson.M{"organization_id": orgID, "user_id": userID})
+	if err != nil {
+		log.Println("Error retrieving user team memberships:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		log.Println("Error decoding user team memberships:", err)
+		return nil, err
+	}
+	return members, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactions runs functions in MongoDB transactions, so that the changes made with the
+// repositories they call are committed together. Transactions require a replica set.
+type Transactions struct {
+	client *mongo.Client
+}
+
+func NewTransactions(database *mongo.Database) *Transactions {
+	return &Transactions{
+		client: database.Client(),
+	}
+}
+
+// commitHooksKey is the context key of the hooks run once a transaction is committed.
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+}
+
+// Run calls fn in a transaction, which is committed if fn returns nil and aborted otherwise.
+// Repositories must be called with the context passed to fn. fn may be called again when the
+// transaction hits a transient error, so it must not have other side effects.
+func (t *Transactions) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		log.Println("Error starting session:", err)
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	var hooks *commitHooks
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		// Hooks queued by an attempt that was retried are dropped
+		hooks = &commitHooks{}
+		return nil, fn(context.WithValue(sessionContext, commitHooksKey{}, hooks))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks.hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+// afterCommit calls fn once the transaction of ctx is committed, or right away outside of
+// transactions. It is used for side effects which must not happen if the changes are rolled
+// back, such as invalidating caches.
+func afterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn(ctx)
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = append(hooks.hooks, fn)
+}
+
+// inTransaction reports whether ctx belongs to a transaction.
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	return ok
+}
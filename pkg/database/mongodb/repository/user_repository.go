@@ -11,16 +11,23 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/cache"
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
 )
 
+// userCacheTTL bounds how long a cached user may be served after a change made outside of
+// this repository.
+const userCacheTTL = 5 * time.Minute
+
 type UserRepository struct {
     collection *mongo.Collection
+    cache      cache.Cache
 }
 
-func NewUserRepository(database *mongo.Database) *UserRepository {
+func NewUserRepository(database *mongo.Database, cache cache.Cache) *UserRepository {
     return &UserRepository{
         collection: database.Collection("users"),
+        cache:      cache,
     }
 }
 
@@ -37,6 +44,7 @@ func (ur *UserRepository) CreateUser(ctx context.Context, user *models.User) err
     return nil
 }
 
+// GetUserByID retrieves a user, secrets included. It is never served from the cache.
 func (ur *UserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
     var user models.User
     err := ur.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
@@ -44,6 +52,66 @@ func (ur *UserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID
         log.Println("Error getting user by ID:", err)
         return nil, err
     }
+
+    return &user, nil
+}
+
+// GetUserProfile retrieves a user without its password, tokens and second factor secrets,
+// which keeps them out of the cache it is usually served from.
+func (ur *UserRepository) GetUserProfile(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+    var user models.User
+
+    // Serve the user from the cache when possible. Transactions read their own changes, which
+    // must not be cached before they are committed.
+    cached := !inTransaction(ctx)
+    if cached {
+        found, err := ur.cache.Get(ctx, userCacheKey(id), &user)
+        if err != nil {
+            log.Println("Error getting user from cache:", err)
+        }
+        if found {
+            return &user, nil
+        }
+    }
+
+    projection := bson.M{
+        "password":            0,
+        "access_token":        0,
+        "refresh_token":       0,
+        "totp_secret":         0,
+        "pending_totp_secret": 0,
+        "recovery_codes":      0,
+        "last_totp_step":      0,
+    }
+    err := ur.collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(projection)).Decode(&user)
+    if err != nil {
+        log.Println("Error getting user profile:", err)
+        return nil, err
+    }
+
+    if cached {
+        if err := ur.cache.Set(ctx, userCacheKey(id), &user, userCacheTTL); err != nil {
+            log.Println("Error caching user:", err)
+        }
+    }
+
+    return &user, nil
+}
+
+// GetSessionUser retrieves the email, two-factor status and latest access token of a user,
+// to authenticate requests. It is never served from the cache, so that a replaced access token
+// is rejected as soon as it is replaced.
+func (ur *UserRepository) GetSessionUser(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+    var user models.User
+    projection := bson.M{"email": 1, "mfa_enabled": 1, "access_token": 1}
+    err := ur.collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(projection)).Decode(&user)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting session user:", err)
+        }
+        return nil, err
+    }
+
     return &user, nil
 }
 
@@ -52,7 +120,7 @@ func (ur *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mo
     err := ur.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
     if err != nil {
         if err == mongo.ErrNoDocuments {
-            return nil, fmt.Errorf("user with email %s not found", email)
+            return nil, fmt.Errorf("user with email %s not found: %w", email, err)
         }
         log.Println("Error getting user by email:", err)
         return nil, err
@@ -67,6 +135,7 @@ func (ur *UserRepository) UpdateUser(ctx context.Context, id primitive.ObjectID,
         log.Println("Error updating user:", err)
         return err
     }
+    ur.invalidate(ctx, id)
     return nil
 }
 
@@ -76,6 +145,7 @@ func (ur *UserRepository) DeleteUser(ctx context.Context, id primitive.ObjectID)
         log.Println("Error deleting user:", err)
         return err
     }
+    ur.invalidate(ctx, id)
     return nil
 }
 
@@ -98,6 +168,7 @@ func (ur *UserRepository) SaveTokens(ctx context.Context, userID primitive.Objec
         log.Println("Error saving tokens:", err)
         return err
     }
+    ur.invalidate(ctx, userID)
 
     return nil
 }
@@ -140,6 +211,7 @@ func (ur *UserRepository) UpdateAccessToken(ctx context.Context, userID primitiv
         log.Println("Error updating access token:", err)
         return err
     }
+    ur.invalidate(ctx, userID)
 
     return nil
 }
@@ -182,3 +254,200 @@ func (ur *UserRepository) GetUserByToken(ctx context.Context, token string) (*mo
 
     return &user, nil
 }
+
+// SetPendingTOTPSecret stores a TOTP secret that becomes active once the user confirms it with a valid code.
+func (ur *UserRepository) SetPendingTOTPSecret(ctx context.Context, userID primitive.ObjectID, secret string) error {
+    update := bson.M{
+        "$set": bson.M{
+            "pending_totp_secret": secret,
+            "updated_at":          time.Now(),
+        },
+    }
+
+    _, err := ur.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+    if err != nil {
+        log.Println("Error saving pending TOTP secret:", err)
+        return err
+    }
+    ur.invalidate(ctx, userID)
+
+    return nil
+}
+
+// EnableTOTP activates two-factor authentication with the given secret and recovery code hashes.
+// The time step of the code which confirmed the secret is recorded as used.
+func (ur *UserRepository) EnableTOTP(ctx context.Context, userID primitive.ObjectID, secret string, recoveryCodeHashes []string, totpStep int64) error {
+    update := bson.M{
+        "$set": bson.M{
+            "mfa_enabled":    true,
+            "totp_secret":    secret,
+            "recovery_codes": recoveryCodeHashes,
+            "last_totp_step": totpStep,
+            "updated_at":     time.Now(),
+        },
+        "$unset": bson.M{"pending_totp_secret": ""},
+    }
+
+    _, err := ur.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+    if err != nil {
+        log.Println("Error enabling TOTP:", err)
+        return err
+    }
+    ur.invalidate(ctx, userID)
+
+    return nil
+}
+
+// DisableTOTP turns two-factor authentication off and forgets the secret and recovery codes.
+func (ur *UserRepository) DisableTOTP(ctx context.Context, userID primitive.ObjectID) error {
+    update := bson.M{
+        "$set": bson.M{"updated_at": time.Now()},
+        "$unset": bson.M{
+            "mfa_enabled":         "",
+            "totp_secret":         "",
+            "pending_totp_secret": "",
+            "recovery_codes":      "",
+            "last_totp_step":      "",
+        },
+    }
+
+    _, err := ur.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+    if err != nil {
+        log.Println("Error disabling TOTP:", err)
+        return err
+    }
+    ur.invalidate(ctx, userID)
+
+    return nil
+}
+
+// UseRecoveryCode consumes a recovery code by its hash and reports whether it was still valid.
+// Removing the code in the same operation that matches it guarantees it can only be used once.
+func (ur *UserRepository) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, recoveryCodeHash string) (bool, error) {
+    filter := bson.M{"_id": userID, "recovery_codes": recoveryCodeHash}
+    update := bson.M{"$pull": bson.M{"recovery_codes": recoveryCodeHash}}
+
+    result, err := ur.collection.UpdateOne(ctx, filter, update)
+    if err != nil {
+        log.Println("Error using recovery code:", err)
+        return false, err
+    }
+    ur.invalidate(ctx, userID)
+
+    return result.ModifiedCount == 1, nil
+}
+
+// UseTOTPStep records the time step of a TOTP code as used and reports whether it was after the
+// last one used. Matching and recording the step in the same operation guarantees each code can
+// only be used once.
+func (ur *UserRepository) UseTOTPStep(ctx context.Context, userID primitive.ObjectID, totpStep int64) (bool, error) {
+    filter := bson.M{
+        "_id": userID,
+        "$or": bson.A{
+            bson.M{"last_totp_step": bson.M{"$exists": false}},
+            bson.M{"last_totp_step": bson.M{"$lt": totpStep}},
+        },
+    }
+    update := bson.M{"$set": bson.M{"last_totp_step": totpStep}}
+
+    result, err := ur.collection.UpdateOne(ctx, filter, update)
+    if err != nil {
+        log.Println("Error using TOTP code:", err)
+        return false, err
+    }
+
+    return result.ModifiedCount == 1, nil
+}
+
+// GetUserByIdentity retrieves the user linked to an account at an external identity provider.
+func (ur *UserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+    filter := bson.M{
+        "identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}},
+    }
+
+    var user models.User
+    err := ur.collection.FindOne(ctx, filter).Decode(&user)
+    if err != nil {
+        if err != mongo.ErrNoDocuments {
+            log.Println("Error getting user by identity:", err)
+        }
+        return nil, err
+    }
+
+    return &user, nil
+}
+
+// AddIdentity links a user to an account at an external identity provider.
+func (ur *UserRepository) AddIdentity(ctx context.Context, userID primitive.ObjectID, identity models.UserIdentity) error {
+    update := bson.M{
+        "$push": bson.M{"identities": identity},
+        "$set":  bson.M{"updated_at": time.Now()},
+    }
+
+    _, err := ur.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+    if err != nil {
+        log.Println("Error linking identity to user:", err)
+        return err
+    }
+    ur.invalidate(ctx, userID)
+
+    return nil
+}
+
+// GetUsersByEmails retrieves the users with any of the given emails.
+func (ur *UserRepository) GetUsersByEmails(ctx context.Context, emails []string) ([]*models.User, error) {
+    users := []*models.User{}
+    if len(emails) == 0 {
+        return users, nil
+    }
+
+    cursor, err := ur.collection.Find(ctx, bson.M{"email": bson.M{"$in": emails}})
+    if err != nil {
+        log.Println("Error getting users by email:", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    if err := cursor.All(ctx, &users); err != nil {
+        log.Println("Error decoding users:", err)
+        return nil, err
+    }
+
+    return users, nil
+}
+
+// GetUsersByIDs retrieves the users with any of the given IDs.
+func (ur *UserRepository) GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+    users := []*models.User{}
+    if len(ids) == 0 {
+        return users, nil
+    }
+
+    cursor, err := ur.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        log.Println("Error getting users by ID:", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    if err := cursor.All(ctx, &users); err != nil {
+        log.Println("Error decoding users:", err)
+        return nil, err
+    }
+
+    return users, nil
+}
+
+// invalidate drops the cached copy of a user after it changed.
+func (ur *UserRepository) invalidate(ctx context.Context, id primitive.ObjectID) {
+    // Changes made in a transaction are only visible to others once it is committed
+    afterCommit(ctx, func(ctx context.Context) {
+        if err := ur.cache.Delete(ctx, userCacheKey(id)); err != nil {
+            log.Println("Error invalidating cached user:", err)
+        }
+    })
+}
+
+func userCacheKey(id primitive.ObjectID) string {
+    return "user:" + id.Hex()
+}
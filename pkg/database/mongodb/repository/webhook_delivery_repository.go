@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+type WebhookDeliveryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookDeliveryRepository(database *mongo.Database) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		collection: database.Collection("webhook_deliveries"),
+	}
+}
+
+// QueueDeliveries creates the first delivery of an event to each webhook. Deliveries which
+// already exist are left as they are, so an event queued again is only delivered once.
+func (dr *WebhookDeliveryRepository) QueueDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	writes := make([]mongo.WriteModel, len(deliveries))
+	for i, delivery := range deliveries {
+		delivery.CreatedAt = time.Now()
+		filter := bson.M{
+			"webhook_id":    delivery.WebhookID,
+			"event_id":      delivery.EventID,
+			"redelivery_of": bson.M{"$exists": false},
+		}
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$setOnInsert": delivery}).
+			SetUpsert(true)
+	}
+
+	_, err := dr.collection.BulkWrite(ctx, writes)
+	if err != nil {
+		log.Println("Error queuing webhook deliveries:", err)
+		return err
+	}
+
+	return nil
+}
+
+// CreateDelivery creates a delivery, such as a redelivery of an event.
+func (dr *WebhookDeliveryRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.CreatedAt = time.Now()
+
+	_, err := dr.collection.InsertOne(ctx, delivery)
+	if err != nil {
+		log.Println("Error inserting webhook delivery:", err)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueDelivery retrieves a pending delivery whose next attempt is due, and postpones its
+// next attempt by lease so other workers don't attempt it at the same time. It returns
+// mongo.ErrNoDocuments when no delivery is due.
+func (dr *WebhookDeliveryRepository) ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	filter := bson.M{"status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"next_attempt_at": 1}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := dr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error claiming webhook delivery:", err)
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RecordAttempt appends an attempt to the log of a delivery and sets its status. Pending
+// deliveries are attempted again at nextAttemptAt.
+func (dr *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	set := bson.M{"status": status}
+	if status == models.WebhookDeliveryPending {
+		set["next_attempt_at"] = nextAttemptAt
+	} else {
+		set["completed_at"] = attempt.AttemptedAt
+	}
+	update := bson.M{
+		"$set":  set,
+		"$inc":  bson.M{"attempt_count": 1},
+		"$push": bson.M{"attempts": attempt},
+	}
+
+	_, err := dr.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Println("Error recording webhook delivery attempt:", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDelivery retrieves a delivery to a webhook.
+func (dr *WebhookDeliveryRepository) GetWebhookDelivery(ctx context.Context, id, webhookID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := dr.collection.FindOne(ctx, bson.M{"_id": id, "webhook_id": webhookID}).Decode(&delivery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting webhook delivery:", err)
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries retrieves the deliveries to a webhook, most recent first. before is the
+// ID of the last delivery of the previous page.
+func (dr *WebhookDeliveryRepository) GetWebhookDeliveries(ctx context.Context, webhookID, before primitive.ObjectID, limit int64) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+
+	filter := bson.M{"webhook_id": webhookID}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit)
+	cursor, err := dr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error retrieving webhook deliveries:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		log.Println("Error decoding webhook deliveries:", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// DeleteWebhookDeliveries deletes the deliveries to a webhook, when the webhook is deleted.
+func (dr *WebhookDeliveryRepository) DeleteWebhookDeliveries(ctx context.Context, webhookID primitive.ObjectID) error {
+	_, err := dr.collection.DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	if err != nil {
+		log.Println("Error deleting webhook deliveries:", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteOrganizationDeliveries deletes the deliveries to every webhook of an organization.
+func (dr *WebhookDeliveryRepository) DeleteOrganizationDeliveries(ctx context.Context, organizationID primitive.ObjectID) error {
+	_, err := dr.collection.DeleteMany(ctx, bson.M{"organization_id": organizationID})
+	if err != nil {
+		log.Println("Error deleting organization webhook deliveries:", err)
+		return err
+	}
+	return nil
+}
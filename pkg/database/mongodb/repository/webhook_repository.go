@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+type WebhookRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookRepository(database *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		collection: database.Collection("webhooks"),
+	}
+}
+
+func (wr *WebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	webhook.CreatedAt = time.Now()
+
+	_, err := wr.collection.InsertOne(ctx, webhook)
+	if err != nil {
+		log.Println("Error inserting webhook:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (wr *WebhookRepository) GetWebhookByID(ctx context.Context, id primitive.ObjectID) (*models.Webhook, error) {
+	return wr.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetOrganizationWebhook retrieves a webhook of an organization.
+func (wr *WebhookRepository) GetOrganizationWebhook(ctx context.Context, id, organizationID primitive.ObjectID) (*models.Webhook, error) {
+	return wr.findOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+}
+
+func (wr *WebhookRepository) GetOrganizationWebhooks(ctx context.Context, organizationID primitive.ObjectID) ([]*models.Webhook, error) {
+	return wr.find(ctx, bson.M{"organization_id": organizationID})
+}
+
+// GetSubscribedWebhooks retrieves the webhooks of an organization subscribed to an event.
+func (wr *WebhookRepository) GetSubscribedWebhooks(ctx context.Context, organizationID primitive.ObjectID, event string) ([]*models.Webhook, error) {
+	return wr.find(ctx, bson.M{"organization_id": organizationID, "events": event})
+}
+
+// DeleteOrganizationWebhook deletes a webhook of an organization.
+func (wr *WebhookRepository) DeleteOrganizationWebhook(ctx context.Context, id, organizationID primitive.ObjectID) error {
+	result, err := wr.collection.DeleteOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+	if err != nil {
+		log.Println("Error deleting webhook:", err)
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// DeleteOrganizationWebhooks deletes every webhook of an organization.
+func (wr *WebhookRepository) DeleteOrganizationWebhooks(ctx context.Context, organizationID primitive.ObjectID) error {
+	_, err := wr.collection.DeleteMany(ctx, bson.M{"organization_id": organizationID})
+	if err != nil {
+		log.Println("Error deleting organization webhooks:", err)
+		return err
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) findOne(ctx context.Context, filter bson.M) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := wr.collection.FindOne(ctx, filter).Decode(&webhook)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error getting webhook:", err)
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (wr *WebhookRepository) find(ctx context.Context, filter bson.M) ([]*models.Webhook, error) {
+	webhooks := []*models.Webhook{}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := wr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Println("Error retrieving webhooks:", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		log.Println("Error decoding webhooks:", err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
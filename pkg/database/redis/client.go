@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// NewClient connects to the Redis server configured through REDIS_ADDR, REDIS_PASSWORD and
+// REDIS_DB. It returns nil when Redis is not configured or cannot be reached, so callers can
+// fall back to in-process storage.
+func NewClient(ctx context.Context) *goredis.Client {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		log.Println("REDIS_ADDR not set, using in-process storage")
+		return nil
+	}
+
+	db, err := strconv.Atoi(os.Getenv("REDIS_DB"))
+	if err != nil {
+		db = 0
+	}
+
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       db,
+	})
+
+	// Check the connection
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		log.Println("Error pinging Redis, using in-process storage:", err)
+		client.Close()
+		return nil
+	}
+	log.Println("Connected to Redis!")
+
+	return client
+}
@@ -0,0 +1,85 @@
+package dnsverify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+)
+
+// recordPrefix is prepended to a domain to get the name of its verification TXT record.
+const recordPrefix = "_organizationhub-challenge."
+
+// valuePrefix starts the content of a verification TXT record.
+const valuePrefix = "organizationhub-verification="
+
+// ErrRecordNotFound is returned when the verification TXT record is missing or has another value.
+var ErrRecordNotFound = errors.New("verification TXT record not found")
+
+// Resolver looks up TXT records. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DefaultResolver resolves records through the system's DNS configuration.
+var DefaultResolver Resolver = net.DefaultResolver
+
+// GenerateToken generates a random verification token for a domain.
+func GenerateToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// RecordName returns the name of the TXT record proving ownership of domain.
+func RecordName(domain string) string {
+	return recordPrefix + domain
+}
+
+// RecordValue returns the content of the TXT record for the given token.
+func RecordValue(token string) string {
+	return valuePrefix + token
+}
+
+// Verify checks that domain publishes the TXT record for token.
+func Verify(ctx context.Context, resolver Resolver, domain, token string) error {
+	records, err := resolver.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == RecordValue(token) {
+			return nil
+		}
+	}
+
+	return ErrRecordNotFound
+}
+
+// NormalizeDomain lowercases a domain and strips a trailing dot, or returns an empty string
+// when it isn't a plausible domain name.
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " /@:") {
+		return ""
+	}
+	return domain
+}
+
+// EmailDomain returns the normalized domain of an email address.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return NormalizeDomain(email[at+1:])
+}
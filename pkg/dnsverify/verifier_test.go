@@ -0,0 +1,96 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// stubResolver answers TXT lookups from a fixed set of records, and fails lookups of names it
+// has no records for as a DNS server would for names which don't exist.
+type stubResolver struct {
+	records map[string][]string
+	err     error
+	lookups []string
+}
+
+func (sr *stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	sr.lookups = append(sr.lookups, name)
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	records, ok := sr.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver *stubResolver
+		expected error
+	}{
+		{
+			name: "TXT record found",
+			resolver: &stubResolver{records: map[string][]string{
+				"_organizationhub-challenge.example.com": {"v=spf1 -all", " organizationhub-verification=token "},
+			}},
+		},
+		{
+			name:     "TXT record missing",
+			resolver: &stubResolver{records: map[string][]string{}},
+			expected: ErrRecordNotFound,
+		},
+		{
+			name: "TXT record with another token",
+			resolver: &stubResolver{records: map[string][]string{
+				"_organizationhub-challenge.example.com": {"organizationhub-verification=other"},
+			}},
+			expected: ErrRecordNotFound,
+		},
+		{
+			name: "token published on the domain itself",
+			resolver: &stubResolver{records: map[string][]string{
+				"example.com": {"organizationhub-verification=token"},
+			}},
+			expected: ErrRecordNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(context.Background(), test.resolver, "example.com", "token")
+			if !errors.Is(err, test.expected) {
+				t.Errorf("Verify returned %v, want %v", err, test.expected)
+			}
+			if len(test.resolver.lookups) != 1 || test.resolver.lookups[0] != "_organizationhub-challenge.example.com" {
+				t.Errorf("looked up %v, want the challenge record only", test.resolver.lookups)
+			}
+		})
+	}
+}
+
+func TestVerifyReturnsLookupFailures(t *testing.T) {
+	failure := &net.DNSError{Err: "i/o timeout", Name: "_organizationhub-challenge.example.com", IsTimeout: true}
+	err := Verify(context.Background(), &stubResolver{err: failure}, "example.com", "token")
+	if !errors.Is(err, failure) {
+		t.Errorf("Verify returned %v, want the lookup failure", err)
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	tests := map[string]string{
+		"jane@example.com":         "example.com",
+		"Jane@Mail.Example.COM.":   "mail.example.com",
+		"jane@localhost":           "",
+		"jane.example.com":         "",
+		"jane@evil.com@example.io": "example.io",
+	}
+	for email, expected := range tests {
+		if domain := EmailDomain(email); domain != expected {
+			t.Errorf("EmailDomain(%q) = %q, want %q", email, domain, expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package migrations moves existing data to the layout the current version of the service
+// expects. Migrations run on startup and can be run again safely.
+package migrations
+
+import (
+	"context"
+	"log"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// MoveEmbeddedMembers moves the members embedded in organizations to the memberships
+// collection. Members are only removed from the organization once they were added to the
+// collection, so an interrupted migration is completed by the next one. Members invited
+// before signing up have no user to key the membership with; they are kept until they do.
+func MoveEmbeddedMembers(ctx context.Context, organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, userRepository *repository.UserRepository) error {
+	organizations, err := organizationRepository.GetOrganizationsWithEmbeddedMembers(ctx)
+	if err != nil {
+		return err
+	}
+
+	moved, kept := 0, 0
+	for _, organization := range organizations {
+		emails := make([]string, len(organization.Members))
+		for i, member := range organization.Members {
+			emails[i] = member.Email
+		}
+
+		users, err := userRepository.GetUsersByEmails(ctx, emails)
+		if err != nil {
+			return err
+		}
+		usersByEmail := make(map[string]*models.User, len(users))
+		for _, user := range users {
+			usersByEmail[user.Email] = user
+		}
+
+		members := []models.OrganizationMember{}
+		movedEmails := []string{}
+		for _, member := range organization.Members {
+			user, ok := usersByEmail[member.Email]
+			if !ok {
+				kept++
+				continue
+			}
+			member.UserID = user.ID
+			members = append(members, member)
+			movedEmails = append(movedEmails, member.Email)
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		if err := membershipRepository.ImportMembers(ctx, organization.ID, members); err != nil {
+			return err
+		}
+		if err := organizationRepository.RemoveEmbeddedMembers(ctx, organization.ID, movedEmails); err != nil {
+			return err
+		}
+		moved += len(members)
+	}
+
+	if moved > 0 || kept > 0 {
+		log.Printf("Moved %d embedded members to the memberships collection, %d without a user were kept", moved, kept)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// issuers of well-known OpenID Connect providers, so only their credentials need to be configured.
+var wellKnownIssuers = map[string]string{
+	"google":    "https://accounts.google.com",
+	"microsoft": "https://login.microsoftonline.com/common/v2.0",
+}
+
+// LoadProviders configures the providers listed in OAUTH_PROVIDERS, e.g. "google,github,corp".
+// Each provider NAME is configured through the following environment variables:
+//
+//	OAUTH_NAME_TYPE           "oidc" (default) or "github"
+//	OAUTH_NAME_CLIENT_ID      client ID registered with the provider
+//	OAUTH_NAME_CLIENT_SECRET  client secret registered with the provider
+//	OAUTH_NAME_REDIRECT_URL   URL of /users/oauth/name/callback as seen by browsers
+//	OAUTH_NAME_ISSUER         issuer URL of an OIDC provider, optional for well-known ones
+//	OAUTH_NAME_BASE_URL       base URL of a GitHub Enterprise server
+//	OAUTH_NAME_API_URL        API URL of a GitHub Enterprise server
+//	OAUTH_NAME_SCOPES         comma-separated scopes, defaults depend on the type
+//
+// Providers that are misconfigured or unreachable are logged and left out.
+func LoadProviders(ctx context.Context) map[string]Provider {
+	providers := make(map[string]Provider)
+
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		provider, err := loadProvider(ctx, name)
+		if err != nil {
+			log.Printf("Error configuring OAuth provider %s: %v", name, err)
+			continue
+		}
+
+		providers[name] = provider
+		log.Printf("Configured OAuth provider %s", name)
+	}
+
+	return providers
+}
+
+func loadProvider(ctx context.Context, name string) (Provider, error) {
+	setting := func(key string) string {
+		return os.Getenv("OAUTH_" + strings.ToUpper(name) + "_" + key)
+	}
+
+	config := oauth2.Config{
+		ClientID:     setting("CLIENT_ID"),
+		ClientSecret: setting("CLIENT_SECRET"),
+		RedirectURL:  setting("REDIRECT_URL"),
+	}
+	if scopes := setting("SCOPES"); scopes != "" {
+		config.Scopes = strings.Split(scopes, ",")
+	}
+
+	providerType := setting("TYPE")
+	if providerType == "" && name == "github" {
+		providerType = "github"
+	}
+
+	if providerType == "github" {
+		return NewGitHubProvider(name, setting("BASE_URL"), setting("API_URL"), config), nil
+	}
+
+	issuer := setting("ISSUER")
+	if issuer == "" {
+		issuer = wellKnownIssuers[name]
+	}
+	return NewOIDCProvider(ctx, name, issuer, config)
+}
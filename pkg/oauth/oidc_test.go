@@ -0,0 +1,304 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID    = "client"
+	testRedirectURL = "https://hub.example.com/users/oauth/corp/callback"
+)
+
+// testIDP is an OpenID Connect provider which issues an authorization code to whoever asks and
+// only redeems it with the PKCE verifier matching its challenge.
+type testIDP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]testAuthorization
+	claims jwt.MapClaims
+}
+
+type testAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newTestIDP(t *testing.T) *testIDP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIDP{
+		key:   key,
+		codes: map[string]testAuthorization{},
+		claims: jwt.MapClaims{
+			"sub":            "subject-1",
+			"email":          "jane@example.com",
+			"email_verified": true,
+			"name":           "Jane",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/keys", idp.keys)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func (idp *testIDP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                idp.URL,
+		"authorization_endpoint":                idp.URL + "/authorize",
+		"token_endpoint":                        idp.URL + "/token",
+		"jwks_uri":                              idp.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *testIDP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code := oauth2.GenerateVerifier()
+	idp.mu.Lock()
+	idp.codes[code] = testAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *testIDP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Codes are redeemed once, with the verifier of their challenge
+	idp.mu.Lock()
+	authorization, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	claims := jwt.MapClaims{}
+	for name, value := range idp.claims {
+		claims[name] = value
+	}
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	defaults := jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range defaults {
+		if _, set := claims[name]; !set {
+			claims[name] = value
+		}
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "test"
+	rawIDToken, err := idToken.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     rawIDToken,
+	})
+}
+
+func (idp *testIDP) keys(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+// signin follows the redirect to the provider's signin page, and returns the state and the
+// authorization code it redirects back to the callback with.
+func (idp *testIDP) signin(t *testing.T, authCodeURL string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authCodeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("signin page returned %s", resp.Status)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.Query().Get("state"), callback.Query().Get("code")
+}
+
+func newTestOIDCProvider(t *testing.T, idp *testIDP) *OIDCProvider {
+	t.Helper()
+
+	provider, err := NewOIDCProvider(context.Background(), "corp", idp.URL, oauth2.Config{
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestOIDCProviderAuthCodeURLUsesPKCE(t *testing.T) {
+	provider := newTestOIDCProvider(t, newTestIDP(t))
+
+	authCodeURL, err := url.Parse(provider.AuthCodeURL("state", "verifier", "nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authCodeURL.Query()
+
+	sum := sha256.Sum256([]byte("verifier"))
+	expected := map[string]string{
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"response_type":         "code",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge_method": "S256",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	for name, value := range expected {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+	if query.Get("scope") != "openid email profile" {
+		t.Errorf("scope = %q, want the default OpenID scopes", query.Get("scope"))
+	}
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	idp := newTestIDP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	state, code := idp.signin(t, provider.AuthCodeURL("state", "verifier", "nonce"))
+	if state != "state" {
+		t.Fatalf("state = %q, want the state of the signin", state)
+	}
+
+	identity, err := provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Identity{
+		Provider:      "corp",
+		Subject:       "subject-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane",
+	}
+	if *identity != expected {
+		t.Errorf("identity = %+v, want %+v", *identity, expected)
+	}
+}
+
+func TestOIDCProviderExchangeReportsUnverifiedEmail(t *testing.T) {
+	idp := newTestIDP(t)
+	idp.claims["email_verified"] = false
+	provider := newTestOIDCProvider(t, idp)
+
+	_, code := idp.signin(t, provider.AuthCodeURL("state", "verifier", "nonce"))
+	identity, err := provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.EmailVerified {
+		t.Error("identity email is verified, want unverified")
+	}
+}
+
+func TestOIDCProviderExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	idp := newTestIDP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	_, code := idp.signin(t, provider.AuthCodeURL("state", "verifier", "nonce"))
+	if _, err := provider.Exchange(context.Background(), code, "another verifier", "nonce"); err == nil {
+		t.Fatal("exchange succeeded with the wrong code verifier")
+	}
+}
+
+func TestOIDCProviderExchangeRejectsNonceMismatch(t *testing.T) {
+	idp := newTestIDP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	_, code := idp.signin(t, provider.AuthCodeURL("state", "verifier", "nonce"))
+	if _, err := provider.Exchange(context.Background(), code, "verifier", "another nonce"); err == nil {
+		t.Fatal("exchange succeeded with the nonce of another signin")
+	}
+}
+
+func TestOIDCProviderExchangeRejectsForeignIDToken(t *testing.T) {
+	idp := newTestIDP(t)
+	idp.claims["aud"] = "another client"
+	provider := newTestOIDCProvider(t, idp)
+
+	_, code := idp.signin(t, provider.AuthCodeURL("state", "verifier", "nonce"))
+	if _, err := provider.Exchange(context.Background(), code, "verifier", "nonce"); err == nil {
+		t.Fatal("exchange succeeded with an ID token issued to another client")
+	}
+}
@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEmailNotVerified is returned when a provider can't vouch for the email address of an identity.
+var ErrEmailNotVerified = errors.New("email address not verified by the provider")
+
+// Identity is a user as known by an external identity provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider signs users in through the OAuth2 authorization code flow with PKCE.
+type Provider interface {
+	// Name identifies the provider in routes and in linked identities.
+	Name() string
+
+	// AuthCodeURL returns the URL the user is redirected to in order to sign in.
+	AuthCodeURL(state, codeVerifier, nonce string) string
+
+	// Exchange trades the authorization code returned to the callback for the user's identity.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// LoginState is kept between the redirect to a provider and its callback.
+type LoginState struct {
+	Provider     string `bson:"provider"`
+	CodeVerifier string `bson:"code_verifier"`
+	Nonce        string `bson:"nonce"`
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// NATSSink publishes events to NATS JetStream, on the subject made of the prefix and the type
+// of the event, such as "organizationhub.member.added". A stream must capture these subjects.
+// Events are published with their ID as message ID, so JetStream drops the duplicates published
+// within its deduplication window.
+type NATSSink struct {
+	jetStream     jetstream.JetStream
+	subjectPrefix string
+}
+
+func NewNATSSink(url, subjectPrefix string) (*NATSSink, error) {
+	if url == "" {
+		return nil, errors.New("NATS_URL is required by the nats outbox sink")
+	}
+	if subjectPrefix == "" {
+		subjectPrefix = "organizationhub"
+	}
+
+	// The connection reconnects by itself when the server is unavailable
+	conn, err := nats.Connect(url, nats.Name("OrganizationHub outbox"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	jetStream, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &NATSSink{
+		jetStream:     jetStream,
+		subjectPrefix: subjectPrefix,
+	}, nil
+}
+
+func (ns *NATSSink) Name() string {
+	return "nats"
+}
+
+func (ns *NATSSink) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	_, err := ns.jetStream.Publish(ctx, ns.subjectPrefix+"."+event.Type, event.Payload, jetstream.WithMsgID(event.ID.Hex()))
+	return err
+}
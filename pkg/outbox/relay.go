@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+const (
+	// pollInterval is how often the relay looks for events to publish.
+	pollInterval = time.Second
+
+	// eventLease is how long a claimed event is hidden from other relays. It exceeds the time
+	// publishing takes, so an event is only published again if its relay died.
+	eventLease = time.Minute
+
+	// publishTimeout bounds the time a sink can take to publish an event.
+	publishTimeout = 15 * time.Second
+
+	// Retries are delayed exponentially from initialBackoff, up to maxBackoff. Events are
+	// retried until every sink published them.
+	initialBackoff = 5 * time.Second
+	maxBackoff     = time.Hour
+
+	// retention is how long published events are kept, to investigate deliveries.
+	retention = 7 * 24 * time.Hour
+)
+
+// Relay publishes the events of the outbox to its sinks, at least once to each of them. Events
+// are published in the order they were recorded, except when publishing one is retried.
+type Relay struct {
+	outboxRepository *repository.OutboxRepository
+	sinks            []Sink
+}
+
+func NewRelay(outboxRepository *repository.OutboxRepository, sinks []Sink) *Relay {
+	return &Relay{
+		outboxRepository: outboxRepository,
+		sinks:            sinks,
+	}
+}
+
+// Start publishes events in the background until ctx is done. Several instances can run
+// relays, each event is claimed by one of them at a time.
+func (r *Relay) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		lastCleanup := time.Time{}
+		for {
+			r.publishDue(ctx)
+
+			// Delete old published events now and then
+			if time.Since(lastCleanup) > time.Hour {
+				if err := r.outboxRepository.DeletePublishedBefore(ctx, time.Now().Add(-retention)); err == nil {
+					lastCleanup = time.Now()
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// publishDue publishes every due event.
+func (r *Relay) publishDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		event, err := r.outboxRepository.ClaimNext(ctx, time.Now(), eventLease)
+		if err != nil {
+			return
+		}
+		r.publish(ctx, event)
+	}
+}
+
+// publish publishes an event to the sinks it wasn't published to yet, and schedules a retry if
+// any of them failed.
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent) {
+	var errs []error
+	for _, sink := range r.sinks {
+		if slices.Contains(event.PublishedTo, sink.Name()) {
+			continue
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := sink.Publish(publishCtx, event)
+		cancel()
+		if err != nil {
+			log.Printf("Error publishing event %s to %s: %v", event.ID.Hex(), sink.Name(), err)
+			errs = append(errs, err)
+			continue
+		}
+
+		// Sinks which succeeded are skipped when the others are retried
+		if err := r.outboxRepository.MarkPublishedTo(ctx, event.ID, sink.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		nextAttemptAt := time.Now().Add(Backoff(event.Attempts + 1))
+		if err := r.outboxRepository.RecordFailure(ctx, event.ID, errors.Join(errs...).Error(), nextAttemptAt); err != nil {
+			log.Println("Error recording outbox event failure:", err)
+		}
+		return
+	}
+
+	if err := r.outboxRepository.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+		log.Println("Error marking outbox event published:", err)
+	}
+}
+
+// Backoff returns the delay before the attempt following the given number of failed attempts.
+func Backoff(failedAttempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failedAttempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// Sink is a destination events of the outbox are published to.
+type Sink interface {
+	// Name identifies the sink in the outbox, which records the sinks each event was
+	// published to. It must not change between releases.
+	Name() string
+
+	// Publish publishes an event, returning once the destination acknowledged it. Events can
+	// be published more than once, so destinations should deduplicate them by ID.
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// LoadSinks returns the sinks listed in OUTBOX_SINKS, a comma-separated list of "webhooks",
+// "stdout" and "nats". Events are only delivered to webhooks when it isn't set.
+func LoadSinks(webhooks Sink) ([]Sink, error) {
+	names := os.Getenv("OUTBOX_SINKS")
+	if names == "" {
+		names = "webhooks"
+	}
+
+	var sinks []Sink
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "webhooks":
+			sinks = append(sinks, webhooks)
+		case "stdout":
+			sinks = append(sinks, NewWriterSink("stdout", os.Stdout))
+		case "nats":
+			sink, err := NewNATSSink(os.Getenv("NATS_URL"), os.Getenv("NATS_SUBJECT_PREFIX"))
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}
@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// WriterSink writes the payload of each event as a line of JSON, such as to the standard output
+// for log collectors to pick up.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{
+		name: name,
+		w:    w,
+	}
+}
+
+func (ws *WriterSink) Name() string {
+	return ws.name
+}
+
+func (ws *WriterSink) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	line := append([]byte{}, event.Payload...)
+	_, err := ws.w.Write(append(line, '\n'))
+	return err
+}
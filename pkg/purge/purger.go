@@ -0,0 +1,155 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// DefaultRetention is how long deleted organizations can be restored unless configured otherwise.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// purgeInterval is how often deleted organizations are looked for.
+const purgeInterval = time.Hour
+
+// LoadRetention reads how long deleted organizations can be restored before they are purged
+// from the ORGANIZATION_RETENTION environment variable, such as "720h".
+func LoadRetention() (time.Duration, error) {
+	raw := os.Getenv("ORGANIZATION_RETENTION")
+	if raw == "" {
+		return DefaultRetention, nil
+	}
+
+	retention, err := time.ParseDuration(raw)
+	if err != nil || retention < 0 {
+		return 0, fmt.Errorf("invalid ORGANIZATION_RETENTION %q", raw)
+	}
+	return retention, nil
+}
+
+// Purger permanently deletes the organizations deleted longer than the retention ago, along
+// with their members, teams, invitations, invitation jobs, webhooks, tokens and OAuth clients. Audit events are kept.
+type Purger struct {
+	organizationRepository    *repository.OrganizationRepository
+	membershipRepository      *repository.MembershipRepository
+	teamRepository            *repository.TeamRepository
+	invitationRepository      *repository.InvitationRepository
+	invitationJobRepository   *repository.InvitationJobRepository
+	webhookRepository         *repository.WebhookRepository
+	webhookDeliveryRepository *repository.WebhookDeliveryRepository
+	apiTokenRepository        *repository.APITokenRepository
+	oauthClientRepository     *repository.OAuthClientRepository
+	auditEventRepository      *repository.AuditEventRepository
+	retention                 time.Duration
+}
+
+func NewPurger(organizationRepository *repository.OrganizationRepository, membershipRepository *repository.MembershipRepository, teamRepository *repository.TeamRepository, invitationRepository *repository.InvitationRepository, invitationJobRepository *repository.InvitationJobRepository, webhookRepository *repository.WebhookRepository, webhookDeliveryRepository *repository.WebhookDeliveryRepository, apiTokenRepository *repository.APITokenRepository, oauthClientRepository *repository.OAuthClientRepository, auditEventRepository *repository.AuditEventRepository, retention time.Duration) *Purger {
+	return &Purger{
+		organizationRepository:    organizationRepository,
+		membershipRepository:      membershipRepository,
+		teamRepository:            teamRepository,
+		invitationRepository:      invitationRepository,
+		invitationJobRepository:   invitationJobRepository,
+		webhookRepository:         webhookRepository,
+		webhookDeliveryRepository: webhookDeliveryRepository,
+		apiTokenRepository:        apiTokenRepository,
+		oauthClientRepository:     oauthClientRepository,
+		auditEventRepository:      auditEventRepository,
+		retention:                 retention,
+	}
+}
+
+// Start purges organizations in the background until ctx is done.
+func (p *Purger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			p.purgeDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// purgeDue purges every organization whose retention elapsed.
+func (p *Purger) purgeDue(ctx context.Context) {
+	organizations, err := p.organizationRepository.GetOrganizationsDeletedBefore(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		return
+	}
+
+	for _, organization := range organizations {
+		if err := p.purge(ctx, organization); err != nil {
+			log.Println("Error purging organization "+organization.ID.Hex()+":", err)
+		}
+	}
+}
+
+// purge deletes the data of an organization, then the organization itself. A purge which
+// failed midway is resumed on the next run, since the organization is deleted last.
+func (p *Purger) purge(ctx context.Context, organization *models.Organization) error {
+	if err := p.membershipRepository.DeleteOrganizationMemberships(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.teamRepository.DeleteOrganizationTeams(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.invitationRepository.DeleteOrganizationInvitations(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.invitationJobRepository.DeleteOrganizationJobs(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.webhookDeliveryRepository.DeleteOrganizationDeliveries(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.webhookRepository.DeleteOrganizationWebhooks(ctx, organization.ID); err != nil {
+		return err
+	}
+
+	// Tokens issued to OAuth clients on behalf of users don't refer to the organization
+	clients, err := p.oauthClientRepository.GetOrganizationClients(ctx, organization.ID)
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		if err := p.apiTokenRepository.DeleteClientTokens(ctx, client.ClientID); err != nil {
+			return err
+		}
+	}
+	if err := p.oauthClientRepository.DeleteOrganizationClients(ctx, organization.ID); err != nil {
+		return err
+	}
+	if err := p.apiTokenRepository.DeleteOrganizationTokens(ctx, organization.ID); err != nil {
+		return err
+	}
+
+	// Organizations nested in it, deleted ones included, become top organizations
+	if err := p.organizationRepository.DetachChildren(ctx, organization.ID); err != nil {
+		return err
+	}
+
+	if err := p.organizationRepository.PurgeOrganization(ctx, organization.ID); err != nil {
+		return err
+	}
+
+	// The purge is done by the service rather than a user
+	return p.auditEventRepository.CreateEvent(ctx, &models.AuditEvent{
+		OrganizationID: organization.ID,
+		Action:         models.AuditActionOrganizationPurged,
+		TargetType:     models.AuditTargetOrganization,
+		TargetID:       organization.ID.Hex(),
+		CreatedAt:      time.Now(),
+	})
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// FallbackStore uses its primary store and switches to the fallback for any call the
+// primary fails, so an outage of Redis degrades rate limiting to per-instance counters
+// instead of rejecting or letting through every request.
+type FallbackStore struct {
+	primary  Store
+	fallback Store
+}
+
+func NewFallbackStore(primary, fallback Store) *FallbackStore {
+	return &FallbackStore{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (fs *FallbackStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	count, ttl, err := fs.primary.Increment(ctx, key, window)
+	if err != nil {
+		log.Println("Error incrementing rate limit counter, using fallback store:", err)
+		return fs.fallback.Increment(ctx, key, window)
+	}
+	return count, ttl, nil
+}
+
+func (fs *FallbackStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	if err := fs.primary.Set(ctx, key, value, ttl); err != nil {
+		log.Println("Error setting rate limit counter, using fallback store:", err)
+		return fs.fallback.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+func (fs *FallbackStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := fs.primary.TTL(ctx, key)
+	if err != nil {
+		log.Println("Error reading rate limit counter, using fallback store:", err)
+		return fs.fallback.TTL(ctx, key)
+	}
+	return ttl, nil
+}
+
+func (fs *FallbackStore) Delete(ctx context.Context, keys ...string) error {
+	// Clear both stores so counters written during an outage don't linger
+	fallbackErr := fs.fallback.Delete(ctx, keys...)
+	if err := fs.primary.Delete(ctx, keys...); err != nil {
+		log.Println("Error deleting rate limit counters:", err)
+		return fallbackErr
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Result describes the state of a rate limit after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	Reset     time.Duration
+}
+
+// Headers returns the RateLimit-* headers describing the result, plus Retry-After when the
+// request was rejected.
+func (r Result) Headers() map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.FormatInt(r.Limit, 10),
+		"RateLimit-Remaining": strconv.FormatInt(r.Remaining, 10),
+		"RateLimit-Reset":     strconv.FormatInt(seconds(r.Reset), 10),
+	}
+	if !r.Allowed {
+		headers["Retry-After"] = strconv.FormatInt(seconds(r.Reset), 10)
+	}
+	return headers
+}
+
+// Limiter allows up to limit requests per key within a fixed window.
+type Limiter struct {
+	store  Store
+	name   string
+	limit  int64
+	window time.Duration
+}
+
+func NewLimiter(store Store, name string, limit int64, window time.Duration) *Limiter {
+	return &Limiter{
+		store:  store,
+		name:   name,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow counts a request for key and reports whether it fits within the limit.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	count, ttl, err := l.store.Increment(ctx, "limit:"+l.name+":"+key, l.window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	remaining := l.limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   count <= l.limit,
+		Limit:     l.limit,
+		Remaining: remaining,
+		Reset:     ttl,
+	}, nil
+}
+
+// seconds rounds a duration up to whole seconds, as expected by the rate limit headers.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Lockout blocks a key after repeated failures. Once threshold failures were recorded
+// within the failure window, every further failure locks the key for twice as long as the
+// previous one, starting at baseDelay and capped at maxDelay.
+type Lockout struct {
+	store         Store
+	name          string
+	threshold     int64
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	failureWindow time.Duration
+}
+
+func NewLockout(store Store, name string, threshold int64, baseDelay, maxDelay time.Duration) *Lockout {
+	return &Lockout{
+		store:     store,
+		name:      name,
+		threshold: threshold,
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+		// Failures are forgotten once a full maximum lockout has passed without new ones
+		failureWindow: 2 * maxDelay,
+	}
+}
+
+// Locked returns how long key remains locked, or zero when it is not locked.
+func (lo *Lockout) Locked(ctx context.Context, key string) (time.Duration, error) {
+	return lo.store.TTL(ctx, lo.lockKey(key))
+}
+
+// RecordFailure counts a failed attempt for key and returns how long the key is now locked,
+// or zero when the threshold has not been reached yet.
+func (lo *Lockout) RecordFailure(ctx context.Context, key string) (time.Duration, error) {
+	failures, _, err := lo.store.Increment(ctx, lo.failureKey(key), lo.failureWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < lo.threshold {
+		return 0, nil
+	}
+
+	delay := lo.baseDelay
+	for i := lo.threshold; i < failures && delay < lo.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > lo.maxDelay {
+		delay = lo.maxDelay
+	}
+
+	if err := lo.store.Set(ctx, lo.lockKey(key), failures, delay); err != nil {
+		return 0, err
+	}
+
+	return delay, nil
+}
+
+// Reset clears the failures recorded for key, typically after a successful attempt.
+func (lo *Lockout) Reset(ctx context.Context, key string) error {
+	return lo.store.Delete(ctx, lo.failureKey(key), lo.lockKey(key))
+}
+
+func (lo *Lockout) failureKey(key string) string {
+	return "lockout:" + lo.name + ":failures:" + key
+}
+
+func (lo *Lockout) lockKey(key string) string {
+	return "lockout:" + lo.name + ":locked:" + key
+}
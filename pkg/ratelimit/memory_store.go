@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the number of writes between two passes over expired entries.
+const sweepInterval = 1000
+
+type memoryEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-process Store. Counters are not shared between instances of the
+// API, so it is only meant as a fallback when Redis is unavailable.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (ms *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	ms.sweep(now)
+
+	entry, ok := ms.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = &memoryEntry{expiresAt: now.Add(window)}
+		ms.entries[key] = entry
+	}
+	entry.value++
+
+	return entry.value, entry.expiresAt.Sub(now), nil
+}
+
+func (ms *MemoryStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	ms.sweep(now)
+	ms.entries[key] = &memoryEntry{value: value, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+func (ms *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, ok := ms.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	ttl := time.Until(entry.expiresAt)
+	if ttl <= 0 {
+		delete(ms.entries, key)
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (ms *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, key := range keys {
+		delete(ms.entries, key)
+	}
+
+	return nil
+}
+
+// sweep drops expired entries every sweepInterval writes. The caller must hold the lock.
+func (ms *MemoryStore) sweep(now time.Time) {
+	ms.writes++
+	if ms.writes < sweepInterval {
+		return
+	}
+	ms.writes = 0
+
+	for key, entry := range ms.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(ms.entries, key)
+		}
+	}
+}
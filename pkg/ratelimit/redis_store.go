@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// incrementScript increments a counter and starts its expiry window on the first hit, so
+// both happen atomically even when several API instances share the same Redis.
+var incrementScript = goredis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisStore is a Store shared by every instance of the API through Redis.
+type RedisStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+func NewRedisStore(client *goredis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: "ratelimit:",
+	}
+}
+
+func (rs *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := incrementScript.Run(ctx, rs.client, []string{rs.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+func (rs *RedisStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	return rs.client.Set(ctx, rs.prefix+key, value, ttl).Err()
+}
+
+func (rs *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := rs.client.PTTL(ctx, rs.prefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL reports missing keys and keys without expiry as negative durations
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (rs *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rs.prefix + key
+	}
+
+	return rs.client.Del(ctx, prefixed...).Err()
+}
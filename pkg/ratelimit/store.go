@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Store keeps the counters used by limiters and lockouts. Keys expire on their own, so a
+// store never needs to be cleaned up by its callers.
+type Store interface {
+	// Increment adds one to the counter stored under key and returns the new value together
+	// with the time left before the counter expires. A missing counter starts a new window
+	// of the given length.
+	Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+
+	// Set stores a counter under key that expires after ttl.
+	Set(ctx context.Context, key string, value int64, ttl time.Duration) error
+
+	// TTL returns the time left before key expires, or zero when it does not exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Delete removes the given keys.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NewStore returns a Redis-backed store falling back to in-process counters, or only the
+// in-process store when no Redis client is available.
+func NewStore(client *goredis.Client) Store {
+	if client == nil {
+		return NewMemoryStore()
+	}
+	return NewFallbackStore(NewRedisStore(client), NewMemoryStore())
+}
@@ -0,0 +1,48 @@
+// Package safehttp provides HTTP clients for requests to URLs chosen by users, such as webhook
+// endpoints and identity provider metadata, which must not reach the internal network.
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a URL resolves to an address of the internal network.
+var ErrPrivateAddress = errors.New("URL resolves to a private address")
+
+// NewClient returns an HTTP client which doesn't follow redirects. Unless allowPrivateNetworks
+// is true, it refuses to connect to loopback, private and link-local addresses, so it can't be
+// used to reach internal services. The check is done on the resolved address so DNS rebinding
+// can't get around it.
+func NewClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Servers are expected to answer directly
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,307 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Resource exposes the attribute values of a SCIM resource to filters. Attribute paths are
+// lowercase and relative to the core schema, e.g. "username" or "emails.value".
+type Resource interface {
+	Values(path string) []string
+}
+
+// Filter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2).
+type Filter interface {
+	Match(resource Resource) bool
+}
+
+// ParseFilter parses a filter such as `userName eq "bjensen" and not (active eq false)`.
+// Comparisons are case-insensitive and value paths with brackets are not supported.
+func ParseFilter(filter string) (Filter, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+
+	return expression, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case r == '"':
+			// Read a JSON string, honouring escaped quotes
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			value, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid string in filter: %v", err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = j + 1
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && runes[j] != '(' && runes[j] != ')' && runes[j] != '"' {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[i:j])})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peekWord(word string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].text, word)
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("unexpected end of filter")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	if p.peekWord("not") {
+		p.pos++
+		inner, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner}, nil
+	}
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOpen {
+		return p.parseGroup()
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseGroup() (Filter, error) {
+	open, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if open.kind != tokenOpen {
+		return nil, fmt.Errorf("expected ( but found %q", open.text)
+	}
+
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	closing, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if closing.kind != tokenClose {
+		return nil, fmt.Errorf("expected ) but found %q", closing.text)
+	}
+
+	return inner, nil
+}
+
+func (p *parser) parseComparison() (Filter, error) {
+	attribute, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if attribute.kind != tokenWord {
+		return nil, fmt.Errorf("expected attribute but found %q", attribute.text)
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(operator.text)
+	path := AttributePath(attribute.text)
+
+	if op == "pr" {
+		return comparison{path: path, op: op}, nil
+	}
+	switch op {
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", operator.text)
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if value.kind != tokenString && value.kind != tokenWord {
+		return nil, fmt.Errorf("expected value but found %q", value.text)
+	}
+
+	return comparison{path: path, op: op, value: strings.ToLower(value.text)}, nil
+}
+
+// AttributePath normalizes an attribute path: lowercase and without the core schema URN.
+func AttributePath(path string) string {
+	path = strings.ToLower(path)
+	for _, schema := range []string{strings.ToLower(UserSchema) + ":", strings.ToLower(GroupSchema) + ":"} {
+		path = strings.TrimPrefix(path, schema)
+	}
+	return path
+}
+
+type andFilter struct{ left, right Filter }
+
+func (f andFilter) Match(resource Resource) bool {
+	return f.left.Match(resource) && f.right.Match(resource)
+}
+
+type orFilter struct{ left, right Filter }
+
+func (f orFilter) Match(resource Resource) bool {
+	return f.left.Match(resource) || f.right.Match(resource)
+}
+
+type notFilter struct{ inner Filter }
+
+func (f notFilter) Match(resource Resource) bool {
+	return !f.inner.Match(resource)
+}
+
+type comparison struct {
+	path  string
+	op    string
+	value string
+}
+
+// Match reports whether any value of the attribute satisfies the comparison.
+func (f comparison) Match(resource Resource) bool {
+	values := resource.Values(f.path)
+	if f.op == "pr" {
+		for _, value := range values {
+			if value != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if f.op == "ne" {
+		for _, value := range values {
+			if strings.EqualFold(value, f.value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, value := range values {
+		value = strings.ToLower(value)
+		matched := false
+		switch f.op {
+		case "eq":
+			matched = value == f.value
+		case "co":
+			matched = strings.Contains(value, f.value)
+		case "sw":
+			matched = strings.HasPrefix(value, f.value)
+		case "ew":
+			matched = strings.HasSuffix(value, f.value)
+		case "gt":
+			matched = value > f.value
+		case "ge":
+			matched = value >= f.value
+		case "lt":
+			matched = value < f.value
+		case "le":
+			matched = value <= f.value
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
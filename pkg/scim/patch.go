@@ -0,0 +1,235 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// memberFilterPath matches the path removing a single group member, e.g. members[value eq "id"].
+var memberFilterPath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// PatchError is returned for operations that can't be applied, with the SCIM error type to report.
+type PatchError struct {
+	ScimType string
+	Detail   string
+}
+
+func (e *PatchError) Error() string {
+	return e.Detail
+}
+
+// ApplyUserPatch applies PATCH operations to a user. The user name and emails identify the
+// user across organizations, so they can't be changed.
+func ApplyUserPatch(user *User, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return &PatchError{ScimType: "invalidSyntax", Detail: fmt.Sprintf("unsupported operation %q", operation.Op)}
+		}
+
+		// Without a path the value holds the attributes to set
+		if operation.Path == "" {
+			if op == "remove" {
+				return &PatchError{ScimType: "noTarget", Detail: "remove requires a path"}
+			}
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+				return &PatchError{ScimType: "invalidValue", Detail: "value must be an object when no path is given"}
+			}
+			for path, value := range attributes {
+				if err := setUserAttribute(user, AttributePath(path), value, false); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := setUserAttribute(user, AttributePath(operation.Path), operation.Value, op == "remove"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setUserAttribute(user *User, path string, value json.RawMessage, remove bool) error {
+	var text string
+	if !remove && path != "active" && path != "name" {
+		if err := json.Unmarshal(value, &text); err != nil {
+			return &PatchError{ScimType: "invalidValue", Detail: fmt.Sprintf("%s must be a string", path)}
+		}
+	}
+
+	if user.Name == nil && strings.HasPrefix(path, "name") {
+		user.Name = &Name{}
+	}
+
+	switch path {
+	case "active":
+		if remove {
+			return &PatchError{ScimType: "mutability", Detail: "active can't be removed"}
+		}
+		active, err := parseBool(value)
+		if err != nil {
+			return &PatchError{ScimType: "invalidValue", Detail: "active must be a boolean"}
+		}
+		user.Active = &active
+	case "displayname":
+		user.DisplayName = text
+	case "externalid":
+		user.ExternalID = text
+	case "name":
+		if remove {
+			user.Name = nil
+			return nil
+		}
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return &PatchError{ScimType: "invalidValue", Detail: "name must be an object"}
+		}
+		user.Name = &name
+	case "name.formatted":
+		user.Name.Formatted = text
+	case "name.givenname":
+		user.Name.GivenName = text
+	case "name.familyname":
+		user.Name.FamilyName = text
+	case "username":
+		if remove || !strings.EqualFold(text, user.UserName) {
+			return &PatchError{ScimType: "mutability", Detail: "userName can't be changed"}
+		}
+	default:
+		if strings.HasPrefix(path, "emails") {
+			return &PatchError{ScimType: "mutability", Detail: "emails can't be changed"}
+		}
+		return &PatchError{ScimType: "invalidPath", Detail: fmt.Sprintf("unsupported path %q", path)}
+	}
+
+	return nil
+}
+
+// ApplyGroupPatch applies PATCH operations to the display name and members of a group.
+func ApplyGroupPatch(group *Group, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		path := AttributePath(operation.Path)
+
+		// Without a path the value holds the attributes to set
+		if path == "" && op != "remove" {
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+				return &PatchError{ScimType: "invalidValue", Detail: "value must be an object when no path is given"}
+			}
+			for attribute, value := range attributes {
+				if err := applyGroupOperation(group, op, AttributePath(attribute), value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := applyGroupOperation(group, op, path, operation.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyGroupOperation(group *Group, op, path string, value json.RawMessage) error {
+	// Remove a single member selected by a filter
+	if match := memberFilterPath.FindStringSubmatch(path); match != nil && op == "remove" {
+		group.Members = removeMembers(group.Members, map[string]bool{match[1]: true})
+		return nil
+	}
+
+	switch path {
+	case "displayname":
+		if op == "remove" {
+			return &PatchError{ScimType: "mutability", Detail: "displayName can't be removed"}
+		}
+		if err := json.Unmarshal(value, &group.DisplayName); err != nil {
+			return &PatchError{ScimType: "invalidValue", Detail: "displayName must be a string"}
+		}
+	case "members":
+		var members []Member
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &members); err != nil {
+				return &PatchError{ScimType: "invalidValue", Detail: "members must be an array"}
+			}
+		}
+
+		switch op {
+		case "add":
+			existing := memberSet(group.Members)
+			for _, member := range members {
+				if !existing[member.Value] {
+					group.Members = append(group.Members, member)
+					existing[member.Value] = true
+				}
+			}
+		case "replace":
+			group.Members = members
+		case "remove":
+			// Without a value every member is removed
+			if len(members) == 0 {
+				group.Members = nil
+			} else {
+				group.Members = removeMembers(group.Members, memberSet(members))
+			}
+		default:
+			return &PatchError{ScimType: "invalidSyntax", Detail: fmt.Sprintf("unsupported operation %q", op)}
+		}
+	default:
+		return &PatchError{ScimType: "invalidPath", Detail: fmt.Sprintf("unsupported path %q", path)}
+	}
+
+	return nil
+}
+
+func memberSet(members []Member) map[string]bool {
+	set := make(map[string]bool, len(members))
+	for _, member := range members {
+		set[member.Value] = true
+	}
+	return set
+}
+
+func removeMembers(members []Member, removed map[string]bool) []Member {
+	kept := members[:0]
+	for _, member := range members {
+		if !removed[member.Value] {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+// parseBool accepts JSON booleans as well as the "True"/"False" strings some identity
+// providers send.
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema URNs defined by RFC 7643 and RFC 7644.
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Email returns the primary email address of the user, falling back to the first one and
+// then to the user name.
+func (u *User) Email() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return u.UserName
+}
+
+// FullName returns the display name of the user, or a name built from its components.
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+func (u *User) Values(path string) []string {
+	switch path {
+	case "id":
+		return []string{u.ID}
+	case "externalid":
+		return []string{u.ExternalID}
+	case "username":
+		return []string{u.UserName}
+	case "displayname":
+		return []string{u.DisplayName}
+	case "active":
+		return []string{strconv.FormatBool(u.Active == nil || *u.Active)}
+	case "emails", "emails.value":
+		values := make([]string, len(u.Emails))
+		for i, email := range u.Emails {
+			values[i] = email.Value
+		}
+		return values
+	case "name.formatted", "name.givenname", "name.familyname":
+		if u.Name == nil {
+			return nil
+		}
+		return map[string][]string{
+			"name.formatted":  {u.Name.Formatted},
+			"name.givenname":  {u.Name.GivenName},
+			"name.familyname": {u.Name.FamilyName},
+		}[path]
+	case "meta.created":
+		if u.Meta != nil && u.Meta.Created != nil {
+			return []string{u.Meta.Created.UTC().Format(time.RFC3339)}
+		}
+	case "meta.lastmodified":
+		if u.Meta != nil && u.Meta.LastModified != nil {
+			return []string{u.Meta.LastModified.UTC().Format(time.RFC3339)}
+		}
+	}
+	return nil
+}
+
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+func (g *Group) Values(path string) []string {
+	switch path {
+	case "id":
+		return []string{g.ID}
+	case "displayname":
+		return []string{g.DisplayName}
+	case "members", "members.value":
+		values := make([]string, len(g.Members))
+		for i, member := range g.Members {
+			values[i] = member.Value
+		}
+		return values
+	}
+	return nil
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// NewListResponse returns the page of resources starting at the 1-based startIndex.
+func NewListResponse(resources []interface{}, startIndex, count int) ListResponse {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	page := []interface{}{}
+	if startIndex <= len(resources) {
+		end := len(resources)
+		if count >= 0 && startIndex-1+count < end {
+			end = startIndex - 1 + count
+		}
+		page = resources[startIndex-1 : end]
+	}
+
+	return ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// NewError returns the SCIM error response for a status code.
+func NewError(status int, scimType, detail string) Error {
+	return Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
@@ -0,0 +1,66 @@
+// Package scopes defines the permissions that can be granted to access tokens, personal
+// access tokens and API keys.
+package scopes
+
+import "fmt"
+
+const (
+	AccountRead      = "account:read"
+	AccountWrite     = "account:write"
+	OrgsRead         = "orgs:read"
+	OrgsWrite        = "orgs:write"
+	MembersManage    = "members:manage"
+	InvitationsWrite = "invitations:write"
+)
+
+// All lists every scope. Sessions started by signing in are granted all of them.
+var All = []string{
+	AccountRead,
+	AccountWrite,
+	OrgsRead,
+	OrgsWrite,
+	MembersManage,
+	InvitationsWrite,
+}
+
+// Valid reports whether scope is a known scope.
+func Valid(scope string) bool {
+	for _, known := range All {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize validates the requested scopes and removes repeated ones.
+func Normalize(requested []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, scope := range requested {
+		if !Valid(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// Missing returns the required scopes that weren't granted.
+func Missing(granted, required []string) []string {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		grantedSet[scope] = true
+	}
+
+	missing := []string{}
+	for _, scope := range required {
+		if !grantedSet[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,210 @@
+// Package signing manages the asymmetric keys access tokens are signed with, their scheduled
+// rotation and their publication as a JSON Web Key Set.
+package signing
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+)
+
+// Supported signing algorithms
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// encryptionKeySize is the size of the key encryption key, for AES-256.
+const encryptionKeySize = 32
+
+// Key is a signing key loaded from storage.
+type Key struct {
+	ID          string
+	Algorithm   string
+	Signer      crypto.Signer
+	ActivatesAt time.Time
+}
+
+// JWK is the public part of a signing key, as described by RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key in JWK format.
+func (k *Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+
+	switch publicKey := k.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(publicKey.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Coordinates are padded to the size of the curve
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = publicKey.Curve.Params().Name
+		jwk.X = encode(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(publicKey)
+	}
+
+	return jwk
+}
+
+// ValidAlgorithm reports whether algorithm is a supported signing algorithm.
+func ValidAlgorithm(algorithm string) bool {
+	return algorithm == RS256 || algorithm == ES256 || algorithm == EdDSA
+}
+
+// newSigningKey generates a private key for the algorithm, ready to be stored encrypted with
+// the key encryption key.
+func newSigningKey(algorithm string, activatesAt time.Time, encryptionKey []byte) (*models.SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case RS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := make([]byte, 16)
+	if _, err := rand.Read(keyID); err != nil {
+		return nil, err
+	}
+
+	stored := &models.SigningKey{
+		KeyID:       encode(keyID),
+		Algorithm:   algorithm,
+		ActivatesAt: activatesAt,
+	}
+	if stored.EncryptedPrivateKey, err = sealPrivateKey(encryptionKey, stored, privateKey); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// parseSigningKey loads a stored private key, decrypting it with the key encryption key.
+func parseSigningKey(stored *models.SigningKey, encryptionKey []byte) (*Key, error) {
+	plaintext := stored.PrivateKey
+	if stored.EncryptedPrivateKey != nil {
+		var err error
+		if plaintext, err = openPrivateKey(encryptionKey, stored); err != nil {
+			return nil, err
+		}
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key can't sign")
+	}
+
+	return &Key{
+		ID:          stored.KeyID,
+		Algorithm:   stored.Algorithm,
+		Signer:      signer,
+		ActivatesAt: stored.ActivatesAt,
+	}, nil
+}
+
+// sealPrivateKey encrypts a PKCS #8 private key with AES-256-GCM. The nonce is prepended to the
+// ciphertext, and the key ID and algorithm are authenticated along with it, so that a private
+// key can't be swapped into another stored key.
+func sealPrivateKey(encryptionKey []byte, stored *models.SigningKey, privateKey []byte) ([]byte, error) {
+	aead, err := newAEAD(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(privateKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, privateKey, associatedData(stored)), nil
+}
+
+// openPrivateKey decrypts a private key encrypted by sealPrivateKey.
+func openPrivateKey(encryptionKey []byte, stored *models.SigningKey) ([]byte, error) {
+	aead, err := newAEAD(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := stored.EncryptedPrivateKey
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted private key is too short")
+	}
+	privateKey, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], associatedData(stored))
+	if err != nil {
+		return nil, errors.New("private key can't be decrypted with the key encryption key")
+	}
+	return privateKey, nil
+}
+
+func newAEAD(encryptionKey []byte) (cipher.AEAD, error) {
+	if len(encryptionKey) != encryptionKeySize {
+		return nil, fmt.Errorf("key encryption key must be %d bytes", encryptionKeySize)
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func associatedData(stored *models.SigningKey) []byte {
+	return []byte(stored.KeyID + "." + stored.Algorithm)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func newEncryptionKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSigningKeyEncryption(t *testing.T) {
+	encryptionKey := newEncryptionKey(t)
+
+	stored, err := newSigningKey(ES256, time.Now(), encryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PrivateKey != nil || stored.EncryptedPrivateKey == nil {
+		t.Fatal("private key stored in plaintext")
+	}
+
+	key, err := parseSigningKey(stored, encryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != stored.KeyID || key.Algorithm != ES256 {
+		t.Errorf("parsed key %s %s, want %s %s", key.ID, key.Algorithm, stored.KeyID, ES256)
+	}
+
+	t.Run("another key encryption key", func(t *testing.T) {
+		if _, err := parseSigningKey(stored, newEncryptionKey(t)); err == nil {
+			t.Error("private key decrypted with another key encryption key")
+		}
+	})
+
+	t.Run("moved to another key", func(t *testing.T) {
+		moved := *stored
+		moved.KeyID = "another-key"
+		if _, err := parseSigningKey(&moved, encryptionKey); err == nil {
+			t.Error("private key decrypted for another key ID")
+		}
+	})
+
+	t.Run("stored before encryption", func(t *testing.T) {
+		privateKey, err := openPrivateKey(encryptionKey, stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := x509.ParsePKCS8PrivateKey(privateKey); err != nil {
+			t.Fatal(err)
+		}
+
+		legacy := *stored
+		legacy.PrivateKey = privateKey
+		legacy.EncryptedPrivateKey = nil
+		if _, err := parseSigningKey(&legacy, nil); err != nil {
+			t.Errorf("plaintext key rejected: %v", err)
+		}
+		if bytes.Contains(stored.EncryptedPrivateKey, privateKey) {
+			t.Error("encrypted private key contains the plaintext")
+		}
+	})
+}
+
+func TestLoadConfigEncryptionKey(t *testing.T) {
+	t.Setenv("JWT_SIGNING_ALGORITHM", ES256)
+
+	for _, value := range []string{"", "not base64", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		t.Setenv("JWT_KEY_ENCRYPTION_KEY", value)
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("configuration with JWT_KEY_ENCRYPTION_KEY %q accepted", value)
+		}
+	}
+
+	encryptionKey := newEncryptionKey(t)
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(encryptionKey))
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(config.EncryptionKey, encryptionKey) {
+		t.Error("encryption key not loaded")
+	}
+}
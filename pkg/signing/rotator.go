@@ -0,0 +1,238 @@
+package signing
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// refreshInterval is how often keys are reloaded from storage, which is how instances learn
+// about keys created by each other.
+const refreshInterval = time.Minute
+
+// Config describes how signing keys are rotated.
+type Config struct {
+	// Algorithm of new keys. Empty when tokens are signed with the SECRET_KEY shared secret.
+	Algorithm string
+
+	// RotationInterval is how long a key is used to sign tokens before being replaced.
+	RotationInterval time.Duration
+
+	// Prepublish is how long a new key is published before tokens are signed with it, so
+	// services caching the key set learn about it in time.
+	Prepublish time.Duration
+
+	// Retention is how long a replaced key remains published to verify tokens it signed. It
+	// must exceed the lifetime of access tokens.
+	Retention time.Duration
+
+	// EncryptionKey encrypts private keys before they are stored, so that a copy of the
+	// database isn't enough to sign tokens.
+	EncryptionKey []byte
+}
+
+// LoadConfig reads the signing configuration from the JWT_SIGNING_ALGORITHM,
+// JWT_KEY_ROTATION_INTERVAL, JWT_KEY_PREPUBLISH and JWT_KEY_RETENTION environment variables.
+// Signing keys require JWT_KEY_ENCRYPTION_KEY, a base64-encoded 32-byte key.
+func LoadConfig() (Config, error) {
+	config := Config{
+		Algorithm:        os.Getenv("JWT_SIGNING_ALGORITHM"),
+		RotationInterval: 30 * 24 * time.Hour,
+		Prepublish:       time.Hour,
+		Retention:        2 * time.Hour,
+	}
+
+	// The shared secret remains the default
+	if config.Algorithm == "" || config.Algorithm == "HS256" {
+		config.Algorithm = ""
+		return config, nil
+	}
+	if !ValidAlgorithm(config.Algorithm) {
+		return config, fmt.Errorf("unsupported JWT_SIGNING_ALGORITHM %q", config.Algorithm)
+	}
+
+	for name, value := range map[string]*time.Duration{
+		"JWT_KEY_ROTATION_INTERVAL": &config.RotationInterval,
+		"JWT_KEY_PREPUBLISH":        &config.Prepublish,
+		"JWT_KEY_RETENTION":         &config.Retention,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			duration, err := time.ParseDuration(raw)
+			if err != nil || duration <= 0 {
+				return config, fmt.Errorf("invalid %s %q", name, raw)
+			}
+			*value = duration
+		}
+	}
+
+	if config.Prepublish >= config.RotationInterval {
+		return config, fmt.Errorf("JWT_KEY_PREPUBLISH must be shorter than JWT_KEY_ROTATION_INTERVAL")
+	}
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(encryptionKey) != encryptionKeySize {
+		return config, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be a base64-encoded %d-byte key", encryptionKeySize)
+	}
+	config.EncryptionKey = encryptionKey
+
+	return config, nil
+}
+
+// Enabled reports whether tokens are signed with asymmetric keys.
+func (c Config) Enabled() bool {
+	return c.Algorithm != ""
+}
+
+// Rotator keeps the signing keys loaded from storage, creating new keys on schedule and
+// deleting the ones no longer needed. Keys are shared by every instance of the service
+// through the database.
+type Rotator struct {
+	repository *repository.SigningKeyRepository
+	config     Config
+
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+func NewRotator(repository *repository.SigningKeyRepository, config Config) *Rotator {
+	return &Rotator{
+		repository: repository,
+		config:     config,
+	}
+}
+
+// Start loads the keys, creating the first one if needed, and keeps them up to date until
+// the context is cancelled.
+func (r *Rotator) Start(ctx context.Context) error {
+	if err := r.Refresh(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Refresh(ctx); err != nil {
+					log.Println("Error refreshing signing keys:", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Refresh reloads the keys from storage, creating the next key when the current one is due
+// for rotation and deleting keys past their retention.
+func (r *Rotator) Refresh(ctx context.Context) error {
+	stored, err := r.repository.GetSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if activatesAt, due := r.nextActivation(stored, now); due {
+		next, err := newSigningKey(r.config.Algorithm, activatesAt, r.config.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		if err := r.repository.CreateSigningKey(ctx, next); err != nil {
+			return err
+		}
+		stored = append([]*models.SigningKey{next}, stored...)
+	}
+
+	keys := make([]*Key, 0, len(stored))
+	for i, storedKey := range stored {
+		// Replaced keys are kept to verify the tokens they signed until the retention passed
+		if i > 0 && stored[i-1].ActivatesAt.Before(now.Add(-r.config.Retention)) {
+			if err := r.repository.DeleteSigningKey(ctx, storedKey.ID); err != nil {
+				log.Println("Error deleting retired signing key:", err)
+			}
+			continue
+		}
+
+		key, err := parseSigningKey(storedKey, r.config.EncryptionKey)
+		if err != nil {
+			log.Println("Error parsing signing key", storedKey.KeyID+":", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+
+	return nil
+}
+
+// nextActivation returns when a new key should become active, if one has to be created now.
+func (r *Rotator) nextActivation(stored []*models.SigningKey, now time.Time) (time.Time, bool) {
+	// The first key is used right away, as there is nothing else to sign with
+	if len(stored) == 0 {
+		return now, true
+	}
+
+	// Replace keys of another algorithm after the configuration changed, keys stored before
+	// private keys were encrypted, and keys that will reach the end of their rotation interval
+	// within the prepublication period
+	newest := stored[0]
+	rotatesAt := newest.ActivatesAt.Add(r.config.RotationInterval)
+	if newest.Algorithm != r.config.Algorithm || newest.EncryptedPrivateKey == nil || !now.Before(rotatesAt.Add(-r.config.Prepublish)) {
+		return now.Add(r.config.Prepublish), true
+	}
+
+	return time.Time{}, false
+}
+
+// Current returns the key new tokens are signed with: the most recently activated one.
+func (r *Rotator) Current() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range r.keys {
+		if !key.ActivatesAt.After(now) {
+			return key
+		}
+	}
+	return nil
+}
+
+// Lookup returns the published key with the given ID, or nil when there is none.
+func (r *Rotator) Lookup(keyID string) *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID == keyID {
+			return key
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys of every published key, including the ones not active yet.
+func (r *Rotator) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keySet := JWKSet{Keys: make([]JWK, 0, len(r.keys))}
+	for _, key := range r.keys {
+		keySet.Keys = append(keySet.Keys, key.JWK())
+	}
+	return keySet
+}
@@ -0,0 +1,236 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/safehttp"
+)
+
+// maxMetadataSize limits the size of IdP metadata fetched from a URL.
+const maxMetadataSize = 1 << 20
+
+// emailAttributes are the attribute names identity providers commonly use for email addresses.
+var emailAttributes = []string{
+	"email",
+	"mail",
+	"emailAddress",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+}
+
+// nameAttributes are the attribute names identity providers commonly use for display names.
+var nameAttributes = []string{
+	"name",
+	"displayName",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name",
+	"urn:oid:2.16.840.1.113730.3.1.241",
+}
+
+// ServiceProviders builds the SAML service provider of each organization. All of them share
+// the same signing key, but every organization gets its own entity ID and endpoints so that
+// it can be registered separately with its identity provider.
+type ServiceProviders struct {
+	key         *rsa.PrivateKey
+	certificate *x509.Certificate
+	baseURL     string
+}
+
+// NewServiceProviders loads the service provider key pair from SAML_SP_CERT_FILE and
+// SAML_SP_KEY_FILE, and the public URL of the API from PUBLIC_BASE_URL. Without a key pair a
+// temporary one is generated, which only suits development since identity providers have to
+// be reconfigured on every restart.
+func NewServiceProviders() (*ServiceProviders, error) {
+	baseURL := strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	certFile, keyFile := os.Getenv("SAML_SP_CERT_FILE"), os.Getenv("SAML_SP_KEY_FILE")
+	if certFile == "" || keyFile == "" {
+		log.Println("SAML_SP_CERT_FILE or SAML_SP_KEY_FILE not set, generating a temporary SAML key pair")
+		key, certificate, err := generateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceProviders{key: key, certificate: certificate, baseURL: baseURL}, nil
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("SAML service provider key must be an RSA key")
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceProviders{key: key, certificate: certificate, baseURL: baseURL}, nil
+}
+
+// For returns the service provider of an organization. idpMetadata may be nil when only the
+// service provider metadata is needed.
+func (sps *ServiceProviders) For(organizationID primitive.ObjectID, idpMetadata *saml.EntityDescriptor) *saml.ServiceProvider {
+	base := fmt.Sprintf("%s/sso/saml/%s", sps.baseURL, organizationID.Hex())
+	metadataURL, _ := url.Parse(base + "/metadata")
+	acsURL, _ := url.Parse(base + "/acs")
+
+	if idpMetadata == nil {
+		idpMetadata = &saml.EntityDescriptor{}
+	}
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               sps.key,
+		Certificate:       sps.certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+		AllowIDPInitiated: true,
+	}
+}
+
+// ParseIDPMetadata parses identity provider metadata, accepting either an EntityDescriptor or
+// an EntitiesDescriptor containing a single identity provider.
+func ParseIDPMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil {
+		if len(entity.IDPSSODescriptors) == 0 {
+			return nil, errors.New("SAML metadata does not describe an identity provider")
+		}
+		return &entity, nil
+	}
+
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err != nil {
+		return nil, errors.New("invalid SAML metadata")
+	}
+	for _, candidate := range entities.EntityDescriptors {
+		if len(candidate.IDPSSODescriptors) > 0 {
+			return &candidate, nil
+		}
+	}
+
+	return nil, errors.New("SAML metadata does not describe an identity provider")
+}
+
+// ErrInvalidMetadataURL is returned for metadata URLs which aren't absolute https URLs.
+var ErrInvalidMetadataURL = errors.New("SAML metadata URL must be an absolute https URL")
+
+// FetchIDPMetadata downloads identity provider metadata from its URL. Only https URLs are
+// fetched, without following redirects, and unless SAML_METADATA_ALLOW_PRIVATE_NETWORKS is true
+// the URL can't point to the internal network.
+func FetchIDPMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	parsed, err := url.Parse(metadataURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil {
+		return nil, ErrInvalidMetadataURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := safehttp.NewClient(10*time.Second, os.Getenv("SAML_METADATA_ALLOW_PRIVATE_NETWORKS") == "true")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching SAML metadata returned %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+// AssertionEmail returns the email address asserted by the identity provider.
+func AssertionEmail(assertion *saml.Assertion) string {
+	if email := assertionAttribute(assertion, emailAttributes); email != "" {
+		return email
+	}
+
+	// Fall back to the subject when it is an email address
+	if assertion.Subject != nil && assertion.Subject.NameID != nil && strings.Contains(assertion.Subject.NameID.Value, "@") {
+		return assertion.Subject.NameID.Value
+	}
+
+	return ""
+}
+
+// AssertionName returns the display name asserted by the identity provider, if any.
+func AssertionName(assertion *saml.Assertion) string {
+	return assertionAttribute(assertion, nameAttributes)
+}
+
+// AssertionSubject returns the subject identifier of the assertion.
+func AssertionSubject(assertion *saml.Assertion) string {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil {
+		return ""
+	}
+	return assertion.Subject.NameID.Value
+}
+
+func assertionAttribute(assertion *saml.Assertion, names []string) string {
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, name := range names {
+				if (attribute.Name == name || attribute.FriendlyName == name) && len(attribute.Values) > 0 {
+					return strings.TrimSpace(attribute.Values[0].Value)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func generateKeyPair() (*rsa.PrivateKey, *x509.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "OrganizationHub SAML service provider"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, certificate, nil
+}
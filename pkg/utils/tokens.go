@@ -3,45 +3,308 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
-    
-	"github.com/dgrijalva/jwt-go"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/signing"
+
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// GenerateAccessToken generates an access token for the given user.
-func GenerateAccessToken(user *models.User) (string, error) {
-    // Define the expiration time for the token (1 hour)
-    expirationTime := time.Now().Add(1 * time.Hour)
+// Tokens tolerate this much difference between the clocks of the issuer and the verifier.
+const clockSkew = 30 * time.Second
+
+// accessTokenLifetime is how long access tokens are valid.
+const accessTokenLifetime = time.Hour
+
+// legacyTokensDeadline is when tokens without registered claims stop being accepted unless
+// JWT_LEGACY_TOKENS_UNTIL says otherwise: one access token lifetime after the service started,
+// so tokens issued before the new format was deployed can expire on their own.
+var legacyTokensDeadline = time.Now().Add(accessTokenLifetime)
+
+// mfaPurpose marks tokens proving the first signin step, which don't grant access.
+const mfaPurpose = "mfa"
+
+// tokenClaims are the claims of tokens issued by the service. Tokens issued before the
+// registered claims were introduced carry the user ID in UserID instead of the subject.
+type tokenClaims struct {
+	UserID  string `json:"user_id,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateAccessToken generates an access token for the given user, granting the given scopes.
+func GenerateAccessToken(user *models.User, scopes []string) (string, error) {
+	// Access tokens are valid for 1 hour, scopes are a space-separated list as in OAuth 2.0
+	claims := newTokenClaims(user, tokenAudience(), accessTokenLifetime)
+	claims.Scope = strings.Join(scopes, " ")
+
+	// Sign the token with the current signing key
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ParseAccessToken verifies an access token and returns the hex ID of the user it was issued
+// to and the scopes it grants.
+func ParseAccessToken(tokenString string) (string, []string, error) {
+	claims, err := parseToken(tokenString, tokenAudience())
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Tokens issued for another purpose, such as MFA challenges, don't grant access
+	if claims.Purpose != "" {
+		return "", nil, errors.New("not an access token")
+	}
+
+	// Legacy tokens issued before scopes were introduced were only issued by signing in, which
+	// grants every scope
+	if isLegacyToken(claims) && claims.Scope == "" {
+		return tokenSubject(claims), scopes.All, nil
+	}
+
+	return tokenSubject(claims), strings.Fields(claims.Scope), nil
+}
+
+// GenerateMFAToken generates a short-lived token proving that the given user passed the
+// password step of signin, to be exchanged for an access token with a second factor.
+func GenerateMFAToken(user *models.User) (string, error) {
+	// The second factor must be provided within 5 minutes. The audience differs from access
+	// tokens so that other services verifying tokens reject it.
+	claims := newTokenClaims(user, mfaAudience(), 5*time.Minute)
+	claims.Purpose = mfaPurpose
+
+	return signToken(claims)
+}
+
+// ParseMFAToken verifies an MFA challenge token and returns the hex ID of the user it was issued to.
+func ParseMFAToken(tokenString string) (string, error) {
+	claims, err := parseToken(tokenString, mfaAudience())
+	if err != nil {
+		return "", err
+	}
+
+	if claims.Purpose != mfaPurpose {
+		return "", errors.New("not an MFA token")
+	}
+
+	return tokenSubject(claims), nil
+}
+
+// KeySource provides the asymmetric keys tokens are signed and verified with.
+type KeySource interface {
+	Current() *signing.Key
+	Lookup(keyID string) *signing.Key
+}
+
+// signingKeys is nil while tokens are signed with the SECRET_KEY shared secret.
+var signingKeys KeySource
+
+// UseSigningKeys signs new tokens with the current key of the given source instead of the
+// SECRET_KEY shared secret. Tokens signed with the secret remain valid until they expire.
+func UseSigningKeys(keys KeySource) {
+	signingKeys = keys
+}
+
+func newTokenClaims(user *models.User, audience string, lifetime time.Duration) *tokenClaims {
+	now := time.Now()
+	return &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    tokenIssuer(),
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		},
+	}
+}
 
-    // Create a new JWT token with the user's ID and expiration time
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": user.ID,
-        "exp":     expirationTime.Unix(),
-    })
+func signToken(claims *tokenClaims) (string, error) {
+	if signingKeys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("SECRET_KEY")))
+	}
 
-    // Sign the token with a secret key
-    tokenString, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
-    if err != nil {
-        return "", err
-    }
+	key := signingKeys.Current()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
 
-    return tokenString, nil
+	// The key ID tells verifiers which published key to use
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Signer)
+}
+
+// parseToken verifies the signature and time claims of a token, and that it was issued by
+// this service for the given audience.
+func parseToken(tokenString string, audience string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey,
+		jwt.WithValidMethods([]string{"HS256", signing.RS256, signing.ES256, signing.EdDSA}),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Tokens issued before the registered claims were introduced have no issuer
+	if isLegacyToken(claims) {
+		if !legacyTokensAccepted() {
+			return nil, errors.New("token format is no longer accepted")
+		}
+		return claims, nil
+	}
+
+	if claims.Issuer != tokenIssuer() {
+		return nil, errors.New("unexpected token issuer")
+	}
+	if !slices.Contains(claims.Audience, audience) {
+		return nil, errors.New("unexpected token audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token subject")
+	}
+
+	return claims, nil
+}
+
+// verificationKey returns the key a token must be verified with, only accepting tokens signed
+// the way they are generated.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	// Tokens without a key ID are signed with the shared secret
+	keyID, _ := token.Header["kid"].(string)
+	if keyID == "" {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		secret := os.Getenv("SECRET_KEY")
+		if signingKeys != nil && secret == "" {
+			return nil, errors.New("shared secret tokens are not accepted")
+		}
+		return []byte(secret), nil
+	}
+
+	if signingKeys == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	key := signingKeys.Lookup(keyID)
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.Signer.Public(), nil
+}
+
+// isLegacyToken reports whether a token was issued before the registered claims were introduced.
+func isLegacyToken(claims *tokenClaims) bool {
+	return claims.Issuer == "" && claims.Subject == "" && claims.UserID != ""
+}
+
+func tokenSubject(claims *tokenClaims) string {
+	if claims.Subject != "" {
+		return claims.Subject
+	}
+	return claims.UserID
+}
+
+// legacyTokensAccepted reports whether tokens without registered claims are still accepted.
+// They are until the time set in JWT_LEGACY_TOKENS_UNTIL (RFC 3339), or legacyTokensDeadline
+// when it isn't set.
+func legacyTokensAccepted() bool {
+	until, err := time.Parse(time.RFC3339, os.Getenv("JWT_LEGACY_TOKENS_UNTIL"))
+	if err != nil {
+		until = legacyTokensDeadline
+	}
+	return time.Now().Before(until)
+}
+
+// tokenIssuer returns the issuer of tokens, from JWT_ISSUER or else PUBLIC_BASE_URL.
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	if baseURL := os.Getenv("PUBLIC_BASE_URL"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "OrganizationHub"
+}
+
+// tokenAudience returns the audience of access tokens, from JWT_AUDIENCE.
+func tokenAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "organizationhub-api"
+}
+
+func mfaAudience() string {
+	return tokenAudience() + ":mfa"
 }
 
 // GenerateRefreshToken generates a refresh token for the given user.
 func GenerateRefreshToken() string {
-    // Generate a UUID as the refresh token
-    refreshToken := uuid.New().String()
-    return refreshToken
+	// Generate a UUID as the refresh token
+	refreshToken := uuid.New().String()
+	return refreshToken
+}
+
+// Prefixes identifying the kind of an opaque token
+const (
+	PersonalAccessTokenPrefix = "ohp_"
+	APIKeyPrefix              = "ohk_"
+	SCIMTokenPrefix           = "scim_"
+	OAuthAccessTokenPrefix    = "oha_"
+	OAuthRefreshTokenPrefix   = "ohr_"
+	OAuthClientIDPrefix       = "ohc_"
+	OAuthClientSecretPrefix   = "ohs_"
+	InvitationTokenPrefix     = "ohi_"
+	WebhookSecretPrefix       = "whsec_"
+)
+
+// GenerateOpaqueToken generates a random token with a recognizable prefix, for credentials
+// that are stored hashed and shown to their owner only once.
+func GenerateOpaqueToken(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashOpaqueToken returns the hash an opaque token is stored and looked up by.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // VerifyPassword checks if the provided password matches the hashed password.
 func VerifyPassword(plainPassword, hashedPassword string) bool {
-    err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword))
-    return err == nil
-}
\ No newline at end of file
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword))
+	return err == nil
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/scopes"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/signing"
+)
+
+const testSecret = "test secret"
+
+// testKeys is a key source with a single ES256 key.
+type testKeys struct {
+	key *signing.Key
+}
+
+func (tk *testKeys) Current() *signing.Key {
+	return tk.key
+}
+
+func (tk *testKeys) Lookup(keyID string) *signing.Key {
+	if keyID == tk.key.ID {
+		return tk.key
+	}
+	return nil
+}
+
+func setupTokens(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("SECRET_KEY", testSecret)
+	t.Setenv("JWT_ISSUER", "https://hub.example.com")
+	t.Setenv("JWT_AUDIENCE", "organizationhub-api")
+	t.Setenv("JWT_LEGACY_TOKENS_UNTIL", "")
+	UseSigningKeys(nil)
+	t.Cleanup(func() { UseSigningKeys(nil) })
+}
+
+// sign signs claims with the shared secret and the given method.
+func sign(t *testing.T, method jwt.SigningMethod, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   "https://hub.example.com",
+		"aud":   "organizationhub-api",
+		"sub":   primitive.NewObjectID().Hex(),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": scopes.OrgsRead,
+	}
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	setupTokens(t)
+	user := &models.User{ID: primitive.NewObjectID()}
+
+	token, err := GenerateAccessToken(user, []string{scopes.OrgsRead, scopes.OrgsWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, granted, err := ParseAccessToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != user.ID.Hex() || !slices.Equal(granted, []string{scopes.OrgsRead, scopes.OrgsWrite}) {
+		t.Errorf("got user %s with scopes %v, want the user and the scopes granted", userID, granted)
+	}
+}
+
+func TestParseAccessTokenAlgorithms(t *testing.T) {
+	setupTokens(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &testKeys{key: &signing.Key{ID: "key-1", Algorithm: signing.ES256, Signer: key}}
+
+	t.Run("signing key", func(t *testing.T) {
+		UseSigningKeys(keys)
+		t.Cleanup(func() { UseSigningKeys(nil) })
+
+		token, err := GenerateAccessToken(&models.User{ID: primitive.NewObjectID()}, scopes.All)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ParseAccessToken(token); err != nil {
+			t.Errorf("token signed with the current key rejected: %v", err)
+		}
+	})
+
+	t.Run("unsigned token", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ParseAccessToken(token); err == nil {
+			t.Error("unsigned token accepted")
+		}
+	})
+
+	t.Run("algorithm outside the allow-list", func(t *testing.T) {
+		if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS512, validClaims())); err == nil {
+			t.Error("HS512 token accepted")
+		}
+	})
+
+	t.Run("shared secret token with a key ID", func(t *testing.T) {
+		UseSigningKeys(keys)
+		t.Cleanup(func() { UseSigningKeys(nil) })
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString([]byte(testSecret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ParseAccessToken(signed); err == nil {
+			t.Error("HS256 token accepted for an ES256 key")
+		}
+	})
+
+	t.Run("shared secret token once signing keys are used", func(t *testing.T) {
+		UseSigningKeys(keys)
+		t.Cleanup(func() { UseSigningKeys(nil) })
+		t.Setenv("SECRET_KEY", "")
+
+		if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, validClaims())); err == nil {
+			t.Error("shared secret token accepted without a shared secret")
+		}
+	})
+}
+
+func TestParseAccessTokenClaims(t *testing.T) {
+	setupTokens(t)
+
+	if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, validClaims())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"another issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://other.example.com" }},
+		{"another audience", func(claims jwt.MapClaims) { claims["aud"] = "other-api" }},
+		{"MFA audience", func(claims jwt.MapClaims) { claims["aud"] = "organizationhub-api:mfa" }},
+		{"no subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{"no expiration", func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"issued in the future", func(claims jwt.MapClaims) { claims["iat"] = time.Now().Add(time.Hour).Unix() }},
+		{"MFA challenge", func(claims jwt.MapClaims) { claims["purpose"] = mfaPurpose }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := validClaims()
+			test.modify(claims)
+			if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, claims)); err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+}
+
+func TestParseAccessTokenLegacy(t *testing.T) {
+	setupTokens(t)
+	userID := primitive.NewObjectID().Hex()
+	legacy := jwt.MapClaims{"user_id": userID, "exp": time.Now().Add(time.Hour).Unix()}
+
+	t.Run("accepted before the cutoff with every scope", func(t *testing.T) {
+		t.Setenv("JWT_LEGACY_TOKENS_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+
+		subject, granted, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, legacy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subject != userID || !slices.Equal(granted, scopes.All) {
+			t.Errorf("got user %s with scopes %v, want the user with every scope", subject, granted)
+		}
+	})
+
+	t.Run("keeps the scopes it was issued with", func(t *testing.T) {
+		t.Setenv("JWT_LEGACY_TOKENS_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+
+		scoped := jwt.MapClaims{"user_id": userID, "scope": scopes.OrgsRead, "exp": time.Now().Add(time.Hour).Unix()}
+		_, granted, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, scoped))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(granted, []string{scopes.OrgsRead}) {
+			t.Errorf("scopes = %v, want %v", granted, []string{scopes.OrgsRead})
+		}
+	})
+
+	t.Run("rejected after the cutoff", func(t *testing.T) {
+		t.Setenv("JWT_LEGACY_TOKENS_UNTIL", time.Now().Add(-time.Minute).Format(time.RFC3339))
+
+		if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, legacy)); err == nil {
+			t.Error("legacy token accepted after the cutoff")
+		}
+	})
+
+	t.Run("cutoff defaults to one access token lifetime after startup", func(t *testing.T) {
+		deadline := legacyTokensDeadline
+		t.Cleanup(func() { legacyTokensDeadline = deadline })
+
+		legacyTokensDeadline = time.Now().Add(time.Minute)
+		if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, legacy)); err != nil {
+			t.Errorf("legacy token rejected before the default cutoff: %v", err)
+		}
+
+		legacyTokensDeadline = time.Now().Add(-time.Minute)
+		if _, _, err := ParseAccessToken(sign(t, jwt.SigningMethodHS256, legacy)); err == nil {
+			t.Error("legacy token accepted after the default cutoff")
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// recoveryCodeCount is the number of recovery codes issued when two-factor authentication is enabled.
+const recoveryCodeCount = 10
+
+// recoveryCodeSize is the number of random bytes in a recovery code, 80 bits.
+const recoveryCodeSize = 10
+
+// GenerateTOTPKey generates a new TOTP secret for the given account.
+func GenerateTOTPKey(accountName string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer(),
+		AccountName: accountName,
+	})
+}
+
+// TOTPKey rebuilds the key of an existing base32 encoded secret, e.g. to render its QR code again.
+func TOTPKey(accountName, secret string) (*otp.Key, error) {
+	rawSecret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer(),
+		AccountName: accountName,
+		Secret:      rawSecret,
+	})
+}
+
+// TOTPQRCode renders the otpauth:// URI of a key as a PNG QR code.
+func TOTPQRCode(key *otp.Key) ([]byte, error) {
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// totpPeriod is the number of seconds each TOTP code is valid for.
+const totpPeriod = 30
+
+// VerifyTOTP checks a code against a secret, allowing one period of clock drift. It returns the
+// time step the code belongs to, which callers store to reject codes at or before the last one
+// accepted, so that each code is only used once.
+func VerifyTOTP(code, secret string) (int64, bool) {
+	code = strings.TrimSpace(code)
+	now := time.Now().UTC()
+
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns a set of single-use recovery codes and their hashes. Only the
+// hashes are meant to be stored.
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+
+		// Format as groups of five characters, e.g. 3f9a1-c07b2-9e4d0-a81c5
+		encoded := hex.EncodeToString(raw)
+		groups := make([]string, 0, len(encoded)/5)
+		for start := 0; start < len(encoded); start += 5 {
+			groups = append(groups, encoded[start:start+5])
+		}
+		codes[i] = strings.Join(groups, "-")
+
+		hash, err := HashRecoveryCode(codes[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes[i] = hash
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage, with an HMAC keyed by
+// MFA_RECOVERY_CODE_KEY, or SECRET_KEY when it isn't set. Hashes leaked without the key
+// can't be checked against guessed codes.
+func HashRecoveryCode(code string) (string, error) {
+	key := os.Getenv("MFA_RECOVERY_CODE_KEY")
+	if key == "" {
+		key = os.Getenv("SECRET_KEY")
+	}
+	if key == "" {
+		return "", errors.New("no recovery code key configured")
+	}
+
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "OrganizationHub"
+}
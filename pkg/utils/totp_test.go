@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	t.Setenv("MFA_RECOVERY_CODE_KEY", "recovery key")
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if digits := strings.ReplaceAll(code, "-", ""); len(digits) != 2*recoveryCodeSize {
+			t.Errorf("code %q has %d hex digits, want %d", code, len(digits), 2*recoveryCodeSize)
+		}
+		if seen[code] {
+			t.Errorf("code %q issued twice", code)
+		}
+		seen[code] = true
+
+		// Codes are accepted however they are typed
+		hash, err := HashRecoveryCode(" " + strings.ToUpper(strings.ReplaceAll(code, "-", "")) + " ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != hashes[i] {
+			t.Errorf("code %q doesn't match its hash", code)
+		}
+	}
+
+	// Hashes depend on the key
+	t.Setenv("MFA_RECOVERY_CODE_KEY", "another key")
+	if hash, _ := HashRecoveryCode(codes[0]); hash == hashes[0] {
+		t.Error("hash doesn't depend on the key")
+	}
+
+	t.Setenv("MFA_RECOVERY_CODE_KEY", "")
+	t.Setenv("SECRET_KEY", "")
+	if _, err := HashRecoveryCode(codes[0]); err == nil {
+		t.Error("code hashed without a key")
+	}
+}
@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// pollInterval is how often the worker looks for due deliveries.
+	pollInterval = 5 * time.Second
+
+	// deliveryLease is how long a claimed delivery is hidden from other workers. It exceeds the
+	// request timeout, so a delivery is only attempted again if its worker died.
+	deliveryLease = time.Minute
+
+	// maxAttempts is the number of attempts before a delivery is given up.
+	maxAttempts = 8
+
+	// Retries are delayed exponentially from initialBackoff, up to maxBackoff.
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+
+	// maxResponseBody is the number of bytes of responses kept in the delivery log.
+	maxResponseBody = 1024
+)
+
+// Dispatcher is the outbox sink queuing events for the webhooks subscribed to them. It
+// delivers them in the background, retrying failed deliveries with exponential backoff.
+type Dispatcher struct {
+	webhookRepository  *repository.WebhookRepository
+	deliveryRepository *repository.WebhookDeliveryRepository
+	client             *http.Client
+}
+
+func NewDispatcher(webhookRepository *repository.WebhookRepository, deliveryRepository *repository.WebhookDeliveryRepository) *Dispatcher {
+	return &Dispatcher{
+		webhookRepository:  webhookRepository,
+		deliveryRepository: deliveryRepository,
+		client:             newClient(),
+	}
+}
+
+// Name identifies the dispatcher among the sinks of the outbox.
+func (d *Dispatcher) Name() string {
+	return "webhooks"
+}
+
+// Publish queues a delivery of an outbox event to each webhook of the organization subscribed
+// to it. Publishing an event again doesn't queue more deliveries.
+func (d *Dispatcher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	webhooks, err := d.webhookRepository.GetSubscribedWebhooks(ctx, event.OrganizationID, event.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	deliveries := make([]*models.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = &models.WebhookDelivery{
+			ID:             primitive.NewObjectID(),
+			WebhookID:      webhook.ID,
+			OrganizationID: event.OrganizationID,
+			EventID:        event.ID.Hex(),
+			Event:          event.Type,
+			Payload:        event.Payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+	}
+
+	return d.deliveryRepository.QueueDeliveries(ctx, deliveries)
+}
+
+// Redeliver queues a new delivery of the same payload as a previous one.
+func (d *Dispatcher) Redeliver(ctx context.Context, previous *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:             primitive.NewObjectID(),
+		WebhookID:      previous.WebhookID,
+		OrganizationID: previous.OrganizationID,
+		EventID:        previous.EventID,
+		Event:          previous.Event,
+		Payload:        previous.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   previous.ID,
+	}
+
+	if err := d.deliveryRepository.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Start delivers due deliveries in the background until ctx is done. Several instances can
+// run workers, each delivery is claimed by one of them at a time.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// deliverDue attempts every due delivery.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := d.deliveryRepository.ClaimDueDelivery(ctx, time.Now(), deliveryLease)
+		if err != nil {
+			return
+		}
+		d.attempt(ctx, delivery)
+	}
+}
+
+// attempt sends a delivery and records the outcome, scheduling a retry after a failure.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	attempt := models.WebhookDeliveryAttempt{AttemptedAt: time.Now()}
+
+	webhook, err := d.webhookRepository.GetWebhookByID(ctx, delivery.WebhookID)
+	if err == mongo.ErrNoDocuments {
+		attempt.Error = "webhook was deleted"
+		d.record(ctx, delivery, attempt, models.WebhookDeliveryFailed)
+		return
+	}
+	if err != nil {
+		// Leave the delivery to be claimed again once the lease expires
+		return
+	}
+
+	attempt.ResponseStatus, attempt.ResponseBody, err = d.send(ctx, webhook, delivery)
+	attempt.DurationMS = time.Since(attempt.AttemptedAt).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	status := models.WebhookDeliverySucceeded
+	if err != nil || attempt.ResponseStatus < 200 || attempt.ResponseStatus > 299 {
+		status = models.WebhookDeliveryPending
+		if delivery.AttemptCount+1 >= maxAttempts {
+			status = models.WebhookDeliveryFailed
+		}
+	}
+	d.record(ctx, delivery, attempt, status)
+}
+
+func (d *Dispatcher) record(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookDeliveryAttempt, status string) {
+	nextAttemptAt := attempt.AttemptedAt.Add(Backoff(delivery.AttemptCount + 1))
+	if err := d.deliveryRepository.RecordAttempt(ctx, delivery.ID, attempt, status, nextAttemptAt); err != nil {
+		log.Println("Error recording webhook delivery:", err)
+	}
+}
+
+// send posts the signed payload to the webhook and returns the status and the beginning of
+// the body of the response.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OrganizationHub-Webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(SignatureHeader, Signature(webhook.Secret, time.Now().Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// Backoff returns the delay before the attempt following the given number of failed attempts.
+func Backoff(failedAttempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failedAttempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,151 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/models"
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/database/mongodb/repository"
+)
+
+// mockDocument converts a model to the document the mock deployment replies with.
+func mockDocument(t testing.TB, value interface{}) bson.D {
+	t.Helper()
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// verifySignature checks a signature header the way endpoints are documented to.
+func verifySignature(secret, header string, payload []byte) bool {
+	parts := strings.Split(header, ",")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "t=") || !strings.HasPrefix(parts[1], "v1=") {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.TrimPrefix(parts[0], "t=") + "."))
+	mac.Write(payload)
+	return hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(strings.TrimPrefix(parts[1], "v1=")))
+}
+
+func TestAttempt(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	webhook := &models.Webhook{ID: primitive.NewObjectID(), OrganizationID: primitive.NewObjectID(), Secret: "whsec"}
+	payload := []byte(`{"name":"Example"}`)
+
+	// attempt delivers an event to an endpoint responding with status, and returns the update
+	// recording the attempt
+	attempt := func(mt *mtest.T, status int, attemptCount int) (*http.Request, bson.Raw) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		endpoint := *webhook
+		endpoint.URL = server.URL
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.webhooks", mtest.FirstBatch, mockDocument(mt, &endpoint)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		// The test server listens on the loopback interface, which deliveries normally refuse
+		d := &Dispatcher{
+			webhookRepository:  repository.NewWebhookRepository(mt.DB),
+			deliveryRepository: repository.NewWebhookDeliveryRepository(mt.DB),
+			client:             server.Client(),
+		}
+		delivery := &models.WebhookDelivery{
+			ID:           primitive.NewObjectID(),
+			WebhookID:    webhook.ID,
+			Event:        models.EventOrganizationUpdated,
+			Payload:      payload,
+			Status:       models.WebhookDeliveryPending,
+			AttemptCount: attemptCount,
+		}
+		d.attempt(context.Background(), delivery)
+
+		if received != nil && string(body) != string(payload) {
+			mt.Errorf("endpoint received %s, want %s", body, payload)
+		}
+		return received, mt.GetAllStartedEvents()[1].Command.Lookup("updates", "0", "u").Document()
+	}
+
+	mt.Run("signs the delivered payload", func(mt *mtest.T) {
+		received, update := attempt(mt, http.StatusNoContent, 0)
+		if received == nil {
+			mt.Fatal("event not delivered")
+		}
+		if received.Header.Get(EventHeader) != models.EventOrganizationUpdated {
+			mt.Errorf("%s = %q, want %q", EventHeader, received.Header.Get(EventHeader), models.EventOrganizationUpdated)
+		}
+		if !verifySignature(webhook.Secret, received.Header.Get(SignatureHeader), payload) {
+			mt.Errorf("%s = %q, not a signature of the payload", SignatureHeader, received.Header.Get(SignatureHeader))
+		}
+		if status := update.Lookup("$set", "status").StringValue(); status != models.WebhookDeliverySucceeded {
+			mt.Errorf("delivery %s, want %s", status, models.WebhookDeliverySucceeded)
+		}
+	})
+
+	mt.Run("retries failed deliveries with backoff", func(mt *mtest.T) {
+		_, update := attempt(mt, http.StatusInternalServerError, 2)
+		if status := update.Lookup("$set", "status").StringValue(); status != models.WebhookDeliveryPending {
+			mt.Fatalf("delivery %s, want %s", status, models.WebhookDeliveryPending)
+		}
+		nextAttemptAt := update.Lookup("$set", "next_attempt_at").Time()
+		if delay := time.Until(nextAttemptAt); delay < Backoff(3)-time.Minute || delay > Backoff(3) {
+			mt.Errorf("next attempt in %s, want %s", delay, Backoff(3))
+		}
+		if responseStatus := update.Lookup("$push", "attempts", "response_status").Int32(); responseStatus != http.StatusInternalServerError {
+			mt.Errorf("recorded response status %d, want %d", responseStatus, http.StatusInternalServerError)
+		}
+	})
+
+	mt.Run("gives up after the last attempt", func(mt *mtest.T) {
+		_, update := attempt(mt, http.StatusInternalServerError, maxAttempts-1)
+		if status := update.Lookup("$set", "status").StringValue(); status != models.WebhookDeliveryFailed {
+			mt.Errorf("delivery %s, want %s", status, models.WebhookDeliveryFailed)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failedAttempts int
+		want           time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{4, 8 * initialBackoff},
+		{20, maxBackoff},
+	}
+	for _, test := range tests {
+		if got := Backoff(test.failedAttempts); got != test.want {
+			t.Errorf("Backoff(%d) = %s, want %s", test.failedAttempts, got, test.want)
+		}
+	}
+}
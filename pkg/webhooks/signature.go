@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers of webhook requests
+const (
+	EventHeader     = "X-OrganizationHub-Event"
+	DeliveryHeader  = "X-OrganizationHub-Delivery"
+	SignatureHeader = "X-OrganizationHub-Signature"
+)
+
+// Signature signs a payload sent at timestamp (Unix seconds). The header value has the form
+// "t=<timestamp>,v1=<signature>" where the signature is the hex HMAC-SHA256 of
+// "<timestamp>.<payload>" keyed with the webhook's secret. Endpoints should reject stale
+// timestamps to prevent replays.
+func Signature(secret string, timestamp int64, payload []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
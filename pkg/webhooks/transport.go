@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/AhmedFatthy1040/OrganizationHub-API/pkg/safehttp"
+)
+
+// requestTimeout bounds how long an endpoint has to respond.
+const requestTimeout = 10 * time.Second
+
+// ValidateURL checks that a webhook URL is an absolute HTTP(S) URL.
+func ValidateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("webhook URL must not contain credentials")
+	}
+	return nil
+}
+
+// newClient returns the HTTP client deliveries are sent with. Unless
+// WEBHOOK_ALLOW_PRIVATE_NETWORKS is true, it refuses to connect to the internal network.
+func newClient() *http.Client {
+	return safehttp.NewClient(requestTimeout, os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true")
+}